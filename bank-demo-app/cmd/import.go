@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bank-demo-app/internal/csvImport"
+	"bank-demo-app/internal/inputParams"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runImport implements the import subcommand: it loads a CSV file into the configured store,
+// logging every rejected row, and fails when any row was rejected.
+func runImport(ctx context.Context, args []string) error {
+	config, err := inputParams.ParseImportParams(args)
+	if err != nil {
+		return err
+	}
+	configureLogging(config.Log)
+
+	file, err := os.Open(config.File)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	if config.Store == inputParams.MemoryStore && !config.DryRun {
+		log.Warn().Msg("Importing into the in-memory store, the data is lost when the command exits")
+	}
+
+	bankStore, err := initBankStore(ctx, &config.AppConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize bank store: %w", err)
+	}
+	if closer, ok := bankStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	result, err := csvImport.Import(ctx, bankStore, file, config.DryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, imported := range result.Imported {
+		log.Info().Int("line", imported.Line).Str("external_reference", imported.ExternalReference).Str("account_id", imported.Account.ID).Msg("Account imported")
+	}
+	for _, rowErr := range result.Errors {
+		log.Error().Int("line", rowErr.Line).Str("column", rowErr.Column).Err(rowErr.Err).Msg("Row rejected")
+	}
+	log.Info().Bool("dry_run", result.DryRun).Int("accounts", result.Accounts).Int("transactions", result.Transactions).Int("imported", len(result.Imported)).Msg("Import finished")
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d rows rejected", len(result.Errors))
+	}
+	return nil
+}
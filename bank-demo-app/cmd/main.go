@@ -1,24 +1,54 @@
 package main
 
 import (
+	"bank-demo-app/internal/bank"
 	"bank-demo-app/internal/bank/dbBank"
 	"bank-demo-app/internal/bank/memoryBank"
+	"bank-demo-app/internal/bank/sqliteBank"
 	"bank-demo-app/internal/inputParams"
+	"bank-demo-app/internal/jwtAuth"
+	"bank-demo-app/internal/mongodb"
+	"bank-demo-app/internal/rateLimit"
 	"bank-demo-app/internal/restServer"
+	"bank-demo-app/internal/serverTLS"
+	"bank-demo-app/internal/tracing"
+	"bank-demo-app/internal/webhooks"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 const (
-	serverPort      = ":8080"
-	shutdownTimeout = 5 * time.Second
+	metricsPath = "/metrics"
+
+	bootstrapAPIKeyName = "bootstrap"
+
+	// jwtKeysReloadInterval is how soon a key added to --jwt-keys-dir starts verifying tokens.
+	jwtKeysReloadInterval = time.Minute
+
+	// certReloadInterval is how often the TLS files are checked for changes.
+	certReloadInterval = 30 * time.Second
+
+	// traceFlushTimeout bounds writing the spans left on exit.
+	traceFlushTimeout = 5 * time.Second
+
+	// storeConnectTimeout bounds each attempt to open the bank store at startup.
+	storeConnectTimeout = 10 * time.Second
+	startupBackoff      = time.Second
+	maxStartupBackoff   = 30 * time.Second
 )
 
 func main() {
@@ -28,43 +58,377 @@ func main() {
 	// Inits logger global instance to use it all around the project with same timestamp.
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	// Code logging through a context that carries no request logger falls back to the global one.
+	zerolog.DefaultContextLogger = &log.Logger
+	// Avoid GIN verbose messages, the routers log requests through the access log instead.
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+
+	if len(os.Args) > 1 && os.Args[1] == inputParams.ImportCommand {
+		if err := runImport(ctx, os.Args[2:]); err != nil {
+			log.Error().Err(err).Msg("Import failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == inputParams.MigrateCommand {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil {
+			log.Error().Err(err).Msg("Migration failed")
+			os.Exit(1)
+		}
+		return
+	}
 
 	config, err := inputParams.ParseInputParams()
 	if err != nil {
 		log.Error().Err(err).Msg("Finishing application")
+		os.Exit(1)
+	}
+
+	if config.PrintConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			log.Error().Err(err).Msg("Failed to print the configuration")
+			os.Exit(1)
+		}
 		return
 	}
+	configureLogging(config.Log)
+
+	if err := run(ctx, config); err != nil {
+		log.Error().Err(err).Msg("Finishing application")
+		os.Exit(1)
+	}
+}
+
+// run starts the server and blocks until it is stopped. Any dependency missing at startup
+// fails it, so the process exits instead of serving requests it can't answer.
+func run(ctx context.Context, config *inputParams.AppConfig) error {
+	closeTraces, err := startTracing(config.TraceOutput)
+	if err != nil {
+		return fmt.Errorf("failed to start tracing: %w", err)
+	}
+	defer func() {
+		// Spans are exported in batches, give the last one time to be written.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), traceFlushTimeout)
+		defer cancel()
+		if err := closeTraces(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to flush traces")
+		}
+	}()
 
 	// Initialize BankStore type.
-	bankStore := initBankStore(ctx, config)
+	bankStore, err := initBankStore(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize bank store: %w", err)
+	}
+	// Closed last, once the server and the webhook dispatcher are done with it.
+	if closer, ok := bankStore.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				log.Error().Err(err).Msg("Failed to close the bank store")
+			}
+		}()
+	}
+
+	outboxStore, hasOutbox := bankStore.(restServer.OutboxStore)
 
-	if err := startServer(ctx, bankStore); err != nil {
-		log.Fatal().Err(err).Msg("Application terminated with error")
+	var dependencyChecks []restServer.DependencyCheck
+	if pinger, ok := bankStore.(restServer.Pinger); ok {
+		dependencyChecks = append(dependencyChecks, restServer.DependencyCheck{Name: config.Store, Check: pinger.Ping})
+	}
+
+	registry := prometheus.NewRegistry()
+	serverMetrics := restServer.NewMetrics(registry)
+	bankStore = restServer.InstrumentStore(bankStore, serverMetrics, config.Store)
+
+	adminToken, err := config.AdminToken()
+	if err != nil {
+		return fmt.Errorf("failed to read admin API key: %w", err)
+	}
+	if err := bootstrapAPIKeys(ctx, bankStore, adminToken); err != nil {
+		return fmt.Errorf("failed to bootstrap API keys: %w", err)
+	}
+
+	// Events are queued in the store and delivered in the background. Stores with an outbox queue
+	// them in the same transaction as the write causing them, the others right after it. The
+	// dispatcher is stopped once the server is, so attempts in flight are recorded before exiting.
+	dispatcher := webhooks.NewDispatcher(bankStore, webhookConfig(config.Webhooks))
+	if hasOutbox {
+		outboxStore.SetOutbox(dispatcher)
+	}
+	bankStore = restServer.PublishEvents(bankStore, dispatcher, hasOutbox)
+	dispatchCtx, stopDispatcher := context.WithCancel(ctx)
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(dispatchCtx)
+	}()
+	defer func() {
+		stopDispatcher()
+		<-dispatcherDone
+	}()
+
+	routeOptions, err := customerTokenOptions(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
+	limitOptions, closeLimiter, err := rateLimitOptions(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to set up rate limiting: %w", err)
+	}
+	defer closeLimiter()
+	routeOptions = append(routeOptions, limitOptions...)
+
+	if config.Server.TLSClientIdentities != "" {
+		identities, err := restServer.LoadClientIdentities(config.Server.TLSClientIdentities)
+		if err != nil {
+			return fmt.Errorf("failed to load client identities: %w", err)
+		}
+		routeOptions = append(routeOptions, restServer.WithClientCertificates(identities))
+		log.Info().Int("identities", len(identities)).Msg("Authenticating client certificates")
+	}
+	routeOptions = append(routeOptions, restServer.WithMetrics(serverMetrics), restServer.WithMaxBodySize(int64(config.Server.MaxBodySize)))
+
+	// Prometheus scrapes /metrics on a listener of its own, as the metrics are unauthenticated.
+	if config.Server.MetricsAddress != "" {
+		metricsServer := serveMetrics(config.Server, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		defer metricsServer.Close()
+	}
+
+	// Orchestrators probe /healthz and /readyz next to the API, outside its versioning and
+	// authentication.
+	health := restServer.NewHealthHandler(dependencyChecks...)
+	mux := http.NewServeMux()
+	mux.Handle(restServer.LivenessPath, health)
+	mux.Handle(restServer.ReadinessPath, health)
+	mux.Handle("/", restServer.NewRouter(restServer.InitRestRoutes(bankStore, routeOptions...)))
+
+	return startServer(ctx, config.Server, mux)
+}
+
+func webhookConfig(config inputParams.WebhookConfig) webhooks.Config {
+	webhookConfig := webhooks.DefaultConfig()
+	webhookConfig.MaxAttempts = config.MaxAttempts
+	webhookConfig.Timeout = config.Timeout
+	webhookConfig.InitialBackoff = config.InitialBackoff
+	webhookConfig.MaxBackoff = config.MaxBackoff
+	return webhookConfig
+}
+
+// configureLogging sets the level and format of the global logger.
+func configureLogging(config inputParams.LogConfig) {
+	// The level was validated with the rest of the configuration.
+	level, _ := zerolog.ParseLevel(strings.ToLower(config.Level))
+	zerolog.SetGlobalLevel(level)
+	if config.Format == inputParams.ConsoleLogFormat {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339})
 	}
 }
 
 /// BootStrap helper functions would be moved to different package, but not needed for this technical test.
 
-// initBankStore initializes the appropriate BankStore based on configuration.
-func initBankStore(ctx context.Context, config *inputParams.AppConfig) restServer.BankStore {
-	if config.InMemory {
-		return memoryBank.NewBankStore()
+// initBankStore initializes the appropriate BankStore based on configuration. A database that
+// can't be reached is retried config.StartupRetries times with exponential backoff, so the server
+// waits for a database starting alongside it but never runs without one.
+func initBankStore(ctx context.Context, config *inputParams.AppConfig) (restServer.BankStore, error) {
+	var bankStore restServer.BankStore
+	err := retryWithBackoff(ctx, config.StartupRetries, func() (err error) {
+		bankStore, err = openBankStore(ctx, config)
+		return err
+	})
+	return bankStore, err
+}
+
+func openBankStore(ctx context.Context, config *inputParams.AppConfig) (restServer.BankStore, error) {
+	ctx, cancel := context.WithTimeout(ctx, storeConnectTimeout)
+	defer cancel()
+
+	switch config.Store {
+	case inputParams.MemoryStore:
+		return memoryBank.NewBankStore(), nil
+	case inputParams.SqliteStore:
+		log.Info().Str("path", config.SqlitePath).Msg("Using SQLite bank store")
+		bankStore, err := sqliteBank.NewBankStore(ctx, config.SqlitePath)
+		if err != nil {
+			return nil, err
+		}
+		return bankStore, nil
+	default:
+		bankStore, err := dbBank.NewBankStore(ctx, &config.MongoConf)
+		if err != nil {
+			return nil, err
+		}
+		return bankStore, nil
+	}
+}
+
+// retryWithBackoff calls attempt until it succeeds or has failed retries+1 times, doubling the
+// wait between attempts up to maxStartupBackoff.
+func retryWithBackoff(ctx context.Context, retries int, attempt func() error) error {
+	backoff := startupBackoff
+	for try := 1; ; try++ {
+		err := attempt()
+		if err == nil || try > retries {
+			return err
+		}
+
+		log.Warn().Err(err).Int("attempt", try).Dur("retry_in", backoff).Msg("Bank store unavailable, retrying")
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxStartupBackoff)
+	}
+}
+
+// bootstrapAPIKeys creates the admin key given with $BANK_ADMIN_API_KEY if the store lacks it, so
+// a fresh deployment has a key to create the others with. A revoked key is not brought back.
+func bootstrapAPIKeys(ctx context.Context, bankStore restServer.BankStore, adminToken string) error {
+	if adminToken == "" {
+		keys, err := bankStore.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			log.Warn().Msgf("No API keys exist, every request but GET /status will be rejected. Start with $%s or --admin-api-key-file to create one.", inputParams.AdminAPIKeyEnv)
+		}
+		return nil
+	}
+
+	existing, err := bankStore.GetAPIKeyByHash(ctx, bank.HashAPIKeyToken(adminToken))
+	if err == nil {
+		if existing.IsRevoked() {
+			log.Warn().Str("api_key_id", existing.ID).Msg("The bootstrap admin API key has been revoked, it won't be recreated")
+		}
+		return nil
+	}
+	if !errors.Is(err, bank.ErrAPIKeyNotFound) {
+		return err
+	}
+
+	key, err := bank.NewAPIKeyFromToken(bootstrapAPIKeyName, adminToken, []string{bank.ScopeAdmin}, time.Now())
+	if err != nil {
+		return err
+	}
+	if _, err := bankStore.CreateAPIKey(ctx, key); err != nil {
+		return err
+	}
+	log.Info().Str("api_key_id", key.ID).Str("prefix", key.Prefix).Msg("Created bootstrap admin API key")
+	return nil
+}
+
+// customerTokenOptions accepts customer JWTs when --jwt-keys-dir is set, reloading the keys
+// periodically so rotated ones are picked up.
+func customerTokenOptions(ctx context.Context, config *inputParams.AppConfig) ([]restServer.RouteOption, error) {
+	if config.JWTKeysDir == "" {
+		return nil, nil
+	}
+
+	keys, err := jwtAuth.LoadKeySet(config.JWTKeysDir)
+	if err != nil {
+		return nil, err
 	}
-	return dbBank.NewBankStore(ctx, &config.MongoConf)
+	go keys.Watch(ctx, jwtKeysReloadInterval)
+
+	verifier := jwtAuth.NewVerifier(keys, jwtAuth.Options{Issuer: config.JWTIssuer, Audience: config.JWTAudience})
+	log.Info().Str("dir", config.JWTKeysDir).Msg("Accepting customer tokens")
+	return []restServer.RouteOption{restServer.WithTokenVerifier(verifier)}, nil
 }
 
-// startServer starts the HTTP server and handles graceful shutdown.
-func startServer(ctx context.Context, bankStore restServer.BankStore) error {
-	router := restServer.NewRouter(restServer.InitRestRoutes(bankStore))
+// rateLimitOptions limits requests as configured in --rate-limit-config, keeping the buckets
+// where --rate-limit-store says. The returned function disconnects from the database holding
+// them, once the server stopped.
+func rateLimitOptions(ctx context.Context, config *inputParams.AppConfig) ([]restServer.RouteOption, func(), error) {
+	noCleanup := func() {}
+	if config.RateLimitFile == "" {
+		return nil, noCleanup, nil
+	}
+
+	limits, err := rateLimit.LoadConfig(config.RateLimitFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var limiter rateLimit.Limiter = rateLimit.NewMemoryLimiter()
+	cleanup := noCleanup
+	if config.RateLimitStore == inputParams.MongoStore {
+		mongoClient := mongodb.NewMongoDBClient(&config.MongoConf)
+		if err := mongoClient.ConnectMongoClient(ctx); err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), storeConnectTimeout)
+			defer cancel()
+			if err := mongoClient.Client.Disconnect(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to disconnect the rate limit store")
+			}
+		}
+		mongoClient.GetCollections([]string{rateLimit.Collection})
+		if limiter, err = rateLimit.NewMongoLimiter(ctx, mongoClient.Collections[rateLimit.Collection]); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	log.Info().Str("file", config.RateLimitFile).Str("store", config.RateLimitStore).Msg("Rate limiting requests")
+	return []restServer.RouteOption{restServer.WithRateLimiter(limiter, limits)}, cleanup, nil
+}
+
+// startTracing exports spans to output when set. The returned function flushes the spans and
+// closes the output.
+func startTracing(output string) (func(ctx context.Context) error, error) {
+	if output == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	shutdown, err := tracing.Start(output)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("output", output).Msg("Tracing requests")
+	return shutdown, nil
+}
+
+// startServer serves handler, over HTTPS when a certificate is configured, and handles graceful
+// shutdown.
+func startServer(ctx context.Context, config inputParams.ServerConfig, handler http.Handler) error {
 	server := &http.Server{
-		Addr:    serverPort,
-		Handler: router,
+		Addr:              config.Address,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+
+	listen := server.ListenAndServe
+	if config.TLSCertFile != "" {
+		certificates, err := serverTLS.NewReloader(serverTLS.Config{
+			CertFile:          config.TLSCertFile,
+			KeyFile:           config.TLSKeyFile,
+			ClientCAFile:      config.TLSClientCAFile,
+			RequireClientCert: config.TLSRequireClientCert,
+		})
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = certificates.TLSConfig()
+
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
+		go certificates.Watch(ctx, certReloadInterval, hangup)
+
+		// The certificate comes from TLSConfig, so no files are given here.
+		listen = func() error { return server.ListenAndServeTLS("", "") }
 	}
 
 	// Run server in a goroutine
 	go func() {
-		log.Info().Msgf("Server listening on port %s...", serverPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info().Bool("tls", server.TLSConfig != nil).Bool("mutual_tls", config.TLSClientCAFile != "").Msgf("Server listening on %s...", config.Address)
+		if err := listen(); err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("Server failed")
 		}
 	}()
@@ -75,7 +439,7 @@ func startServer(ctx context.Context, bankStore restServer.BankStore) error {
 
 	// Shutdown server gracefully
 	log.Info().Msg("Shutting down server...")
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, config.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctxWithTimeout); err != nil {
@@ -87,6 +451,28 @@ func startServer(ctx context.Context, bankStore restServer.BankStore) error {
 	return nil
 }
 
+// serveMetrics serves handler as /metrics on config.MetricsAddress, in plain HTTP and
+// until the returned server is closed.
+func serveMetrics(config inputParams.ServerConfig, handler http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, handler)
+	server := &http.Server{
+		Addr:              config.MetricsAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+
+	go func() {
+		log.Info().Msgf("Serving metrics on %s%s", config.MetricsAddress, metricsPath)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("Metrics server failed")
+		}
+	}()
+	return server
+}
+
 // handleSignals listens for termination signals and returns a cancellable context.
 func handleSignals() context.Context {
 	stop := make(chan os.Signal, 1)
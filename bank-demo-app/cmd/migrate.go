@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bank-demo-app/internal/bank/dbBank"
+	"bank-demo-app/internal/inputParams"
+	"bank-demo-app/internal/mongodb"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runMigrate implements the migrate subcommand: up applies the pending MongoDB migrations,
+// status lists every migration and when it was applied.
+func runMigrate(ctx context.Context, args []string) error {
+	config, err := inputParams.ParseMigrateParams(args)
+	if err != nil {
+		return err
+	}
+	configureLogging(config.Log)
+
+	mongoClient := mongodb.NewMongoDBClient(&config.MongoConf)
+	connectCtx, cancel := context.WithTimeout(ctx, storeConnectTimeout)
+	defer cancel()
+	if err := mongoClient.ConnectMongoClient(connectCtx); err != nil {
+		return err
+	}
+	defer mongoClient.Client.Disconnect(context.Background())
+
+	if config.Action == inputParams.MigrateUp {
+		applied, err := mongodb.Migrate(ctx, mongoClient.Database(), dbBank.Migrations)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("applied", len(applied)).Msg("Database is up to date")
+		return nil
+	}
+
+	statuses, err := mongodb.MigrationStatuses(ctx, mongoClient.Database(), dbBank.Migrations)
+	if err != nil {
+		return err
+	}
+	return printMigrationStatuses(os.Stdout, statuses)
+}
+
+func printMigrationStatuses(w io.Writer, statuses []mongodb.MigrationStatus) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied != nil {
+			state, appliedAt = "applied", status.Applied.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", status.Version, state, appliedAt, status.Description)
+	}
+	return table.Flush()
+}
@@ -9,11 +9,26 @@ const (
 	WithdrawalTransactionType = "withdrawal"
 )
 
-// Account represents a bank account owner information.
+const (
+	AccountStatusOpen   = "open"
+	AccountStatusClosed = "closed"
+)
+
+// Account represents a bank account owner information. Closed accounts keep their history
+// but can't take part in new transactions or transfers.
 type Account struct {
-	ID      string  `json:"id" bson:"_id"`
-	Owner   string  `json:"owner" bson:"owner"`
-	Balance float64 `json:"balance" bson:"balance"`
+	ID       string            `json:"id" bson:"_id"`
+	Owner    string            `json:"owner" bson:"owner"`
+	Balance  float64           `json:"balance" bson:"balance"`
+	Status   string            `json:"status" bson:"status"`
+	Metadata map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	ClosedAt *time.Time        `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+}
+
+// IsClosed reports whether the account was closed. Accounts stored before closure existed have no
+// status and are open.
+func (acc *Account) IsClosed() bool {
+	return acc.Status == AccountStatusClosed
 }
 
 func (acc *Account) Deposit(amount float64) {
@@ -48,4 +63,5 @@ type Transaction struct {
 	Type      string    `json:"type" bson:"type"`
 	Amount    float64   `json:"amount" bson:"amount"`
 	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	Reference string    `json:"reference,omitempty" bson:"reference,omitempty"`
 }
@@ -0,0 +1,130 @@
+package bank
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxMetadataKeys        = 20
+	MaxMetadataValueLength = 500
+)
+
+// metadataKeyPattern keeps keys short and free of characters that have a meaning in store queries.
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,40}$`)
+
+// AccountUpdate describes a change to the mutable fields of an account. A nil Owner leaves it unchanged.
+// Metadata is merged into the current metadata and a nil value removes its key; with ReplaceMetadata
+// the current metadata is dropped first.
+type AccountUpdate struct {
+	Owner           *string
+	Metadata        map[string]*string
+	ReplaceMetadata bool
+}
+
+// Apply returns a copy of account with the update applied. The metadata map is never shared with account.
+func (u AccountUpdate) Apply(account Account) Account {
+	if u.Owner != nil {
+		account.Owner = *u.Owner
+	}
+
+	metadata := maps.Clone(account.Metadata)
+	if u.ReplaceMetadata || metadata == nil {
+		metadata = make(map[string]string, len(u.Metadata))
+	}
+	for key, value := range u.Metadata {
+		if value == nil {
+			delete(metadata, key)
+		} else {
+			metadata[key] = *value
+		}
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	account.Metadata = metadata
+
+	return account
+}
+
+// ValidateAccountUpdate checks the update on its own. The merged metadata must still pass ValidateMetadata.
+func ValidateAccountUpdate(update AccountUpdate) error {
+	if update.Owner != nil && *update.Owner == "" {
+		return ErrEmptyOwnerName
+	}
+	for key, value := range update.Metadata {
+		if err := validateMetadataEntry(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateMetadata checks the complete metadata of an account.
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return InvalidMetadataError(fmt.Sprintf("at most %d keys are allowed", MaxMetadataKeys))
+	}
+	for key, value := range metadata {
+		if err := validateMetadataEntry(key, &value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMetadataEntry(key string, value *string) error {
+	if !metadataKeyPattern.MatchString(key) {
+		return InvalidMetadataError(fmt.Sprintf("key %q must be 1 to 40 letters, digits, '_' or '-'", key))
+	}
+	if value != nil && utf8.RuneCountInString(*value) > MaxMetadataValueLength {
+		return InvalidMetadataError(fmt.Sprintf("value of %q is longer than %d characters", key, MaxMetadataValueLength))
+	}
+	return nil
+}
+
+// AccountClosure is the outcome of closing an account. When a balance was paid out, Withdrawal
+// records it leaving the closed account and Deposit records it reaching the payout account, both
+// with the ClosureReference of the closed account.
+type AccountClosure struct {
+	Account    Account
+	Withdrawal *Transaction
+	Deposit    *Transaction
+}
+
+// ClosureReference is the reference of the transactions paying out the balance of a closed account.
+func ClosureReference(accountID string) string {
+	return "account-closure:" + accountID
+}
+
+// CloseAccount returns a copy of account closed at closedAt with its balance paid out.
+func CloseAccount(account Account, closedAt time.Time) Account {
+	account.Status = AccountStatusClosed
+	account.Balance = 0
+	account.ClosedAt = &closedAt
+	return account
+}
+
+// ValidateClosure checks that account can be closed, paying out its balance to payout when it isn't zero.
+// payout is nil when no payout account was requested.
+func ValidateClosure(account Account, payout *Account) error {
+	if account.IsClosed() {
+		return AccountClosedError(account.ID)
+	}
+	if payout == nil {
+		if account.Balance != 0 {
+			return AccountBalanceNotZeroError(account.ID, account.Balance)
+		}
+		return nil
+	}
+	if payout.ID == account.ID {
+		return SameSourceDestinationAccountError(account.ID)
+	}
+	if payout.IsClosed() {
+		return AccountClosedError(payout.ID)
+	}
+	return nil
+}
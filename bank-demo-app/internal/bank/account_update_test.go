@@ -0,0 +1,68 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountUpdateApply(t *testing.T) {
+	owner, tier := "Jane Doe", "gold"
+	account := Account{
+		ID:       "1",
+		Owner:    "John Doe",
+		Balance:  100.0,
+		Metadata: map[string]string{"branch": "BCN", "tier": "silver"},
+	}
+
+	merged := AccountUpdate{Metadata: map[string]*string{"tier": &tier, "branch": nil}}.Apply(account)
+	assert.Equal(t, "John Doe", merged.Owner)
+	assert.Equal(t, map[string]string{"tier": "gold"}, merged.Metadata)
+	assert.Equal(t, map[string]string{"branch": "BCN", "tier": "silver"}, account.Metadata, "the original metadata must not change")
+
+	replaced := AccountUpdate{Owner: &owner, ReplaceMetadata: true}.Apply(account)
+	assert.Equal(t, "Jane Doe", replaced.Owner)
+	assert.Nil(t, replaced.Metadata)
+	assert.Equal(t, 100.0, replaced.Balance)
+}
+
+func TestValidateAccountUpdate(t *testing.T) {
+	empty, value := "", "value"
+	tests := []struct {
+		name          string
+		update        AccountUpdate
+		expectedError error
+	}{
+		{"no change", AccountUpdate{}, nil},
+		{"removal", AccountUpdate{Metadata: map[string]*string{"tier": nil}}, nil},
+		{"empty owner", AccountUpdate{Owner: &empty}, ErrEmptyOwnerName},
+		{"empty key", AccountUpdate{Metadata: map[string]*string{"": &value}}, ErrInvalidMetadata},
+		{"operator key", AccountUpdate{Metadata: map[string]*string{"$set": &value}}, ErrInvalidMetadata},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateAccountUpdate(test.update)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateClosure(t *testing.T) {
+	funded := Account{ID: "1", Balance: 10.0, Status: AccountStatusOpen}
+	empty := Account{ID: "2", Status: AccountStatusOpen}
+	legacy := Account{ID: "3"}
+	closed := CloseAccount(Account{ID: "4"}, time.Now())
+
+	assert.NoError(t, ValidateClosure(empty, nil))
+	assert.NoError(t, ValidateClosure(funded, &legacy), "accounts without status are open")
+	assert.ErrorIs(t, ValidateClosure(funded, nil), ErrAccountBalanceNotZero)
+	assert.ErrorIs(t, ValidateClosure(funded, &funded), ErrSameSourceDestination)
+	assert.ErrorIs(t, ValidateClosure(funded, &closed), ErrAccountClosed)
+	assert.ErrorIs(t, ValidateClosure(closed, nil), ErrAccountClosed)
+}
@@ -0,0 +1,61 @@
+package bank
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	createdAt := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	key, token, err := NewAPIKey("reporting", []string{ScopeAccountsRead}, createdAt)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(token, apiKeyTokenPrefix))
+	assert.True(t, strings.HasPrefix(token, key.Prefix))
+	assert.Len(t, key.Prefix, apiKeyDisplayLength)
+	assert.Equal(t, HashAPIKeyToken(token), key.Hash)
+	assert.NotContains(t, key.Hash, token)
+	assert.Equal(t, createdAt, key.CreatedAt)
+	assert.False(t, key.IsRevoked())
+
+	_, other, err := NewAPIKey("reporting", []string{ScopeAccountsRead}, createdAt)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other, "tokens must be random")
+}
+
+func TestNewAPIKeyRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		keyFor func() error
+	}{
+		{"empty name", func() error { _, _, err := NewAPIKey("", []string{ScopeAdmin}, time.Now()); return err }},
+		{"no scopes", func() error { _, _, err := NewAPIKey("ops", nil, time.Now()); return err }},
+		{"unknown scope", func() error { _, _, err := NewAPIKey("ops", []string{"accounts:delete"}, time.Now()); return err }},
+		{"short token", func() error {
+			_, err := NewAPIKeyFromToken("ops", "too-short", []string{ScopeAdmin}, time.Now())
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.ErrorIs(t, test.keyFor(), ErrInvalidAPIKey)
+		})
+	}
+}
+
+func TestAPIKeyHasScope(t *testing.T) {
+	reader := APIKey{Scopes: []string{ScopeAccountsRead, ScopeTransactionsRead}}
+	assert.True(t, reader.HasScope(ScopeAccountsRead))
+	assert.False(t, reader.HasScope(ScopeAccountsWrite))
+	assert.False(t, reader.HasScope(ScopeAdmin))
+
+	admin := APIKey{Scopes: []string{ScopeAdmin}}
+	for _, scope := range Scopes {
+		assert.True(t, admin.HasScope(scope), "admin must grant %s", scope)
+	}
+}
@@ -0,0 +1,161 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	BatchDeposit    = DepositTransactionType
+	BatchWithdrawal = WithdrawalTransactionType
+	BatchTransfer   = "transfer"
+
+	// MaxBatchOperations bounds the size of a batch so a single request can't hold a store for long.
+	MaxBatchOperations = 1000
+)
+
+const (
+	BatchOperationSucceeded = "succeeded"
+	BatchOperationFailed    = "failed"
+)
+
+// Batch is a list of operations applied in order. An atomic batch is all-or-nothing: the first failure
+// undoes every operation already applied. Otherwise each operation succeeds or fails on its own.
+type Batch struct {
+	Atomic     bool
+	Operations []BatchOperation
+}
+
+// BatchOperation is a deposit or withdrawal on AccountID, or a transfer between FromAccountID and ToAccountID.
+type BatchOperation struct {
+	Type          string  `json:"type"`
+	AccountID     string  `json:"account_id,omitempty"`
+	FromAccountID string  `json:"from_account_id,omitempty"`
+	ToAccountID   string  `json:"to_account_id,omitempty"`
+	Amount        float64 `json:"amount"`
+	Reference     string  `json:"reference,omitempty"`
+}
+
+// BatchOperationResult is the outcome of the operation at Index. Transaction is only set for
+// successful deposits and withdrawals, since transfers don't record transactions.
+type BatchOperationResult struct {
+	Index       int
+	Status      string
+	Transaction *Transaction
+	Err         error
+}
+
+type BatchResult struct {
+	Atomic    bool
+	Succeeded int
+	Failed    int
+	Results   []BatchOperationResult
+}
+
+// BatchOperationError reports the operation that made an atomic batch fail.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("batch operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
+
+// BatchApplier applies single operations. Every BankStore satisfies it.
+type BatchApplier interface {
+	PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*Transaction, error)
+	TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error
+}
+
+// ValidateBatch checks the size of the batch. Operations are checked by ValidateBatchOperation.
+func ValidateBatch(batch Batch) error {
+	if len(batch.Operations) == 0 {
+		return InvalidBatchError("at least one operation is required")
+	}
+	if len(batch.Operations) > MaxBatchOperations {
+		return InvalidBatchError(fmt.Sprintf("at most %d operations are allowed", MaxBatchOperations))
+	}
+	return nil
+}
+
+// ValidateAtomicBatch checks the batch and every operation, so an invalid atomic batch is rejected
+// before anything is applied.
+func ValidateAtomicBatch(batch Batch) error {
+	if err := ValidateBatch(batch); err != nil {
+		return err
+	}
+	for i, operation := range batch.Operations {
+		if err := ValidateBatchOperation(operation); err != nil {
+			return &BatchOperationError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func ValidateBatchOperation(operation BatchOperation) error {
+	switch operation.Type {
+	case BatchDeposit, BatchWithdrawal:
+		if operation.AccountID == "" {
+			return InvalidBatchError("account_id is required for " + operation.Type)
+		}
+		if err := ValidateTransaction(operation.Type, operation.Amount); err != nil {
+			return err
+		}
+		return ValidateReference(operation.Reference)
+	case BatchTransfer:
+		if operation.FromAccountID == "" || operation.ToAccountID == "" {
+			return InvalidBatchError("from_account_id and to_account_id are required for transfer")
+		}
+		return ValidateTransfer(operation.FromAccountID, operation.ToAccountID, operation.Amount)
+	case "":
+		return InvalidBatchError("operation type is required")
+	default:
+		return InvalidBatchError(fmt.Sprintf("unknown operation type %q", operation.Type))
+	}
+}
+
+// ApplyBatchOperation applies a single operation with store.
+func ApplyBatchOperation(ctx context.Context, store BatchApplier, operation BatchOperation) (*Transaction, error) {
+	if err := ValidateBatchOperation(operation); err != nil {
+		return nil, err
+	}
+	if operation.Type == BatchTransfer {
+		return nil, store.TransferFunds(ctx, operation.FromAccountID, operation.ToAccountID, operation.Amount)
+	}
+	return store.PerformTransaction(ctx, operation.AccountID, operation.Type, operation.Amount, operation.Reference)
+}
+
+// ExecuteBestEffort applies every operation on its own, in order, and records each outcome.
+func ExecuteBestEffort(ctx context.Context, store BatchApplier, operations []BatchOperation) *BatchResult {
+	result := &BatchResult{Results: make([]BatchOperationResult, 0, len(operations))}
+	for i, operation := range operations {
+		transaction, err := ApplyBatchOperation(ctx, store, operation)
+		result.record(i, transaction, err)
+	}
+	return result
+}
+
+// NewAtomicBatchResult builds the result of an atomic batch whose operations all succeeded.
+// transactions holds the recorded transaction of each operation, nil for transfers.
+func NewAtomicBatchResult(transactions []*Transaction) *BatchResult {
+	result := &BatchResult{Atomic: true, Results: make([]BatchOperationResult, 0, len(transactions))}
+	for i, transaction := range transactions {
+		result.record(i, transaction, nil)
+	}
+	return result
+}
+
+func (r *BatchResult) record(index int, transaction *Transaction, err error) {
+	if err != nil {
+		r.Failed++
+		r.Results = append(r.Results, BatchOperationResult{Index: index, Status: BatchOperationFailed, Err: err})
+		return
+	}
+	r.Succeeded++
+	r.Results = append(r.Results, BatchOperationResult{Index: index, Status: BatchOperationSucceeded, Transaction: transaction})
+}
@@ -0,0 +1,81 @@
+package bank
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBatchOperation(t *testing.T) {
+	tests := []struct {
+		name          string
+		operation     BatchOperation
+		expectedError error
+	}{
+		{"deposit", BatchOperation{Type: BatchDeposit, AccountID: "1", Amount: 10}, nil},
+		{"withdrawal", BatchOperation{Type: BatchWithdrawal, AccountID: "1", Amount: 10}, nil},
+		{"transfer", BatchOperation{Type: BatchTransfer, FromAccountID: "1", ToAccountID: "2", Amount: 10}, nil},
+		{"missing type", BatchOperation{AccountID: "1", Amount: 10}, ErrInvalidBatch},
+		{"unknown type", BatchOperation{Type: "refund", AccountID: "1", Amount: 10}, ErrInvalidBatch},
+		{"missing account", BatchOperation{Type: BatchDeposit, Amount: 10}, ErrInvalidBatch},
+		{"missing destination", BatchOperation{Type: BatchTransfer, FromAccountID: "1", Amount: 10}, ErrInvalidBatch},
+		{"zero amount", BatchOperation{Type: BatchDeposit, AccountID: "1"}, ErrZeroTransactionAmount},
+		{"same accounts", BatchOperation{Type: BatchTransfer, FromAccountID: "1", ToAccountID: "1", Amount: 10}, ErrSameSourceDestination},
+		{"long reference", BatchOperation{Type: BatchDeposit, AccountID: "1", Amount: 10, Reference: strings.Repeat("a", MaxReferenceLength+1)}, ErrReferenceTooLong},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBatchOperation(test.operation)
+			if test.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expectedError)
+			}
+		})
+	}
+}
+
+type recordingApplier struct {
+	calls []string
+}
+
+func (a *recordingApplier) PerformTransaction(_ context.Context, accountID string, txType string, amount float64, reference string) (*Transaction, error) {
+	a.calls = append(a.calls, txType+":"+accountID)
+	if txType == WithdrawalTransactionType {
+		return nil, InsufficientFundsError(accountID, 0, amount)
+	}
+	return &Transaction{AccountID: accountID, Type: txType, Amount: amount, Reference: reference}, nil
+}
+
+func (a *recordingApplier) TransferFunds(_ context.Context, fromAccountID, toAccountID string, _ float64) error {
+	a.calls = append(a.calls, "transfer:"+fromAccountID+">"+toAccountID)
+	return nil
+}
+
+func TestExecuteBestEffort(t *testing.T) {
+	applier := &recordingApplier{}
+	result := ExecuteBestEffort(context.Background(), applier, []BatchOperation{
+		{Type: BatchWithdrawal, AccountID: "1", Amount: 10},
+		{Type: "refund", AccountID: "1", Amount: 10},
+		{Type: BatchTransfer, FromAccountID: "1", ToAccountID: "2", Amount: 10},
+		{Type: BatchDeposit, AccountID: "2", Amount: 10},
+	})
+
+	assert.Equal(t, []string{"withdrawal:1", "transfer:1>2", "deposit:2"}, applier.calls, "invalid operations never reach the store")
+	assert.False(t, result.Atomic)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+	assert.ErrorIs(t, result.Results[0].Err, ErrInsufficientFunds)
+	assert.ErrorIs(t, result.Results[1].Err, ErrInvalidBatch)
+	assert.Nil(t, result.Results[2].Transaction)
+	assert.Equal(t, "2", result.Results[3].Transaction.AccountID)
+}
+
+func TestBatchOperationError(t *testing.T) {
+	err := &BatchOperationError{Index: 2, Err: AccountNotFoundError("1")}
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.Equal(t, "batch operation 2: account not found: account ID 1", err.Error())
+}
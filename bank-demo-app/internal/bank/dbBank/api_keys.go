@@ -0,0 +1,83 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (bs *BankStore) CreateAPIKey(ctx context.Context, key bank.APIKey) (*bank.APIKey, error) {
+	if err := bank.ValidateAPIKeyInput(key.Name, key.Scopes); err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt = key.CreatedAt.Truncate(time.Millisecond) // BSON dates only keep milliseconds.
+
+	collection := bs.dbClient.Collections[apiKeysCollection]
+	if _, err := collection.InsertOne(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (bs *BankStore) GetAPIKeyByHash(ctx context.Context, hash string) (*bank.APIKey, error) {
+	collection := bs.dbClient.Collections[apiKeysCollection]
+
+	var key bank.APIKey
+	err := collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, bank.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (bs *BankStore) ListAPIKeys(ctx context.Context) ([]bank.APIKey, error) {
+	collection := bs.dbClient.Collections[apiKeysCollection]
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []bank.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking it again keeps the first revocation time.
+func (bs *BankStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (*bank.APIKey, error) {
+	collection := bs.dbClient.Collections[apiKeysCollection]
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt.UTC().Truncate(time.Millisecond)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	var key bank.APIKey
+	err = collection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, bank.APIKeyNotFoundError(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
@@ -9,43 +9,106 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
 	accountsCollection     string = "accounts"
 	transactionsCollection string = "transactions"
+	apiKeysCollection      string = "api_keys"
+	webhooksCollection     string = "webhooks"
+	deliveriesCollection   string = "webhook_deliveries"
+
+	// compensationTimeout bounds the writes that undo a half-applied operation.
+	compensationTimeout = 5 * time.Second
+
+	// disconnectTimeout bounds closing the connections to the database when the store is closed.
+	disconnectTimeout = 5 * time.Second
+
+	// closeAttempts bounds how often closing an account is retried while its balance keeps changing.
+	closeAttempts = 3
 )
 
+// openAccount matches accounts that are not closed, including those stored before accounts had a status.
+var openAccount = bson.M{"$ne": bank.AccountStatusClosed}
+
 type BankStore struct {
 	dbClient *mongodb.MongoDBClient
+	// transactions is set when the deployment supports multi-document transactions.
+	transactions bool
 }
 
-func NewBankStore(ctx context.Context, dbConf *mongodb.MongoConfig) *BankStore {
+// NewBankStore connects to the database of dbConf and applies the pending Migrations, failing
+// when it can't be reached.
+func NewBankStore(ctx context.Context, dbConf *mongodb.MongoConfig) (*BankStore, error) {
 	mongoClient := mongodb.NewMongoDBClient(dbConf)
 
 	if err := mongoClient.ConnectMongoClient(ctx); err != nil {
-		return nil
+		return nil, err
 	}
 	// Workarround for testing application, this would be refactored and way more clean.
-	mongoClient.GetCollections([]string{accountsCollection, transactionsCollection})
+	mongoClient.GetCollections([]string{accountsCollection, transactionsCollection, apiKeysCollection, webhooksCollection, deliveriesCollection})
 
-	bankStore := &BankStore{dbClient: mongoClient}
+	if _, err := mongodb.Migrate(ctx, mongoClient.Database(), Migrations); err != nil {
+		mongoClient.Client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	var reply helloReply
+	if err := mongoClient.Database().RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&reply); err != nil {
+		mongoClient.Client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to describe the deployment: %w", err)
+	}
+	transactions := reply.supportsTransactions()
+	if !transactions {
+		log.Ctx(ctx).Warn().Msg("MongoDB is a standalone server, atomic batches will be rejected")
+	}
 
-	return bankStore
+	return &BankStore{dbClient: mongoClient, transactions: transactions}, nil
 }
 
-func (bs *BankStore) CreateAccount(owner string, initialBalance float64) (*bank.Account, error) {
+// helloReply holds the fields of the hello command that tell the kind of deployment.
+type helloReply struct {
+	SetName string `bson:"setName"`
+	Msg     string `bson:"msg"`
+}
+
+// supportsTransactions reports whether the deployment is a replica set member or a mongos, the
+// deployments that run multi-document transactions.
+func (reply helloReply) supportsTransactions() bool {
+	return reply.SetName != "" || reply.Msg == "isdbgrid"
+}
+
+// Close disconnects from the database, waiting for the operations in flight.
+func (bs *BankStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	return bs.dbClient.Client.Disconnect(ctx)
+}
+
+// Ping checks that the database is still reachable.
+func (bs *BankStore) Ping(ctx context.Context) error {
+	return bs.dbClient.CheckConnection(ctx)
+}
+
+func (bs *BankStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	if err := bank.ValidateAccountInput(owner, initialBalance); err != nil {
+		return nil, err
+	}
+
 	accountID := uuid.New().String()
 	account := bank.Account{
 		ID:      accountID,
 		Owner:   owner,
 		Balance: initialBalance,
+		Status:  bank.AccountStatusOpen,
 	}
 
 	collection := bs.dbClient.Collections[accountsCollection]
-	_, err := collection.InsertOne(context.Background(), account)
+	_, err := collection.InsertOne(ctx, account)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
@@ -53,159 +116,522 @@ func (bs *BankStore) CreateAccount(owner string, initialBalance float64) (*bank.
 	return &account, nil
 }
 
-func (bs *BankStore) GetAccountByID(id string) (*bank.Account, error) {
+func (bs *BankStore) GetAccountByID(ctx context.Context, id string) (*bank.Account, error) {
 	collection := bs.dbClient.Collections[accountsCollection]
 
 	var account bank.Account
-	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&account)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&account)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, bank.AccountNotFoundError(id)
 		}
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
+	normalizeAccount(&account)
 
 	return &account, nil
 }
 
-func (bs *BankStore) ListAccounts() []bank.Account {
+func (bs *BankStore) UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error) {
+	if err := bank.ValidateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+
+	account, err := bs.GetAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.IsClosed() {
+		return nil, bank.AccountClosedError(id)
+	}
+
+	updated := update.Apply(*account)
+	if err := bank.ValidateMetadata(updated.Metadata); err != nil {
+		return nil, err
+	}
+
+	// Only owner and metadata are written, so a concurrent balance change is never overwritten.
+	// Concurrent updates of the same account resolve as last writer wins.
+	change := bson.M{"$set": bson.M{"owner": updated.Owner, "metadata": updated.Metadata}}
+	if len(updated.Metadata) == 0 {
+		change = bson.M{"$set": bson.M{"owner": updated.Owner}, "$unset": bson.M{"metadata": ""}}
+	}
+	result, err := bs.dbClient.Collections[accountsCollection].UpdateOne(ctx,
+		bson.M{"_id": id, "status": openAccount}, change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update account: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, bank.AccountClosedError(id)
+	}
+
+	// Return the balance as stored now rather than the one read before the update.
+	return bs.GetAccountByID(ctx, id)
+}
+
+// CloseAccount closes the account, then credits its balance to payoutAccountID when one is given and
+// records the payout on both accounts. Closing only matches the balance that was read, so a concurrent
+// transaction makes it retry. A failed payout credit or record reopens the account with its balance.
+func (bs *BankStore) CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error) {
 	collection := bs.dbClient.Collections[accountsCollection]
 
-	cursor, err := collection.Find(context.Background(), bson.M{})
+	for attempt := 0; attempt < closeAttempts; attempt++ {
+		account, err := bs.GetAccountByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var payout *bank.Account
+		if payoutAccountID != "" {
+			payout, err = bs.GetAccountByID(ctx, payoutAccountID)
+			if errors.Is(err, bank.ErrAccountNotFound) {
+				return nil, bank.TransferDestinationNotFoundError(payoutAccountID)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := bank.ValidateClosure(*account, payout); err != nil {
+			return nil, err
+		}
+
+		closed := bank.CloseAccount(*account, time.Now().UTC().Truncate(time.Millisecond))
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": id, "status": openAccount, "balance": account.Balance},
+			bson.M{"$set": bson.M{"status": closed.Status, "balance": closed.Balance, "closed_at": closed.ClosedAt}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to close account: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+
+		closure := &bank.AccountClosure{Account: closed}
+		if payout != nil && account.Balance != 0 {
+			if credited, err := bs.payOut(ctx, closure, *account, payout.ID); err != nil {
+				if credited {
+					// The payout account kept the balance, reopening would count it twice.
+					return nil, err
+				}
+				compensateCtx, cancel := compensationContext(ctx)
+				defer cancel()
+				_, reopenErr := collection.UpdateOne(compensateCtx, bson.M{"_id": id}, bson.M{
+					"$set":   bson.M{"status": bank.AccountStatusOpen, "balance": account.Balance},
+					"$unset": bson.M{"closed_at": ""},
+				})
+				if reopenErr != nil {
+					return nil, errors.Join(err, fmt.Errorf("failed to reopen account: %w", reopenErr))
+				}
+				if errors.Is(err, bank.ErrAccountNotFound) {
+					return nil, bank.TransferDestinationNotFoundError(payout.ID)
+				}
+				return nil, err
+			}
+		}
+
+		return closure, nil
+	}
+
+	return nil, fmt.Errorf("failed to close account %s: balance kept changing", id)
+}
+
+// payOut credits the balance of the closed account to payoutAccountID and records the withdrawal and
+// deposit in closure. The credit is taken back when the transactions can't be stored, leaving the
+// closed account for the caller to reopen. credited reports a failure that left the credit in place.
+func (bs *BankStore) payOut(ctx context.Context, closure *bank.AccountClosure, account bank.Account, payoutAccountID string) (credited bool, err error) {
+	if err := bs.updateAccountBalance(ctx, payoutAccountID, account.Balance); err != nil {
+		return false, err
+	}
+
+	reference := bank.ClosureReference(account.ID)
+	withdrawal := newTransaction(account.ID, bank.WithdrawalTransactionType, account.Balance, reference)
+	deposit := newTransaction(payoutAccountID, bank.DepositTransactionType, account.Balance, reference)
+	if _, err := bs.dbClient.Collections[transactionsCollection].InsertMany(ctx, []any{withdrawal, deposit}); err != nil {
+		err = fmt.Errorf("failed to insert transaction: %w", err)
+		compensateCtx, cancel := compensationContext(ctx)
+		defer cancel()
+		// Either record may have been stored before the failure.
+		_, deleteErr := bs.dbClient.Collections[transactionsCollection].DeleteMany(compensateCtx,
+			bson.M{"_id": bson.M{"$in": bson.A{withdrawal.ID, deposit.ID}}})
+		if deleteErr != nil {
+			// The records may be stored, keep the credit they describe.
+			return true, errors.Join(err, fmt.Errorf("failed to delete transactions: %w", deleteErr))
+		}
+		if revertErr := bs.restoreAccountBalance(compensateCtx, payoutAccountID, -account.Balance); revertErr != nil {
+			return true, errors.Join(err, fmt.Errorf("failed to revert payout balance: %w", revertErr))
+		}
+		return false, err
+	}
+
+	closure.Withdrawal = &withdrawal
+	closure.Deposit = &deposit
+	return false, nil
+}
+
+func (bs *BankStore) ListAccounts(ctx context.Context, filter bank.AccountFilter, page bank.PageRequest) (*bank.AccountPage, error) {
+	if err := bank.ValidateAccountFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeAccountPage(page)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	defer cursor.Close(context.Background())
+
+	collection := bs.dbClient.Collections[accountsCollection]
+
+	query := withKeyset(accountFilterDoc(filter), page, cursor)
+	results, err := collection.Find(ctx, query, pageFindOptions(page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer results.Close(ctx)
 
 	// Decode all documents into a slice of bank.Account.
-	var accounts []bank.Account
-	if err := cursor.All(context.Background(), &accounts); err != nil {
-		return nil
+	accounts := make([]bank.Account, 0)
+	if err := results.All(ctx, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+	for i := range accounts {
+		normalizeAccount(&accounts[i])
+	}
+
+	result := &bank.AccountPage{Accounts: accounts}
+	if len(accounts) > page.Limit {
+		result.Accounts = accounts[:page.Limit]
+		last := result.Accounts[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.AccountSortValue(last, page.SortBy), last.ID)
 	}
 
-	return accounts
+	return result, nil
 }
 
-func (bs *BankStore) PerformTransaction(accountID string, txType string, amount float64) (*bank.Transaction, error) {
-	// Fetch the account
-	account, err := bs.GetAccountByID(accountID)
-	if err != nil {
+func (bs *BankStore) PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	if err := bank.ValidateTransaction(txType, amount); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateReference(reference); err != nil {
 		return nil, err
 	}
 
 	// Update the account balance
-	if err := bs.updateAccountBalance(account, txType, amount); err != nil {
+	delta := amount
+	if txType == bank.WithdrawalTransactionType {
+		delta = -amount
+	}
+	if err := bs.updateAccountBalance(ctx, accountID, delta); err != nil {
 		return nil, err
 	}
 
-	// Create the transaction record
-	transaction, err := bs.createTransaction(accountID, txType, amount)
+	// Create the transaction record, giving the money back if it can't be stored
+	// so the balance never changes without its history entry.
+	transaction, err := bs.createTransaction(ctx, accountID, txType, amount, reference)
 	if err != nil {
+		if inTransaction(ctx) {
+			// Aborting the transaction reverts the balance.
+			return nil, err
+		}
+		log.Ctx(ctx).Warn().Err(err).Str("account_id", accountID).Msg("Reverting the balance change of a transaction that couldn't be stored")
+		compensateCtx, cancel := compensationContext(ctx)
+		defer cancel()
+		if revertErr := bs.restoreAccountBalance(compensateCtx, accountID, -delta); revertErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to revert account balance: %w", revertErr))
+		}
 		return nil, err
 	}
 
 	return transaction, nil
-
 }
 
-// updateAccountBalance updates the account balance based on the transaction type
-func (bs *BankStore) updateAccountBalance(account *bank.Account, txType string, amount float64) error {
+// updateAccountBalance atomically adds delta to the account balance. Debits only match while the
+// balance covers them, so concurrent withdrawals can never leave the account negative.
+func (bs *BankStore) updateAccountBalance(ctx context.Context, accountID string, delta float64) error {
 	accountsCollection := bs.dbClient.Collections[accountsCollection]
 
-	if err := account.UpdateBalance(txType, amount); err != nil {
+	filter := bson.M{"_id": accountID, "status": openAccount}
+	if delta < 0 {
+		filter["balance"] = bson.M{"$gte": -delta}
+	}
+
+	result, err := accountsCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"balance": delta}})
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	if result.MatchedCount == 1 {
+		return nil
+	}
+
+	// Nothing matched: the account doesn't exist, is closed or can't cover the debit.
+	account, err := bs.GetAccountByID(ctx, accountID)
+	if err != nil {
 		return err
 	}
+	if account.IsClosed() {
+		return bank.AccountClosedError(accountID)
+	}
+	return bank.InsufficientFundsError(account.ID, account.Balance, -delta)
+}
+
+// restoreAccountBalance adds delta to the balance to undo a change made by updateAccountBalance, even if
+// the account was closed in the meantime. Debits still only match while the balance covers them: undoing
+// a credit that was spent since fails with ErrInsufficientFunds rather than leave the account negative.
+func (bs *BankStore) restoreAccountBalance(ctx context.Context, accountID string, delta float64) error {
+	filter := bson.M{"_id": accountID}
+	if delta < 0 {
+		filter["balance"] = bson.M{"$gte": -delta}
+	}
 
-	_, err := accountsCollection.UpdateOne(context.Background(), bson.M{"_id": account.ID}, bson.M{"$set": bson.M{"balance": account.Balance}})
+	result, err := bs.dbClient.Collections[accountsCollection].UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"balance": delta}})
 	if err != nil {
 		return fmt.Errorf("failed to update account balance: %w", err)
 	}
+	if result.MatchedCount == 1 {
+		return nil
+	}
 
-	return nil
+	account, err := bs.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return bank.InsufficientFundsError(account.ID, account.Balance, -delta)
+}
+
+// normalizeAccount fills in the status of accounts stored before accounts had one.
+func normalizeAccount(account *bank.Account) {
+	if account.Status == "" {
+		account.Status = bank.AccountStatusOpen
+	}
 }
 
 // createTransaction creates a new transaction record and stores it in the database
-func (bs *BankStore) createTransaction(accountID, txType string, amount float64) (*bank.Transaction, error) {
+func (bs *BankStore) createTransaction(ctx context.Context, accountID, txType string, amount float64, reference string) (*bank.Transaction, error) {
 	transactionsCollection := bs.dbClient.Collections[transactionsCollection]
 
-	transaction := bank.Transaction{
+	transaction := newTransaction(accountID, txType, amount, reference)
+	_, err := transactionsCollection.InsertOne(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+// newTransaction builds a transaction record timestamped now.
+func newTransaction(accountID, txType string, amount float64, reference string) bank.Transaction {
+	return bank.Transaction{
 		ID:        uuid.New().String(),
 		AccountID: accountID,
 		Type:      txType,
 		Amount:    amount,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond), // BSON dates only keep milliseconds.
+		Reference: reference,
 	}
+}
+
+func (bs *BankStore) GetTransactionByID(ctx context.Context, id string) (*bank.Transaction, error) {
+	collection := bs.dbClient.Collections[transactionsCollection]
 
-	_, err := transactionsCollection.InsertOne(context.Background(), transaction)
+	var transaction bank.Transaction
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&transaction)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, bank.TransactionNotFoundError(id)
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
 	return &transaction, nil
 }
 
-func (bs *BankStore) GetTransactionsByAccountID(accountID string) ([]bank.Transaction, error) {
+func (bs *BankStore) GetTransactionsByAccountID(ctx context.Context, accountID string, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionPage, error) {
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
+	if err != nil {
+		return nil, err
+	}
+
 	collection := bs.dbClient.Collections[transactionsCollection]
 
 	// Find transactions by the accountID
-	cursor, err := collection.Find(context.Background(), bson.M{"account_id": accountID})
+	query := withKeyset(transactionFilterDoc(accountID, filter), page, cursor)
+	results, err := collection.Find(ctx, query, pageFindOptions(page))
 	if err != nil {
 		return nil, fmt.Errorf("failed to find transactions: %w", err)
 	}
-	defer cursor.Close(context.Background())
+	defer results.Close(ctx)
 
-	var transactions []bank.Transaction
-	if err := cursor.All(context.Background(), &transactions); err != nil {
+	transactions := make([]bank.Transaction, 0)
+	if err := results.All(ctx, &transactions); err != nil {
 		return nil, fmt.Errorf("failed to decode transactions: %w", err)
 	}
 
-	// If no transactions were found, return an error
+	// An empty page is only an error when the account has no history at all.
 	if len(transactions) == 0 {
-		return nil, bank.NoTransactionsForAccountError(accountID)
+		count, err := collection.CountDocuments(ctx, bson.M{"account_id": accountID}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find transactions: %w", err)
+		}
+		if count == 0 {
+			return nil, bank.NoTransactionsForAccountError(accountID)
+		}
 	}
 
-	return transactions, nil
+	result := &bank.TransactionPage{Transactions: transactions}
+	if len(transactions) > page.Limit {
+		result.Transactions = transactions[:page.Limit]
+		last := result.Transactions[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.TransactionSortValue(last, page.SortBy), last.ID)
+	}
+
+	return result, nil
 }
 
-func (bs *BankStore) TransferFunds(fromAccountID, toAccountID string, amount float64) error {
-	// Get both accounts
-	fromAccount, err := bs.GetAccountByID(fromAccountID)
+func (bs *BankStore) SearchTransactions(ctx context.Context, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionSearchPage, error) {
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	toAccount, err := bs.GetAccountByID(toAccountID)
+	collection := bs.dbClient.Collections[transactionsCollection]
+	filterDoc := transactionFilterDoc("", filter)
+
+	summary, err := bs.summarizeTransactions(ctx, filterDoc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := bs.performTransfer(fromAccount, toAccount, amount); err != nil {
-		return err
+	results, err := collection.Find(ctx, withKeyset(filterDoc, page, cursor), pageFindOptions(page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transactions: %w", err)
 	}
+	defer results.Close(ctx)
 
-	return nil
+	transactions := make([]bank.Transaction, 0)
+	if err := results.All(ctx, &transactions); err != nil {
+		return nil, fmt.Errorf("failed to decode transactions: %w", err)
+	}
+
+	result := &bank.TransactionSearchPage{Transactions: transactions, Summary: summary}
+	if len(transactions) > page.Limit {
+		result.Transactions = transactions[:page.Limit]
+		last := result.Transactions[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.TransactionSortValue(last, page.SortBy), last.ID)
+	}
+
+	return result, nil
+}
+
+// summarizeTransactions counts and sums the transactions matching filterDoc, grouped by type.
+func (bs *BankStore) summarizeTransactions(ctx context.Context, filterDoc bson.M) (bank.TransactionSummary, error) {
+	collection := bs.dbClient.Collections[transactionsCollection]
+	summary := bank.NewTransactionSummary()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filterDoc}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$type"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "sum", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+	}
+	results, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+	defer results.Close(ctx)
+
+	var groups []struct {
+		Type  string  `bson:"_id"`
+		Count int     `bson:"count"`
+		Sum   float64 `bson:"sum"`
+	}
+	if err := results.All(ctx, &groups); err != nil {
+		return summary, fmt.Errorf("failed to decode transaction summary: %w", err)
+	}
+	for _, group := range groups {
+		summary.Add(group.Type, group.Count, group.Sum)
+	}
+
+	return summary, nil
 }
 
-func (bs *BankStore) performTransfer(fromAccount, toAccount *bank.Account, amount float64) error {
-	// Withdraw from the source account and deposit to the destination account
-	if err := fromAccount.Withdraw(amount); err != nil {
+func (bs *BankStore) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	if err := bank.ValidateTransfer(fromAccountID, toAccountID, amount); err != nil {
 		return err
 	}
-	toAccount.Deposit(amount)
 
-	// Save the updated accounts back to the database.
-	if err := bs.saveAccount(fromAccount); err != nil {
+	// Check both accounts up front so a missing or closed destination never debits the source.
+	fromAccount, err := bs.GetAccountByID(ctx, fromAccountID)
+	if err != nil {
+		if errors.Is(err, bank.ErrAccountNotFound) {
+			return bank.TransferSourceNotFoundError(fromAccountID)
+		}
+		return err
+	}
+	toAccount, err := bs.GetAccountByID(ctx, toAccountID)
+	if err != nil {
+		if errors.Is(err, bank.ErrAccountNotFound) {
+			return bank.TransferDestinationNotFoundError(toAccountID)
+		}
 		return err
 	}
-	if err := bs.saveAccount(toAccount); err != nil {
+	if fromAccount.IsClosed() {
+		return bank.AccountClosedError(fromAccountID)
+	}
+	if toAccount.IsClosed() {
+		return bank.AccountClosedError(toAccountID)
+	}
+
+	return bs.performTransfer(ctx, fromAccountID, toAccountID, amount)
+}
+
+// performTransfer debits the source and credits the destination with atomic increments. Outside of a
+// transaction a failed credit is compensated by refunding the source.
+func (bs *BankStore) performTransfer(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	if err := bs.updateAccountBalance(ctx, fromAccountID, -amount); err != nil {
+		if errors.Is(err, bank.ErrAccountNotFound) {
+			return bank.TransferSourceNotFoundError(fromAccountID)
+		}
 		return err
 	}
 
+	if err := bs.updateAccountBalance(ctx, toAccountID, amount); err != nil {
+		if inTransaction(ctx) {
+			// Aborting the transaction refunds the source.
+			return translateCreditError(err, toAccountID)
+		}
+		log.Ctx(ctx).Warn().Err(err).Str("from_account_id", fromAccountID).Str("to_account_id", toAccountID).Msg("Refunding the source of a transfer that couldn't be credited")
+		compensateCtx, cancel := compensationContext(ctx)
+		defer cancel()
+		if refundErr := bs.restoreAccountBalance(compensateCtx, fromAccountID, amount); refundErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to refund source account: %w", refundErr))
+		}
+		return translateCreditError(err, toAccountID)
+	}
+
 	return nil
 }
 
-func (bs *BankStore) saveAccount(account *bank.Account) error {
-	collection := bs.dbClient.Collections[accountsCollection]
-	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": account.ID}, bson.M{"$set": account})
+// translateCreditError reports a destination that disappeared before it was credited as such.
+func translateCreditError(err error, toAccountID string) error {
+	if errors.Is(err, bank.ErrAccountNotFound) {
+		return bank.TransferDestinationNotFoundError(toAccountID)
+	}
 	return err
 }
+
+// inTransaction reports whether ctx carries a session running a transaction, whose abort undoes
+// the writes of a failed operation without compensation.
+func inTransaction(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
+
+// compensationContext keeps the values of ctx but not its cancellation: undoing a half-applied
+// operation must still run when the request that started it was canceled or timed out.
+func compensationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+}
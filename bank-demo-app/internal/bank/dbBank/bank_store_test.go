@@ -0,0 +1,25 @@
+package dbBank
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHelloReplySupportsTransactions(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply helloReply
+		want  bool
+	}{
+		{"standalone", helloReply{}, false},
+		{"replica set member", helloReply{SetName: "rs0"}, true},
+		{"mongos", helloReply{Msg: "isdbgrid"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.reply.supportsTransactions())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (bs *BankStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	if !batch.Atomic {
+		if err := bank.ValidateBatch(batch); err != nil {
+			return nil, err
+		}
+		return bank.ExecuteBestEffort(ctx, bs, batch.Operations), nil
+	}
+
+	if err := bank.ValidateAtomicBatch(batch); err != nil {
+		return nil, err
+	}
+	if !bs.transactions {
+		return nil, bank.AtomicBatchUnsupportedError("the MongoDB deployment is a standalone server, atomic batches need a replica set or a sharded cluster")
+	}
+
+	session, err := bs.dbClient.Client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	// The operations run in one multi-document transaction, aborted on the first failure. The driver
+	// retries the whole callback on transient errors, so it starts over each time.
+	transactions, err := session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (any, error) {
+		transactions := make([]*bank.Transaction, 0, len(batch.Operations))
+		for i, operation := range batch.Operations {
+			transaction, err := bank.ApplyBatchOperation(sessionCtx, bs, operation)
+			if err != nil {
+				return nil, &bank.BatchOperationError{Index: i, Err: err}
+			}
+			transactions = append(transactions, transaction)
+		}
+		return transactions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bank.NewAtomicBatchResult(transactions.([]*bank.Transaction)), nil
+}
@@ -0,0 +1,55 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank/storeConformance"
+	"bank-demo-app/internal/mongodb"
+	"bank-demo-app/internal/mongodb/mongoTest"
+	"bank-demo-app/internal/restServer"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// mongodPort is the port of the mongod spawned by TestMain, empty when none could be started.
+var mongodPort string
+
+// TestMain spawns a throwaway mongod (from $MONGOD_PATH or the PATH) for the package tests.
+// Without one the Mongo-backed tests are skipped rather than failed.
+func TestMain(m *testing.M) {
+	port, stop, err := mongoTest.StartMongod()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dbBank: skipping MongoDB tests: %v\n", err)
+	}
+	mongodPort = port
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+func TestBankStoreConformance(t *testing.T) {
+	if mongodPort == "" {
+		t.Skip("mongod is not available")
+	}
+
+	storeConformance.Run(t, func(t *testing.T) restServer.BankStore {
+		dbConf := &mongodb.MongoConfig{
+			Host:   "127.0.0.1",
+			Port:   mongodPort,
+			DbName: "conformance_" + uuid.New().String()[:8],
+		}
+		bankStore, err := NewBankStore(context.Background(), dbConf)
+		if err != nil {
+			t.Fatalf("failed to connect to mongod on port %s: %v", mongodPort, err)
+		}
+		t.Cleanup(func() {
+			ctx := context.Background()
+			bankStore.dbClient.Client.Database(dbConf.DbName).Drop(ctx)
+			bankStore.Close()
+		})
+		return bankStore
+	})
+}
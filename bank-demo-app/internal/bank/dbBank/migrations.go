@@ -0,0 +1,176 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/mongodb"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations bring a database up to the schema the store expects. They run when the store is
+// opened and with the migrate subcommand. Append new ones, never change an applied one.
+var Migrations = []mongodb.Migration{
+	{
+		Version:     1,
+		Description: "Create the accounts, transactions and api_keys collections",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{accountsCollection, transactionsCollection, apiKeysCollection} {
+				if err := mongodb.CreateCollection(ctx, db, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "Validate accounts, transactions and API keys against JSON schemas",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{accountsCollection, transactionsCollection, apiKeysCollection} {
+				if err := mongodb.SetValidator(ctx, db, name, collectionSchemas[name]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "Index the list and search queries, transactions by account_id and timestamp first",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{accountsCollection, transactionsCollection, apiKeysCollection} {
+				// Creating an index that already exists is a no-op.
+				if _, err := db.Collection(name).Indexes().CreateMany(ctx, collectionIndexes[name]); err != nil {
+					return fmt.Errorf("failed to create indexes on %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     4,
+		Description: "Create, validate and index the webhooks and webhook_deliveries collections",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{webhooksCollection, deliveriesCollection} {
+				if err := mongodb.CreateCollection(ctx, db, name); err != nil {
+					return err
+				}
+				if err := mongodb.SetValidator(ctx, db, name, collectionSchemas[name]); err != nil {
+					return err
+				}
+				if _, err := db.Collection(name).Indexes().CreateMany(ctx, collectionIndexes[name]); err != nil {
+					return fmt.Errorf("failed to create indexes on %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// numberType accepts every BSON number a balance or amount may be stored as.
+var numberType = bson.A{"double", "int", "long", "decimal"}
+
+// collectionSchemas are the JSON schemas of the stored documents. Accounts stored before they
+// had a status remain valid.
+var collectionSchemas = map[string]bson.M{
+	accountsCollection: {
+		"bsonType": "object",
+		"required": bson.A{"_id", "owner", "balance"},
+		"properties": bson.M{
+			"_id":       bson.M{"bsonType": "string"},
+			"owner":     bson.M{"bsonType": "string"},
+			"balance":   bson.M{"bsonType": numberType},
+			"status":    bson.M{"enum": bson.A{bank.AccountStatusOpen, bank.AccountStatusClosed}},
+			"metadata":  bson.M{"bsonType": "object", "additionalProperties": bson.M{"bsonType": "string"}},
+			"closed_at": bson.M{"bsonType": "date"},
+		},
+	},
+	transactionsCollection: {
+		"bsonType": "object",
+		"required": bson.A{"_id", "account_id", "type", "amount", "timestamp"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "string"},
+			"account_id": bson.M{"bsonType": "string"},
+			"type":       bson.M{"enum": bson.A{bank.DepositTransactionType, bank.WithdrawalTransactionType}},
+			"amount":     bson.M{"bsonType": numberType},
+			"timestamp":  bson.M{"bsonType": "date"},
+			"reference":  bson.M{"bsonType": "string"},
+		},
+	},
+	apiKeysCollection: {
+		"bsonType": "object",
+		"required": bson.A{"_id", "name", "prefix", "hash", "scopes", "created_at"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "string"},
+			"name":       bson.M{"bsonType": "string"},
+			"prefix":     bson.M{"bsonType": "string"},
+			"hash":       bson.M{"bsonType": "string"},
+			"scopes":     bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string"}},
+			"created_at": bson.M{"bsonType": "date"},
+			"revoked_at": bson.M{"bsonType": "date"},
+		},
+	},
+	webhooksCollection: {
+		"bsonType": "object",
+		"required": bson.A{"_id", "url", "events", "secret", "created_at"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "string"},
+			"url":        bson.M{"bsonType": "string"},
+			"events":     bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string"}},
+			"secret":     bson.M{"bsonType": "string"},
+			"created_at": bson.M{"bsonType": "date"},
+		},
+	},
+	deliveriesCollection: {
+		"bsonType": "object",
+		"required": bson.A{"_id", "webhook_id", "event_id", "event_type", "payload", "status", "next_attempt_at", "created_at"},
+		"properties": bson.M{
+			"_id":             bson.M{"bsonType": "string"},
+			"webhook_id":      bson.M{"bsonType": "string"},
+			"event_id":        bson.M{"bsonType": "string"},
+			"event_type":      bson.M{"bsonType": "string"},
+			"payload":         bson.M{"bsonType": "binData"},
+			"status":          bson.M{"enum": bson.A{bank.WebhookDeliveryPending, bank.WebhookDeliverySucceeded, bank.WebhookDeliveryDead}},
+			"attempts":        bson.M{"bsonType": bson.A{"array", "null"}},
+			"next_attempt_at": bson.M{"bsonType": "date"},
+			"created_at":      bson.M{"bsonType": "date"},
+			"completed_at":    bson.M{"bsonType": "date"},
+		},
+	},
+}
+
+// collectionIndexes lists the indexes backing the sorted and filtered list queries. Each one ends with
+// _id so keyset pagination on (sort field, _id) is answered from the index without an in-memory sort.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	accountsCollection: {
+		{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "balance", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	transactionsCollection: {
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "amount", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "type", Value: 1}, {Key: "timestamp", Value: 1}}},
+		// Cross-account search, sorted by time or amount and optionally narrowed by type.
+		{Keys: bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "amount", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "amount", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	apiKeysCollection: {
+		// Every authenticated request looks its key up by hash.
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	webhooksCollection: {
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	deliveriesCollection: {
+		// The dispatcher claims pending deliveries by due time, the delivery log lists them per webhook.
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+}
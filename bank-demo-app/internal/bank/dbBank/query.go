@@ -0,0 +1,121 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sortFields maps the bank sort fields to their document fields.
+var sortFields = map[string]string{
+	bank.AccountSortID:            "_id",
+	bank.AccountSortOwner:         "owner",
+	bank.AccountSortBalance:       "balance",
+	bank.TransactionSortTimestamp: "timestamp",
+	bank.TransactionSortAmount:    "amount",
+}
+
+func accountFilterDoc(filter bank.AccountFilter) bson.M {
+	doc := bson.M{}
+	if filter.Owner != "" {
+		doc["owner"] = filter.Owner
+	}
+	if balance := rangeDoc(filter.MinBalance, filter.MaxBalance); len(balance) > 0 {
+		doc["balance"] = balance
+	}
+	return doc
+}
+
+// transactionFilterDoc builds the query for filter, restricted to accountID unless it is empty.
+func transactionFilterDoc(accountID string, filter bank.TransactionFilter) bson.M {
+	doc := bson.M{}
+	switch {
+	case accountID != "" && len(filter.AccountIDs) > 0:
+		doc["account_id"] = bson.M{"$eq": accountID, "$in": filter.AccountIDs}
+	case accountID != "":
+		doc["account_id"] = accountID
+	case len(filter.AccountIDs) > 0:
+		doc["account_id"] = bson.M{"$in": filter.AccountIDs}
+	}
+	if filter.Type != "" {
+		doc["type"] = filter.Type
+	}
+	if amount := rangeDoc(filter.MinAmount, filter.MaxAmount); len(amount) > 0 {
+		doc["amount"] = amount
+	}
+	timestamp := bson.M{}
+	if filter.From != nil {
+		timestamp["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		timestamp["$lt"] = *filter.To
+	}
+	if len(timestamp) > 0 {
+		doc["timestamp"] = timestamp
+	}
+	if filter.Reference != "" {
+		// An unanchored regex can't use an index; the other conditions narrow the scan first.
+		doc["reference"] = bson.M{"$regex": regexp.QuoteMeta(filter.Reference), "$options": "i"}
+	}
+	return doc
+}
+
+func rangeDoc(min, max *float64) bson.M {
+	doc := bson.M{}
+	if min != nil {
+		doc["$gte"] = *min
+	}
+	if max != nil {
+		doc["$lte"] = *max
+	}
+	return doc
+}
+
+// withKeyset adds the condition that resumes after the cursor position on (sort field, _id).
+func withKeyset(filter bson.M, page bank.PageRequest, cursor *bank.Cursor) bson.M {
+	if cursor == nil {
+		return filter
+	}
+
+	operator := "$gt"
+	if page.Order == bank.SortDescending {
+		operator = "$lt"
+	}
+
+	field := sortFields[page.SortBy]
+	var keyset bson.M
+	if field == "_id" {
+		keyset = bson.M{"_id": bson.M{operator: cursor.ID}}
+	} else {
+		// The cursor was validated by the caller, so its value has the right type.
+		value, _ := cursor.TypedValue()
+		keyset = bson.M{"$or": bson.A{
+			bson.M{field: bson.M{operator: value}},
+			bson.M{field: value, "_id": bson.M{operator: cursor.ID}},
+		}}
+	}
+
+	if len(filter) == 0 {
+		return keyset
+	}
+	return bson.M{"$and": bson.A{filter, keyset}}
+}
+
+// pageFindOptions sorts by (sort field, _id) and asks for one extra document to know whether
+// another page follows.
+func pageFindOptions(page bank.PageRequest) *options.FindOptions {
+	direction := 1
+	if page.Order == bank.SortDescending {
+		direction = -1
+	}
+
+	field := sortFields[page.SortBy]
+	sort := bson.D{{Key: field, Value: direction}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+
+	return options.Find().SetSort(sort).SetLimit(int64(page.Limit + 1))
+}
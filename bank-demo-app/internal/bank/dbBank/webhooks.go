@@ -0,0 +1,177 @@
+package dbBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (bs *BankStore) CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error) {
+	if err := bank.ValidateWebhookInput(webhook.URL, webhook.Events); err != nil {
+		return nil, err
+	}
+
+	webhook.CreatedAt = webhook.CreatedAt.Truncate(time.Millisecond) // BSON dates only keep milliseconds.
+
+	if _, err := bs.dbClient.Collections[webhooksCollection].InsertOne(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (bs *BankStore) GetWebhook(ctx context.Context, id string) (*bank.Webhook, error) {
+	var webhook bank.Webhook
+	err := bs.dbClient.Collections[webhooksCollection].FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, bank.WebhookNotFoundError(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (bs *BankStore) ListWebhooks(ctx context.Context) ([]bank.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := bs.dbClient.Collections[webhooksCollection].Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []bank.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to decode webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook, then its deliveries. Deliveries left behind by a failure in
+// between are marked dead by the dispatcher, which no longer finds their webhook.
+func (bs *BankStore) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := bs.dbClient.Collections[webhooksCollection].DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return bank.WebhookNotFoundError(id)
+	}
+
+	if _, err := bs.dbClient.Collections[deliveriesCollection].DeleteMany(ctx, bson.M{"webhook_id": id}); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (bs *BankStore) EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	documents := make([]any, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		documents = append(documents, truncateDelivery(delivery))
+	}
+	if _, err := bs.dbClient.Collections[deliveriesCollection].InsertMany(ctx, documents); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, the longest overdue
+// first, and postpones them by lease. Each delivery is only claimed if its due time is still the
+// one read, so two dispatchers never claim the same delivery.
+func (bs *BankStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error) {
+	collection := bs.dbClient.Collections[deliveriesCollection]
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, bson.M{
+		"status":          bank.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	due := []bank.WebhookDelivery{}
+	if err := cursor.All(ctx, &due); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+
+	nextAttemptAt := now.Add(lease).UTC().Truncate(time.Millisecond)
+	claimed := make([]bank.WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": delivery.ID, "status": bank.WebhookDeliveryPending, "next_attempt_at": delivery.NextAttemptAt},
+			bson.M{"$set": bson.M{"next_attempt_at": nextAttemptAt}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+		}
+		if result.ModifiedCount == 1 {
+			delivery.NextAttemptAt = nextAttemptAt
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+// UpdateWebhookDelivery replaces the delivery. Deliveries of a webhook deleted in the meantime
+// are gone and stay so.
+func (bs *BankStore) UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error {
+	_, err := bs.dbClient.Collections[deliveriesCollection].ReplaceOne(ctx, bson.M{"_id": delivery.ID}, truncateDelivery(delivery))
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the deliveries of the webhook matching filter, newest first.
+func (bs *BankStore) ListWebhookDeliveries(ctx context.Context, webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error) {
+	if err := bank.ValidateWebhookDeliveryFilter(&filter); err != nil {
+		return nil, err
+	}
+	if _, err := bs.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"webhook_id": webhookID}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(filter.Limit))
+	cursor, err := bs.dbClient.Collections[deliveriesCollection].Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []bank.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// truncateDelivery rounds the times of delivery to milliseconds, the precision of BSON dates.
+func truncateDelivery(delivery bank.WebhookDelivery) bank.WebhookDelivery {
+	delivery.NextAttemptAt = delivery.NextAttemptAt.UTC().Truncate(time.Millisecond)
+	delivery.CreatedAt = delivery.CreatedAt.UTC().Truncate(time.Millisecond)
+	if delivery.CompletedAt != nil {
+		completedAt := delivery.CompletedAt.UTC().Truncate(time.Millisecond)
+		delivery.CompletedAt = &completedAt
+	}
+	return delivery
+}
@@ -10,22 +10,50 @@ var (
 	ErrAccountNotFound          = errors.New("account not found")
 	ErrEmptyOwnerName           = errors.New("owner name cannot be empty")
 	ErrNoTransactionsForAccount = errors.New("no transactions found in provided account")
+	ErrAccountClosed            = errors.New("account is closed")
+	ErrAccountBalanceNotZero    = errors.New("account balance must be zero to close it without a payout account")
+	ErrInvalidMetadata          = errors.New("invalid account metadata")
 
 	// Transaction errors.
 	ErrTransactionNotFound     = errors.New("transaction not found")
 	ErrInvalidTransaction      = errors.New("invalid transaction type")
 	ErrTransactionTypeRequired = errors.New("transaction type is required")
 	ErrZeroTransactionAmount   = errors.New("transaction amount must be greater than zero")
+	ErrReferenceTooLong        = errors.New("transaction reference is too long")
 
 	// Balance errors
 	ErrNegativeAmount         = errors.New("amount must be greater than zero")
+	ErrAmountNotFinite        = errors.New("amount must be a finite number")
 	ErrNegativeInitialBalance = errors.New("initial balance cannot be negative")
 	ErrInsufficientFunds      = errors.New("insufficient funds")
 
 	// Transfer errors
 	ErrTransferSourceNotFound      = errors.New("transfer account source not found")
-	ErrTransferDestinationNotFound = errors.New("transfer account source not found")
+	ErrTransferDestinationNotFound = errors.New("transfer account destination not found")
 	ErrSameSourceDestination       = errors.New("source and destination cannot be the same")
+
+	// Query errors
+	ErrInvalidCursor    = errors.New("invalid pagination cursor")
+	ErrInvalidPageLimit = errors.New("invalid page limit")
+	ErrInvalidSortField = errors.New("invalid sort field")
+	ErrInvalidSortOrder = errors.New("invalid sort order")
+	ErrInvalidFilter    = errors.New("invalid filter")
+
+	// Batch errors
+	ErrInvalidBatch           = errors.New("invalid batch")
+	ErrAtomicBatchUnsupported = errors.New("atomic batches are not supported by this store")
+
+	// Import errors
+	ErrInvalidImportFile = errors.New("invalid import file")
+	ErrInvalidImportRow  = errors.New("invalid import row")
+
+	// API key errors
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+
+	// Webhook errors
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
 )
 
 // Helper functions for error wrapping.
@@ -39,6 +67,18 @@ func NoTransactionsForAccountError(accountID string) error {
 	return fmt.Errorf("%w: account ID %s", ErrNoTransactionsForAccount, accountID)
 }
 
+func AccountClosedError(accountID string) error {
+	return fmt.Errorf("%w: account ID %s", ErrAccountClosed, accountID)
+}
+
+func AccountBalanceNotZeroError(accountID string, balance float64) error {
+	return fmt.Errorf("%w: account ID %s, balance %.2f", ErrAccountBalanceNotZero, accountID, balance)
+}
+
+func InvalidMetadataError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMetadata, reason)
+}
+
 // Transaction errors.
 func TransactionNotFoundError(transactionID string) error {
 	return fmt.Errorf("%w: transaction ID %s", ErrTransactionNotFound, transactionID)
@@ -48,6 +88,10 @@ func InvalidTransactionError(transactionType string) error {
 	return fmt.Errorf("%w: transaction type %s", ErrInvalidTransaction, transactionType)
 }
 
+func ReferenceTooLongError(length int) error {
+	return fmt.Errorf("%w: %d characters, maximum %d", ErrReferenceTooLong, length, MaxReferenceLength)
+}
+
 // Balance errors.
 func NegativeAmountError(amount float64) error {
 	return fmt.Errorf("%w: amount %.2f", ErrNegativeAmount, amount)
@@ -73,3 +117,56 @@ func TransferDestinationNotFoundError(accountID string) error {
 func SameSourceDestinationAccountError(accountID string) error {
 	return fmt.Errorf("%w: account ID %s", ErrSameSourceDestination, accountID)
 }
+
+// Query errors.
+func InvalidPageLimitError(limit int) error {
+	return fmt.Errorf("%w: limit %d, expected 1 to %d", ErrInvalidPageLimit, limit, MaxPageLimit)
+}
+
+func InvalidSortFieldError(field string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSortField, field)
+}
+
+func InvalidSortOrderError(order string) error {
+	return fmt.Errorf("%w: %s, expected %s or %s", ErrInvalidSortOrder, order, SortAscending, SortDescending)
+}
+
+func InvalidFilterError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidFilter, reason)
+}
+
+// Batch errors.
+func InvalidBatchError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidBatch, reason)
+}
+
+func AtomicBatchUnsupportedError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrAtomicBatchUnsupported, reason)
+}
+
+// Import errors.
+func InvalidImportFileError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidImportFile, reason)
+}
+
+func InvalidImportRowError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidImportRow, reason)
+}
+
+// API key errors.
+func APIKeyNotFoundError(keyID string) error {
+	return fmt.Errorf("%w: api key ID %s", ErrAPIKeyNotFound, keyID)
+}
+
+func InvalidAPIKeyError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAPIKey, reason)
+}
+
+// Webhook errors.
+func WebhookNotFoundError(webhookID string) error {
+	return fmt.Errorf("%w: webhook ID %s", ErrWebhookNotFound, webhookID)
+}
+
+func InvalidWebhookError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWebhook, reason)
+}
@@ -3,7 +3,10 @@ package memoryBank
 import (
 	"bank-demo-app/internal/bank"
 
+	"maps"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +14,7 @@ import (
 type AccountManager struct {
 	mu       sync.RWMutex            // Protect from race conditions.
 	Accounts map[string]bank.Account // Keyed by AccountID. Using map because it's faster than an slice to get speciffic account.
+	ids      []string                // Every account ID in order, so pages sorted by ID don't sort.
 }
 
 func (am *AccountManager) CreateAccount(owner string, initialBalance float64) (*bank.Account, error) {
@@ -20,10 +24,12 @@ func (am *AccountManager) CreateAccount(owner string, initialBalance float64) (*
 		ID:      uuid.New().String(),
 		Owner:   owner,
 		Balance: initialBalance,
+		Status:  bank.AccountStatusOpen,
 	}
 
 	am.mu.Lock()
 	am.Accounts[account.ID] = account
+	am.ids = insertSorted(am.ids, account.ID, strings.Compare)
 	am.mu.Unlock()
 
 	return &account, nil
@@ -35,21 +41,37 @@ func (am *AccountManager) GetAccountByID(accountID string) (*bank.Account, error
 	am.mu.RUnlock()
 
 	if !exists {
-		return &bank.Account{}, bank.AccountNotFoundError(accountID)
+		return nil, bank.AccountNotFoundError(accountID)
 	}
 
 	return &account, nil
 }
 
-func (am *AccountManager) ListAccounts() []bank.Account {
+// PageAccounts returns one page of the accounts that match filter and the cursor of the next one.
+// Pages sorted by ID walk the ordered IDs from the cursor on, the other sorts need a sorted copy
+// of the matching accounts.
+func (am *AccountManager) PageAccounts(filter bank.AccountFilter, page bank.PageRequest, cursor *bank.Cursor) ([]bank.Account, string) {
+	compare := compareAccounts(page.SortBy)
+	after := accountAtCursor(cursor)
+	cursorOf := func(account bank.Account) string {
+		return bank.NewCursor(page, bank.AccountSortValue(account, page.SortBy), account.ID)
+	}
+
 	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	if page.SortBy == bank.AccountSortID {
+		at := func(i int) bank.Account { return am.Accounts[am.ids[i]] }
+		return paginate(len(am.ids), at, compare, page, after, filter.Matches, cursorOf)
+	}
+
 	accounts := make([]bank.Account, 0, len(am.Accounts))
 	for _, account := range am.Accounts {
-		accounts = append(accounts, account)
+		if filter.Matches(account) {
+			accounts = append(accounts, account)
+		}
 	}
-	am.mu.RUnlock()
-
-	return accounts
+	return paginateSlice(accounts, compare, page, after, cursorOf)
 }
 
 func (am *AccountManager) TransferBetweenAccounts(fromAccountID, toAccountID string, amount float64) error {
@@ -66,6 +88,13 @@ func (am *AccountManager) TransferBetweenAccounts(fromAccountID, toAccountID str
 		return bank.TransferDestinationNotFoundError(toAccountID)
 	}
 
+	if fromAccount.IsClosed() {
+		return bank.AccountClosedError(fromAccountID)
+	}
+	if toAccount.IsClosed() {
+		return bank.AccountClosedError(toAccountID)
+	}
+
 	// Withdraw from one account and deposit into the other one.
 	if err := fromAccount.Withdraw(amount); err != nil {
 		return err
@@ -78,13 +107,144 @@ func (am *AccountManager) TransferBetweenAccounts(fromAccountID, toAccountID str
 	return nil
 }
 
-func (am *AccountManager) PerformTransaction(account *bank.Account, transactionType string, amount float64) error {
+// PerformTransaction reads and updates the account under the same lock so concurrent
+// transactions on one account can't overwrite each other's balance.
+func (am *AccountManager) PerformTransaction(accountID string, transactionType string, amount float64) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
+	account, exists := am.Accounts[accountID]
+	if !exists {
+		return bank.AccountNotFoundError(accountID)
+	}
+	if account.IsClosed() {
+		return bank.AccountClosedError(accountID)
+	}
+
 	if err := account.UpdateBalance(transactionType, amount); err != nil {
 		return err
 	}
-	am.Accounts[account.ID] = *account
+	am.Accounts[accountID] = account
+	return nil
+}
+
+func (am *AccountManager) UpdateAccount(accountID string, update bank.AccountUpdate) (*bank.Account, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	account, exists := am.Accounts[accountID]
+	if !exists {
+		return nil, bank.AccountNotFoundError(accountID)
+	}
+	if account.IsClosed() {
+		return nil, bank.AccountClosedError(accountID)
+	}
+
+	updated := update.Apply(account)
+	if err := bank.ValidateMetadata(updated.Metadata); err != nil {
+		return nil, err
+	}
+	am.Accounts[accountID] = updated
+
+	return &updated, nil
+}
+
+// CloseAccount closes the account, first moving its balance to payoutAccountID when one is given,
+// and returns the balance paid out. Both happen under the same lock, so the balance can't change
+// in between.
+func (am *AccountManager) CloseAccount(accountID, payoutAccountID string) (*bank.Account, float64, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	account, exists := am.Accounts[accountID]
+	if !exists {
+		return nil, 0, bank.AccountNotFoundError(accountID)
+	}
+
+	var payout *bank.Account
+	if payoutAccountID != "" {
+		payoutAccount, exists := am.Accounts[payoutAccountID]
+		if !exists {
+			return nil, 0, bank.TransferDestinationNotFoundError(payoutAccountID)
+		}
+		payout = &payoutAccount
+	}
+
+	if err := bank.ValidateClosure(account, payout); err != nil {
+		return nil, 0, err
+	}
+
+	if payout != nil {
+		payout.Deposit(account.Balance)
+		am.Accounts[payout.ID] = *payout
+	}
+	closed := bank.CloseAccount(account, time.Now().UTC())
+	am.Accounts[accountID] = closed
+
+	return &closed, account.Balance, nil
+}
+
+// ApplyBatch applies the balance changes of every operation or of none. Changes are staged on copies
+// of the accounts and only written back once the last operation succeeded.
+func (am *AccountManager) ApplyBatch(operations []bank.BatchOperation) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	staged := make(map[string]bank.Account)
+	lookup := func(accountID string) (bank.Account, bool) {
+		if account, exists := staged[accountID]; exists {
+			return account, true
+		}
+		account, exists := am.Accounts[accountID]
+		return account, exists
+	}
+
+	for i, operation := range operations {
+		if err := stageOperation(staged, lookup, operation); err != nil {
+			return &bank.BatchOperationError{Index: i, Err: err}
+		}
+	}
+
+	maps.Copy(am.Accounts, staged)
+	return nil
+}
+
+func stageOperation(staged map[string]bank.Account, lookup func(string) (bank.Account, bool), operation bank.BatchOperation) error {
+	if operation.Type != bank.BatchTransfer {
+		account, exists := lookup(operation.AccountID)
+		if !exists {
+			return bank.AccountNotFoundError(operation.AccountID)
+		}
+		if account.IsClosed() {
+			return bank.AccountClosedError(operation.AccountID)
+		}
+		if err := account.UpdateBalance(operation.Type, operation.Amount); err != nil {
+			return err
+		}
+		staged[account.ID] = account
+		return nil
+	}
+
+	fromAccount, exists := lookup(operation.FromAccountID)
+	if !exists {
+		return bank.TransferSourceNotFoundError(operation.FromAccountID)
+	}
+	toAccount, exists := lookup(operation.ToAccountID)
+	if !exists {
+		return bank.TransferDestinationNotFoundError(operation.ToAccountID)
+	}
+	if fromAccount.IsClosed() {
+		return bank.AccountClosedError(operation.FromAccountID)
+	}
+	if toAccount.IsClosed() {
+		return bank.AccountClosedError(operation.ToAccountID)
+	}
+
+	if err := fromAccount.Withdraw(operation.Amount); err != nil {
+		return err
+	}
+	toAccount.Deposit(operation.Amount)
+	staged[fromAccount.ID] = fromAccount
+	staged[toAccount.ID] = toAccount
 	return nil
 }
@@ -0,0 +1,74 @@
+package memoryBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"slices"
+	"sync"
+	"time"
+)
+
+type APIKeyManager struct {
+	mu     sync.RWMutex           // Protect against race conditions
+	Keys   map[string]bank.APIKey // Keyed by key ID
+	byHash map[string]string      // Key IDs keyed by token hash
+}
+
+func NewAPIKeyManager() *APIKeyManager {
+	return &APIKeyManager{
+		Keys:   make(map[string]bank.APIKey),
+		byHash: make(map[string]string),
+	}
+}
+
+func (km *APIKeyManager) CreateAPIKey(key bank.APIKey) (*bank.APIKey, error) {
+	key.Scopes = slices.Clone(key.Scopes)
+
+	km.mu.Lock()
+	km.Keys[key.ID] = key
+	km.byHash[key.Hash] = key.ID
+	km.mu.Unlock()
+
+	return &key, nil
+}
+
+func (km *APIKeyManager) GetAPIKeyByHash(hash string) (*bank.APIKey, error) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	id, exists := km.byHash[hash]
+	if !exists {
+		return nil, bank.ErrAPIKeyNotFound
+	}
+	key := km.Keys[id]
+	return &key, nil
+}
+
+// ListAPIKeys returns every key, revoked ones included, oldest first.
+func (km *APIKeyManager) ListAPIKeys() []bank.APIKey {
+	km.mu.RLock()
+	keys := make([]bank.APIKey, 0, len(km.Keys))
+	for _, key := range km.Keys {
+		keys = append(keys, key)
+	}
+	km.mu.RUnlock()
+
+	slices.SortFunc(keys, bank.CompareAPIKeys)
+	return keys
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking it again keeps the first revocation time.
+func (km *APIKeyManager) RevokeAPIKey(id string, revokedAt time.Time) (*bank.APIKey, error) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	key, exists := km.Keys[id]
+	if !exists {
+		return nil, bank.APIKeyNotFoundError(id)
+	}
+	if !key.IsRevoked() {
+		revokedAt = revokedAt.UTC()
+		key.RevokedAt = &revokedAt
+		km.Keys[id] = key
+	}
+	return &key, nil
+}
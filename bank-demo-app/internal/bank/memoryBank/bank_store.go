@@ -1,10 +1,18 @@
 package memoryBank
 
-import "bank-demo-app/internal/bank"
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"time"
+)
 
 type BankStore struct {
 	accManager      *AccountManager
 	transactManager *TransactionManager
+	keyManager      *APIKeyManager
+	webhookManager  *WebhookManager
+	// outbox, when set, lists the webhooks the deliveries of every write are queued for.
+	outbox bank.Outbox
 }
 
 func NewBankStore() *BankStore {
@@ -13,46 +21,357 @@ func NewBankStore() *BankStore {
 		Accounts: make(map[string]bank.Account),
 	}
 
-	transactManager := &TransactionManager{
-		Transactions: make(map[string][]bank.Transaction),
-	}
+	transactManager := NewTransactionManager()
 
 	// Create and return the BankStore with both managers
 	return &BankStore{
 		accManager:      accManager,
 		transactManager: transactManager,
+		keyManager:      NewAPIKeyManager(),
+		webhookManager:  NewWebhookManager(),
+	}
+}
+
+// SetOutbox has the writes queue the deliveries of the events they cause, before returning.
+// It must be called before the store is used.
+func (bs *BankStore) SetOutbox(outbox bank.Outbox) {
+	bs.outbox = outbox
+}
+
+// subscriptions lists the webhooks before a write, so that nothing can fail once it is made.
+func (bs *BankStore) subscriptions(ctx context.Context) ([]bank.Webhook, error) {
+	if bs.outbox == nil {
+		return nil, nil
+	}
+	return bs.outbox.Subscriptions(ctx)
+}
+
+// queue enqueues the deliveries of events for webhooks under the lock of the webhook manager, so
+// webhooks deleted since they were listed get none.
+func (bs *BankStore) queue(webhooks []bank.Webhook, events ...bank.Event) error {
+	deliveries, err := bank.NewWebhookDeliveries(webhooks, events, time.Now())
+	if err != nil {
+		return err
 	}
+	bs.webhookManager.EnqueueWebhookDeliveries(deliveries)
+	return nil
 }
 
-func (bs *BankStore) CreateAccount(owner string, initialBalance float64) (*bank.Account, error) {
-	return bs.accManager.CreateAccount(owner, initialBalance)
+// Operations on memory never block, so each method only checks that ctx is still live
+// before touching the managers.
+
+func (bs *BankStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateAccountInput(owner, initialBalance); err != nil {
+		return nil, err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := bs.accManager.CreateAccount(owner, initialBalance)
+	if err != nil {
+		return nil, err
+	}
+	if err := bs.queue(webhooks, bank.AccountCreatedEvent(account)); err != nil {
+		return nil, err
+	}
+	return account, nil
 }
 
-func (bs *BankStore) GetAccountByID(id string) (*bank.Account, error) {
+func (bs *BankStore) GetAccountByID(ctx context.Context, id string) (*bank.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return bs.accManager.GetAccountByID(id)
 }
 
-func (bs *BankStore) ListAccounts() []bank.Account {
-	return bs.accManager.ListAccounts()
+func (bs *BankStore) UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+	return bs.accManager.UpdateAccount(id, update)
+}
+
+func (bs *BankStore) CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closed, paidOut, err := bs.accManager.CloseAccount(id, payoutAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	closure := &bank.AccountClosure{Account: *closed}
+	if paidOut == 0 {
+		return closure, nil
+	}
+	// Balances are committed, record the payout on both accounts.
+	reference := bank.ClosureReference(id)
+	if closure.Withdrawal, err = bs.transactManager.CreateTransaction(id, bank.WithdrawalTransactionType, paidOut, reference); err != nil {
+		return nil, err
+	}
+	if closure.Deposit, err = bs.transactManager.CreateTransaction(payoutAccountID, bank.DepositTransactionType, paidOut, reference); err != nil {
+		return nil, err
+	}
+	if err := bs.queue(webhooks, bank.ClosureEvents(closure)...); err != nil {
+		return nil, err
+	}
+	return closure, nil
+}
+
+func (bs *BankStore) ListAccounts(ctx context.Context, filter bank.AccountFilter, page bank.PageRequest) (*bank.AccountPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateAccountFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeAccountPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, nextCursor := bs.accManager.PageAccounts(filter, page, cursor)
+
+	return &bank.AccountPage{Accounts: accounts, NextCursor: nextCursor}, nil
+}
+
+func (bs *BankStore) PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateTransaction(txType, amount); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateReference(reference); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bs.accManager.PerformTransaction(accountID, txType, amount); err != nil {
+		return nil, err
+	}
+
+	transaction, err := bs.transactManager.CreateTransaction(accountID, txType, amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	if err := bs.queue(webhooks, bank.TransactionCreatedEvent(transaction)); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (bs *BankStore) GetTransactionByID(ctx context.Context, id string) (*bank.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.transactManager.GetTransactionByID(id)
+}
+
+func (bs *BankStore) GetTransactionsByAccountID(ctx context.Context, accountID string, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, nextCursor, err := bs.transactManager.PageTransactions(accountID, filter, page, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bank.TransactionPage{Transactions: transactions, NextCursor: nextCursor}, nil
+}
+
+func (bs *BankStore) SearchTransactions(ctx context.Context, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionSearchPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, nextCursor, summary := bs.transactManager.SearchTransactions(filter, page, cursor)
+
+	return &bank.TransactionSearchPage{Transactions: transactions, NextCursor: nextCursor, Summary: summary}, nil
 }
 
-func (bs *BankStore) PerformTransaction(accountID string, txType string, amount float64) (*bank.Transaction, error) {
-	account, err := bs.accManager.GetAccountByID(accountID)
+func (bs *BankStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !batch.Atomic {
+		if err := bank.ValidateBatch(batch); err != nil {
+			return nil, err
+		}
+		return bank.ExecuteBestEffort(ctx, bs, batch.Operations), nil
+	}
+
+	if err := bank.ValidateAtomicBatch(batch); err != nil {
+		return nil, err
+	}
+	webhooks, err := bs.subscriptions(ctx)
 	if err != nil {
-		return &bank.Transaction{}, err
+		return nil, err
+	}
+	if err := bs.accManager.ApplyBatch(batch.Operations); err != nil {
+		return nil, err
+	}
+
+	// Balances are committed, record the history of deposits and withdrawals.
+	transactions := make([]*bank.Transaction, len(batch.Operations))
+	for i, operation := range batch.Operations {
+		if operation.Type == bank.BatchTransfer {
+			continue
+		}
+		transaction, err := bs.transactManager.CreateTransaction(operation.AccountID, operation.Type, operation.Amount, operation.Reference)
+		if err != nil {
+			return nil, err
+		}
+		transactions[i] = transaction
+	}
+
+	result := bank.NewAtomicBatchResult(transactions)
+	if err := bs.queue(webhooks, bank.BatchEvents(batch, result)...); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	if err := bs.accManager.PerformTransaction(account, txType, amount); err != nil {
-		return &bank.Transaction{}, err
+func (bs *BankStore) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := bank.ValidateTransfer(fromAccountID, toAccountID, amount); err != nil {
+		return err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return err
 	}
 
-	return bs.transactManager.CreateTransaction(accountID, txType, amount)
+	if err := bs.accManager.TransferBetweenAccounts(fromAccountID, toAccountID, amount); err != nil {
+		return err
+	}
+	return bs.queue(webhooks, bank.TransferCompletedEvent(fromAccountID, toAccountID, amount))
 }
 
-func (bs *BankStore) GetTransactionsByAccountID(accountID string) ([]bank.Transaction, error) {
-	return bs.transactManager.GetTransactionsByAccountID(accountID)
+func (bs *BankStore) CreateAPIKey(ctx context.Context, key bank.APIKey) (*bank.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateAPIKeyInput(key.Name, key.Scopes); err != nil {
+		return nil, err
+	}
+	return bs.keyManager.CreateAPIKey(key)
 }
 
-func (bs *BankStore) TransferFunds(fromAccountID, toAccountID string, amount float64) error {
-	return bs.accManager.TransferBetweenAccounts(fromAccountID, toAccountID, amount)
+func (bs *BankStore) GetAPIKeyByHash(ctx context.Context, hash string) (*bank.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.keyManager.GetAPIKeyByHash(hash)
+}
+
+func (bs *BankStore) ListAPIKeys(ctx context.Context) ([]bank.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.keyManager.ListAPIKeys(), nil
+}
+
+func (bs *BankStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (*bank.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.keyManager.RevokeAPIKey(id, revokedAt)
+}
+
+func (bs *BankStore) CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateWebhookInput(webhook.URL, webhook.Events); err != nil {
+		return nil, err
+	}
+	return bs.webhookManager.CreateWebhook(webhook)
+}
+
+func (bs *BankStore) GetWebhook(ctx context.Context, id string) (*bank.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.webhookManager.GetWebhook(id)
+}
+
+func (bs *BankStore) ListWebhooks(ctx context.Context) ([]bank.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.webhookManager.ListWebhooks(), nil
+}
+
+func (bs *BankStore) DeleteWebhook(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bs.webhookManager.DeleteWebhook(id)
+}
+
+func (bs *BankStore) EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bs.webhookManager.EnqueueWebhookDeliveries(deliveries)
+	return nil
+}
+
+func (bs *BankStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return bs.webhookManager.ClaimWebhookDeliveries(now, limit, lease), nil
+}
+
+func (bs *BankStore) UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bs.webhookManager.UpdateWebhookDelivery(delivery)
+	return nil
+}
+
+func (bs *BankStore) ListWebhookDeliveries(ctx context.Context, webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateWebhookDeliveryFilter(&filter); err != nil {
+		return nil, err
+	}
+	return bs.webhookManager.ListWebhookDeliveries(webhookID, filter)
 }
@@ -2,7 +2,9 @@ package memoryBank
 
 import (
 	"bank-demo-app/internal/bank"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -10,10 +12,11 @@ import (
 
 func TestCreateAccount(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	owner := "John Doe"
 	initialBalance := 1000.0
-	account, err := bankStore.CreateAccount(owner, initialBalance)
+	account, err := bankStore.CreateAccount(ctx, owner, initialBalance)
 
 	assert.NoError(t, err)
 
@@ -24,141 +27,183 @@ func TestCreateAccount(t *testing.T) {
 
 func TestGetAccountByID(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	owner := "John Doe"
 	initialBalance := 1000.0
-	account, err := bankStore.CreateAccount(owner, initialBalance)
+	account, err := bankStore.CreateAccount(ctx, owner, initialBalance)
 	assert.NoError(t, err)
 
-	retrievedAccount, err := bankStore.GetAccountByID(account.ID)
+	retrievedAccount, err := bankStore.GetAccountByID(ctx, account.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, account, retrievedAccount)
 
 	nonExistentID := uuid.New().String()
-	retrievedAccount, err = bankStore.GetAccountByID(nonExistentID)
-	assert.Error(t, err)
-	assert.Equal(t, bank.Account{}, *retrievedAccount)
+	retrievedAccount, err = bankStore.GetAccountByID(ctx, nonExistentID)
+	assert.ErrorIs(t, err, bank.ErrAccountNotFound)
+	assert.Nil(t, retrievedAccount)
 }
 
 func TestListAccounts(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	// Create two accounts
 	owner1 := "Alex Camara"
 	initialBalance1 := 1000.0
-	account1, err := bankStore.CreateAccount(owner1, initialBalance1)
+	account1, err := bankStore.CreateAccount(ctx, owner1, initialBalance1)
 	assert.NoError(t, err)
 
 	owner2 := "Donald Trump"
 	initialBalance2 := 500.0
-	account2, err := bankStore.CreateAccount(owner2, initialBalance2)
+	account2, err := bankStore.CreateAccount(ctx, owner2, initialBalance2)
 	assert.NoError(t, err)
 
-	accounts := bankStore.ListAccounts()
+	page, err := bankStore.ListAccounts(ctx, bank.AccountFilter{}, bank.PageRequest{})
+	assert.NoError(t, err)
 
-	assert.Len(t, accounts, 2)
-	assert.Contains(t, accounts, *account1)
-	assert.Contains(t, accounts, *account2)
+	assert.Len(t, page.Accounts, 2)
+	assert.Contains(t, page.Accounts, *account1)
+	assert.Contains(t, page.Accounts, *account2)
 }
 
 func TestPerformTransaction(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	owner := "Alex Camara"
 	initialBalance := 1000.0
-	account, err := bankStore.CreateAccount(owner, initialBalance)
+	account, err := bankStore.CreateAccount(ctx, owner, initialBalance)
 	assert.NoError(t, err)
 
 	txType := "deposit"
 	amount := 500.0
-	transaction, err := bankStore.PerformTransaction(account.ID, txType, amount)
+	transaction, err := bankStore.PerformTransaction(ctx, account.ID, txType, amount, "")
 	assert.NoError(t, err)
 
 	assert.Equal(t, account.ID, transaction.AccountID)
 	assert.Equal(t, txType, transaction.Type)
 	assert.Equal(t, amount, transaction.Amount)
 
-	accountAfter, err := bankStore.GetAccountByID(account.ID)
+	accountAfter, err := bankStore.GetAccountByID(ctx, account.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, initialBalance+amount, accountAfter.Balance)
 
 	txType = "withdrawal"
 	amount = 200.0
-	_, err = bankStore.PerformTransaction(account.ID, txType, amount)
+	_, err = bankStore.PerformTransaction(ctx, account.ID, txType, amount, "")
 	assert.NoError(t, err)
 
-	accountAfter, err = bankStore.GetAccountByID(account.ID)
+	accountAfter, err = bankStore.GetAccountByID(ctx, account.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, initialBalance+300.0, accountAfter.Balance)
 
 	invalidTxType := "invalid"
-	transaction, err = bankStore.PerformTransaction(account.ID, invalidTxType, amount)
-	assert.Error(t, err)
-	assert.Equal(t, bank.Transaction{}, *transaction)
+	transaction, err = bankStore.PerformTransaction(ctx, account.ID, invalidTxType, amount, "")
+	assert.ErrorIs(t, err, bank.ErrInvalidTransaction)
+	assert.Nil(t, transaction)
 }
 
 func TestGetTransactionsByAccountID(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	owner := "Alex Camara"
 	initialBalance := 1000.0
-	account, err := bankStore.CreateAccount(owner, initialBalance)
+	account, err := bankStore.CreateAccount(ctx, owner, initialBalance)
 	assert.NoError(t, err)
 
 	txType := "deposit"
 	amount := 500.0
-	_, err = bankStore.PerformTransaction(account.ID, txType, amount)
+	_, err = bankStore.PerformTransaction(ctx, account.ID, txType, amount, "")
 	assert.NoError(t, err)
 
-	transactions, err := bankStore.GetTransactionsByAccountID(account.ID)
+	page, err := bankStore.GetTransactionsByAccountID(ctx, account.ID, bank.TransactionFilter{}, bank.PageRequest{})
 	assert.NoError(t, err)
-	assert.Len(t, transactions, 1)
+	assert.Len(t, page.Transactions, 1)
 
-	assert.Equal(t, account.ID, transactions[0].AccountID)
-	assert.Equal(t, txType, transactions[0].Type)
-	assert.Equal(t, amount, transactions[0].Amount)
+	assert.Equal(t, account.ID, page.Transactions[0].AccountID)
+	assert.Equal(t, txType, page.Transactions[0].Type)
+	assert.Equal(t, amount, page.Transactions[0].Amount)
 
 	nonExistentID := uuid.New().String()
-	transactions, err = bankStore.GetTransactionsByAccountID(nonExistentID)
+	page, err = bankStore.GetTransactionsByAccountID(ctx, nonExistentID, bank.TransactionFilter{}, bank.PageRequest{})
 	assert.Error(t, err)
-	assert.Empty(t, transactions)
+	assert.Nil(t, page)
 }
 
 func TestTransferFunds(t *testing.T) {
 	bankStore := NewBankStore()
+	ctx := context.Background()
 
 	// Create two accounts for the transfer test
 	owner1 := "John Doe"
 	initialBalance1 := 1000.0
-	account1, err := bankStore.CreateAccount(owner1, initialBalance1)
+	account1, err := bankStore.CreateAccount(ctx, owner1, initialBalance1)
 	assert.NoError(t, err)
 
 	owner2 := "Jane Doe"
 	initialBalance2 := 1500.0
-	account2, err := bankStore.CreateAccount(owner2, initialBalance2)
+	account2, err := bankStore.CreateAccount(ctx, owner2, initialBalance2)
 	assert.NoError(t, err)
 
 	// Perform a transfer of 200 from account1 to account2
 	transferAmount := 200.0
-	err = bankStore.TransferFunds(account1.ID, account2.ID, transferAmount)
+	err = bankStore.TransferFunds(ctx, account1.ID, account2.ID, transferAmount)
 	assert.NoError(t, err)
 
 	// Assert the balances after the transfer
-	account1After, err := bankStore.GetAccountByID(account1.ID)
+	account1After, err := bankStore.GetAccountByID(ctx, account1.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, initialBalance1-transferAmount, account1After.Balance)
 
-	account2After, err := bankStore.GetAccountByID(account2.ID)
+	account2After, err := bankStore.GetAccountByID(ctx, account2.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, initialBalance2+transferAmount, account2After.Balance)
 
 	// Test transfer with invalid account ID
 	invalidID := uuid.New().String()
-	err = bankStore.TransferFunds(account1.ID, invalidID, transferAmount)
+	err = bankStore.TransferFunds(ctx, account1.ID, invalidID, transferAmount)
 	assert.Error(t, err)
 
 	// Test transfer with insufficient funds
 	insufficientBalanceAmount := initialBalance1 + 500.0
-	err = bankStore.TransferFunds(account1.ID, account2.ID, insufficientBalanceAmount)
+	err = bankStore.TransferFunds(ctx, account1.ID, account2.ID, insufficientBalanceAmount)
 	assert.Error(t, err)
 }
+
+func TestPaginateWalksFromTheCursor(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6}
+	at := func(i int) int { return items[i] }
+	compare := func(a, b int) int { return a - b }
+	cursorOf := func(item int) string { return string(rune('0' + item)) }
+	odd := func(item int) bool { return item%2 == 1 }
+
+	page, next := paginate(len(items), at, compare, bank.PageRequest{Limit: 2}, nil, nil, cursorOf)
+	assert.Equal(t, []int{1, 2}, page)
+	assert.Equal(t, "2", next)
+
+	after := 2
+	page, next = paginate(len(items), at, compare, bank.PageRequest{Limit: 2}, &after, odd, cursorOf)
+	assert.Equal(t, []int{3, 5}, page)
+	assert.Empty(t, next, "no odd item follows 5")
+
+	after = 4
+	page, next = paginate(len(items), at, compare, bank.PageRequest{Limit: 2, Order: bank.SortDescending}, &after, nil, cursorOf)
+	assert.Equal(t, []int{3, 2}, page)
+	assert.Equal(t, "2", next)
+
+	missing := 0
+	page, _ = paginate(len(items), at, compare, bank.PageRequest{Limit: 10, Order: bank.SortDescending}, &missing, nil, cursorOf)
+	assert.Empty(t, page, "nothing sorts before the first item")
+}
+
+func TestTransactionsStayOrderedByTimestamp(t *testing.T) {
+	tm := NewTransactionManager()
+	late := bank.Transaction{ID: "b", AccountID: "1", Timestamp: time.Unix(20, 0)}
+	early := bank.Transaction{ID: "a", AccountID: "1", Timestamp: time.Unix(10, 0)}
+	for _, transaction := range []bank.Transaction{late, early} {
+		tm.Transactions["1"] = insertSorted(tm.Transactions["1"], transaction, compareTransactionTimes)
+	}
+	assert.Equal(t, []bank.Transaction{early, late}, tm.Transactions["1"], "a clock stepping back doesn't break the order")
+}
@@ -0,0 +1,120 @@
+package memoryBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// paginate cuts one page out of n items ordered by compare, at(i) returning the i-th of them. It
+// binary searches the position after the cursor item, then walks in the page order keeping the
+// items keep accepts, so the cost is that of the items visited rather than of the whole set.
+// after is the cursor turned into an item, compared like any other.
+func paginate[T any](n int, at func(int) T, compare func(a, b T) int, page bank.PageRequest, after *T, keep func(T) bool, cursorOf func(T) string) ([]T, string) {
+	descending := page.Order == bank.SortDescending
+	i, step := 0, 1
+	if descending {
+		i, step = n-1, -1
+	}
+	if after != nil {
+		if descending {
+			i = sort.Search(n, func(j int) bool { return compare(at(j), *after) >= 0 }) - 1
+		} else {
+			i = sort.Search(n, func(j int) bool { return compare(at(j), *after) > 0 })
+		}
+	}
+
+	items := make([]T, 0, min(page.Limit, n))
+	for ; i >= 0 && i < n; i += step {
+		item := at(i)
+		if keep != nil && !keep(item) {
+			continue
+		}
+		if len(items) == page.Limit {
+			// Another item follows the page.
+			return items, cursorOf(items[len(items)-1])
+		}
+		items = append(items, item)
+	}
+	return items, ""
+}
+
+// paginateSlice sorts items, which must be a copy, by compare and cuts one page out of them.
+// It serves the sort fields no index is kept for.
+func paginateSlice[T any](items []T, compare func(a, b T) int, page bank.PageRequest, after *T, cursorOf func(T) string) ([]T, string) {
+	slices.SortFunc(items, compare)
+	return paginate(len(items), func(i int) T { return items[i] }, compare, page, after, nil, cursorOf)
+}
+
+// insertSorted inserts item into items, kept ordered by compare. Items usually arrive in order,
+// so this is mostly an append.
+func insertSorted[T any](items []T, item T, compare func(a, b T) int) []T {
+	position, _ := slices.BinarySearchFunc(items, item, compare)
+	return slices.Insert(items, position, item)
+}
+
+// compareAccounts orders accounts by the sortBy field, then by ID.
+func compareAccounts(sortBy string) func(a, b bank.Account) int {
+	switch sortBy {
+	case bank.AccountSortOwner:
+		return func(a, b bank.Account) int {
+			return cmp.Or(strings.Compare(a.Owner, b.Owner), strings.Compare(a.ID, b.ID))
+		}
+	case bank.AccountSortBalance:
+		return func(a, b bank.Account) int {
+			return cmp.Or(cmp.Compare(a.Balance, b.Balance), strings.Compare(a.ID, b.ID))
+		}
+	default:
+		return func(a, b bank.Account) int { return strings.Compare(a.ID, b.ID) }
+	}
+}
+
+// compareTransactions orders transactions by the sortBy field, then by ID.
+func compareTransactions(sortBy string) func(a, b bank.Transaction) int {
+	if sortBy == bank.TransactionSortAmount {
+		return func(a, b bank.Transaction) int {
+			return cmp.Or(cmp.Compare(a.Amount, b.Amount), strings.Compare(a.ID, b.ID))
+		}
+	}
+	return compareTransactionTimes
+}
+
+func compareTransactionTimes(a, b bank.Transaction) int {
+	return cmp.Or(a.Timestamp.Compare(b.Timestamp), strings.Compare(a.ID, b.ID))
+}
+
+// accountAtCursor returns an account holding the sort value and ID of cursor, nil without one.
+// The cursor was validated by the caller, so its value has the right type.
+func accountAtCursor(cursor *bank.Cursor) *bank.Account {
+	if cursor == nil {
+		return nil
+	}
+	account := bank.Account{ID: cursor.ID}
+	value, _ := cursor.TypedValue()
+	switch cursor.SortBy {
+	case bank.AccountSortOwner:
+		account.Owner, _ = value.(string)
+	case bank.AccountSortBalance:
+		account.Balance, _ = value.(float64)
+	}
+	return &account
+}
+
+// transactionAtCursor returns a transaction holding the sort value and ID of cursor, nil without one.
+func transactionAtCursor(cursor *bank.Cursor) *bank.Transaction {
+	if cursor == nil {
+		return nil
+	}
+	transaction := bank.Transaction{ID: cursor.ID}
+	value, _ := cursor.TypedValue()
+	switch cursor.SortBy {
+	case bank.TransactionSortAmount:
+		transaction.Amount, _ = value.(float64)
+	case bank.TransactionSortTimestamp:
+		transaction.Timestamp, _ = value.(time.Time)
+	}
+	return &transaction
+}
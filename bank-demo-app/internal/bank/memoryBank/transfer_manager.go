@@ -2,6 +2,7 @@ package memoryBank
 
 import (
 	"bank-demo-app/internal/bank"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,35 +11,123 @@ import (
 
 type TransactionManager struct {
 	mu           sync.RWMutex                  // Protect against race conditions
-	Transactions map[string][]bank.Transaction // Keyed by AccountID
+	Transactions map[string][]bank.Transaction // Keyed by AccountID, each slice ordered by timestamp then ID
+	ordered      []bank.Transaction            // Every transaction, ordered like the per account slices
+	byID         map[string]bank.Transaction   // Secondary index keyed by transaction ID
 }
 
-func (tm *TransactionManager) CreateTransaction(accountID, transactionType string, amount float64) (*bank.Transaction, error) {
+func NewTransactionManager() *TransactionManager {
+	return &TransactionManager{
+		Transactions: make(map[string][]bank.Transaction),
+		byID:         make(map[string]bank.Transaction),
+	}
+}
+
+func (tm *TransactionManager) CreateTransaction(accountID, transactionType string, amount float64, reference string) (*bank.Transaction, error) {
 	// Create the transaction
 	transaction := bank.Transaction{
 		ID:        uuid.New().String(),
 		AccountID: accountID,
 		Type:      transactionType,
 		Amount:    amount,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
+		Reference: reference,
 	}
 
 	// Store the transaction
 	tm.mu.Lock()
-	tm.Transactions[accountID] = append(tm.Transactions[accountID], transaction)
+	tm.byID[transaction.ID] = transaction
+	// Transactions are stored in the order they are listed by default, so those pages are cut
+	// without sorting.
+	tm.Transactions[accountID] = insertSorted(tm.Transactions[accountID], transaction, compareTransactionTimes)
+	tm.ordered = insertSorted(tm.ordered, transaction, compareTransactionTimes)
 	tm.mu.Unlock()
 
 	return &transaction, nil
 }
 
-func (tm *TransactionManager) GetTransactionsByAccountID(accountID string) ([]bank.Transaction, error) {
+func (tm *TransactionManager) GetTransactionByID(transactionID string) (*bank.Transaction, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	transaction, exists := tm.byID[transactionID]
+	if !exists {
+		return nil, bank.TransactionNotFoundError(transactionID)
+	}
+	return &transaction, nil
+}
+
+// SearchTransactions returns one page of the transactions of every account that match filter,
+// the cursor of the next one and the summary of every match.
+func (tm *TransactionManager) SearchTransactions(filter bank.TransactionFilter, page bank.PageRequest, cursor *bank.Cursor) ([]bank.Transaction, string, bank.TransactionSummary) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	// The summary covers every match, so they are all visited. Searches of a few accounts only
+	// visit those accounts, and must then be sorted.
+	candidates, sorted := tm.ordered, true
+	if len(filter.AccountIDs) > 0 {
+		accountIDs := slices.Clone(filter.AccountIDs)
+		slices.Sort(accountIDs)
+		candidates, sorted = nil, false
+		for _, accountID := range slices.Compact(accountIDs) {
+			candidates = append(candidates, tm.Transactions[accountID]...)
+		}
+	}
+
+	matching := make([]bank.Transaction, 0)
+	for _, transaction := range candidates {
+		if filter.Matches(transaction) {
+			matching = append(matching, transaction)
+		}
+	}
+	summary := bank.SummarizeTransactions(matching)
+
+	transactions, nextCursor := pageTransactions(matching, sorted, page, cursor)
+	return transactions, nextCursor, summary
+}
+
+// PageTransactions returns one page of the account transactions that match filter and the cursor
+// of the next one. It fails only when the account has no transactions at all.
+func (tm *TransactionManager) PageTransactions(accountID string, filter bank.TransactionFilter, page bank.PageRequest, cursor *bank.Cursor) ([]bank.Transaction, string, error) {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
 	transactions, exists := tm.Transactions[accountID]
 	if !exists {
-		return nil, bank.NoTransactionsForAccountError(accountID)
+		return nil, "", bank.NoTransactionsForAccountError(accountID)
+	}
+
+	if page.SortBy == bank.TransactionSortTimestamp {
+		at := func(i int) bank.Transaction { return transactions[i] }
+		items, nextCursor := paginate(len(transactions), at, compareTransactionTimes, page, transactionAtCursor(cursor), filter.Matches, transactionCursor(page))
+		return items, nextCursor, nil
 	}
 
-	return transactions, nil
+	matching := make([]bank.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if filter.Matches(transaction) {
+			matching = append(matching, transaction)
+		}
+	}
+	items, nextCursor := pageTransactions(matching, false, page, cursor)
+	return items, nextCursor, nil
+}
+
+// pageTransactions cuts one page out of matching, a copy, which is already ordered by timestamp
+// when sorted.
+func pageTransactions(matching []bank.Transaction, sorted bool, page bank.PageRequest, cursor *bank.Cursor) ([]bank.Transaction, string) {
+	compare := compareTransactions(page.SortBy)
+	after := transactionAtCursor(cursor)
+	if sorted && page.SortBy == bank.TransactionSortTimestamp {
+		at := func(i int) bank.Transaction { return matching[i] }
+		return paginate(len(matching), at, compare, page, after, nil, transactionCursor(page))
+	}
+	return paginateSlice(matching, compare, page, after, transactionCursor(page))
+}
+
+func transactionCursor(page bank.PageRequest) func(bank.Transaction) string {
+	return func(transaction bank.Transaction) string {
+		return bank.NewCursor(page, bank.TransactionSortValue(transaction, page.SortBy), transaction.ID)
+	}
 }
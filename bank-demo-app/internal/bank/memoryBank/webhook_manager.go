@@ -0,0 +1,164 @@
+package memoryBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WebhookManager struct {
+	mu         sync.RWMutex                    // Protect against race conditions
+	Webhooks   map[string]bank.Webhook         // Keyed by webhook ID
+	Deliveries map[string]bank.WebhookDelivery // Keyed by delivery ID
+}
+
+func NewWebhookManager() *WebhookManager {
+	return &WebhookManager{
+		Webhooks:   make(map[string]bank.Webhook),
+		Deliveries: make(map[string]bank.WebhookDelivery),
+	}
+}
+
+func (wm *WebhookManager) CreateWebhook(webhook bank.Webhook) (*bank.Webhook, error) {
+	webhook.Events = slices.Clone(webhook.Events)
+
+	wm.mu.Lock()
+	wm.Webhooks[webhook.ID] = webhook
+	wm.mu.Unlock()
+
+	return &webhook, nil
+}
+
+func (wm *WebhookManager) GetWebhook(id string) (*bank.Webhook, error) {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	webhook, exists := wm.Webhooks[id]
+	if !exists {
+		return nil, bank.WebhookNotFoundError(id)
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns every webhook, oldest first.
+func (wm *WebhookManager) ListWebhooks() []bank.Webhook {
+	wm.mu.RLock()
+	webhooks := make([]bank.Webhook, 0, len(wm.Webhooks))
+	for _, webhook := range wm.Webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	wm.mu.RUnlock()
+
+	slices.SortFunc(webhooks, bank.CompareWebhooks)
+	return webhooks
+}
+
+// DeleteWebhook removes the webhook along with its deliveries, delivered or not.
+func (wm *WebhookManager) DeleteWebhook(id string) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	if _, exists := wm.Webhooks[id]; !exists {
+		return bank.WebhookNotFoundError(id)
+	}
+	delete(wm.Webhooks, id)
+	for deliveryID, delivery := range wm.Deliveries {
+		if delivery.WebhookID == id {
+			delete(wm.Deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries queues the deliveries, but those of webhooks deleted in the meantime.
+func (wm *WebhookManager) EnqueueWebhookDeliveries(deliveries []bank.WebhookDelivery) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		if _, exists := wm.Webhooks[delivery.WebhookID]; exists {
+			wm.Deliveries[delivery.ID] = cloneDelivery(delivery)
+		}
+	}
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, the longest overdue
+// first, and postpones them by lease so that they aren't claimed again while being delivered.
+func (wm *WebhookManager) ClaimWebhookDeliveries(now time.Time, limit int, lease time.Duration) []bank.WebhookDelivery {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	due := make([]bank.WebhookDelivery, 0)
+	for _, delivery := range wm.Deliveries {
+		if delivery.Status == bank.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b bank.WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]bank.WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		delivery.NextAttemptAt = now.Add(lease).UTC()
+		wm.Deliveries[delivery.ID] = delivery
+		claimed = append(claimed, cloneDelivery(delivery))
+	}
+	return claimed
+}
+
+// UpdateWebhookDelivery replaces the delivery. Deliveries of a webhook deleted in the meantime
+// are gone and stay so.
+func (wm *WebhookManager) UpdateWebhookDelivery(delivery bank.WebhookDelivery) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	if _, exists := wm.Deliveries[delivery.ID]; exists {
+		wm.Deliveries[delivery.ID] = cloneDelivery(delivery)
+	}
+}
+
+// ListWebhookDeliveries returns the deliveries of the webhook matching filter, newest first.
+func (wm *WebhookManager) ListWebhookDeliveries(webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error) {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	if _, exists := wm.Webhooks[webhookID]; !exists {
+		return nil, bank.WebhookNotFoundError(webhookID)
+	}
+
+	deliveries := make([]bank.WebhookDelivery, 0)
+	for _, delivery := range wm.Deliveries {
+		if delivery.WebhookID == webhookID && (filter.Status == "" || delivery.Status == filter.Status) {
+			deliveries = append(deliveries, cloneDelivery(delivery))
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b bank.WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	if len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+// cloneDelivery copies the slices of delivery, so callers can't change the stored one.
+func cloneDelivery(delivery bank.WebhookDelivery) bank.WebhookDelivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	delivery.Attempts = slices.Clone(delivery.Attempts)
+	if delivery.Attempts == nil {
+		delivery.Attempts = []bank.WebhookAttempt{}
+	}
+	return delivery
+}
@@ -0,0 +1,293 @@
+package bank
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+
+	// MaxFilterAccountIDs bounds how many accounts a single transaction search can target.
+	MaxFilterAccountIDs = 100
+)
+
+// Sort fields accepted by the list operations. Every sort uses the item ID as tie-breaker
+// so the ordering is total and stable between pages.
+const (
+	AccountSortID            = "id"
+	AccountSortOwner         = "owner"
+	AccountSortBalance       = "balance"
+	TransactionSortTimestamp = "timestamp"
+	TransactionSortAmount    = "amount"
+)
+
+var (
+	accountSortFields     = []string{AccountSortID, AccountSortOwner, AccountSortBalance}
+	transactionSortFields = []string{TransactionSortTimestamp, TransactionSortAmount}
+)
+
+// PageRequest selects one page of a list. Cursor is the opaque value returned as NextCursor by
+// the previous page; it is only valid with the same sort field and order.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	SortBy string
+	Order  SortOrder
+}
+
+// AccountFilter narrows ListAccounts. Zero values disable each condition; balance bounds are inclusive.
+type AccountFilter struct {
+	Owner      string
+	MinBalance *float64
+	MaxBalance *float64
+}
+
+// TransactionFilter narrows transaction lists. Amount bounds are inclusive, From is inclusive and To exclusive.
+// AccountIDs keeps transactions of any of the listed accounts and Reference keeps those whose reference
+// contains it, ignoring case.
+type TransactionFilter struct {
+	AccountIDs []string
+	Type       string
+	MinAmount  *float64
+	MaxAmount  *float64
+	From       *time.Time
+	To         *time.Time
+	Reference  string
+}
+
+type AccountPage struct {
+	Accounts   []Account
+	NextCursor string
+}
+
+type TransactionPage struct {
+	Transactions []Transaction
+	NextCursor   string
+}
+
+// Cursor is the decoded form of PageRequest.Cursor: the sort key and ID of the last item
+// of the previous page. Stores resume strictly after that position.
+type Cursor struct {
+	SortBy string    `json:"s"`
+	Order  SortOrder `json:"o"`
+	Value  string    `json:"v"`
+	ID     string    `json:"id"`
+}
+
+// IsZero reports whether the filter has no condition at all.
+func (f AccountFilter) IsZero() bool {
+	return f.Owner == "" && f.MinBalance == nil && f.MaxBalance == nil
+}
+
+// Matches reports whether account passes every condition of the filter.
+func (f AccountFilter) Matches(account Account) bool {
+	if f.Owner != "" && account.Owner != f.Owner {
+		return false
+	}
+	if f.MinBalance != nil && account.Balance < *f.MinBalance {
+		return false
+	}
+	if f.MaxBalance != nil && account.Balance > *f.MaxBalance {
+		return false
+	}
+	return true
+}
+
+// IsZero reports whether the filter has no condition at all.
+func (f TransactionFilter) IsZero() bool {
+	return len(f.AccountIDs) == 0 && f.Type == "" && f.MinAmount == nil && f.MaxAmount == nil &&
+		f.From == nil && f.To == nil && f.Reference == ""
+}
+
+// Matches reports whether transaction passes every condition of the filter.
+func (f TransactionFilter) Matches(transaction Transaction) bool {
+	if len(f.AccountIDs) > 0 && !slices.Contains(f.AccountIDs, transaction.AccountID) {
+		return false
+	}
+	if f.Type != "" && transaction.Type != f.Type {
+		return false
+	}
+	if f.MinAmount != nil && transaction.Amount < *f.MinAmount {
+		return false
+	}
+	if f.MaxAmount != nil && transaction.Amount > *f.MaxAmount {
+		return false
+	}
+	if f.From != nil && transaction.Timestamp.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && !transaction.Timestamp.Before(*f.To) {
+		return false
+	}
+	if f.Reference != "" && !strings.Contains(strings.ToLower(transaction.Reference), strings.ToLower(f.Reference)) {
+		return false
+	}
+	return true
+}
+
+// ValidateAccountFilter rejects filters that can never match.
+func ValidateAccountFilter(filter AccountFilter) error {
+	if filter.MinBalance != nil && filter.MaxBalance != nil && *filter.MinBalance > *filter.MaxBalance {
+		return InvalidFilterError("min_balance is greater than max_balance")
+	}
+	return nil
+}
+
+// ValidateTransactionFilter rejects unknown types and ranges that can never match.
+func ValidateTransactionFilter(filter TransactionFilter) error {
+	if len(filter.AccountIDs) > MaxFilterAccountIDs {
+		return InvalidFilterError(fmt.Sprintf("at most %d account IDs can be searched at once", MaxFilterAccountIDs))
+	}
+	if slices.Contains(filter.AccountIDs, "") {
+		return InvalidFilterError("account IDs cannot be empty")
+	}
+	if filter.Type != "" && filter.Type != DepositTransactionType && filter.Type != WithdrawalTransactionType {
+		return InvalidTransactionError(filter.Type)
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return InvalidFilterError("min_amount is greater than max_amount")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return InvalidFilterError("from must be before to")
+	}
+	return ValidateReference(filter.Reference)
+}
+
+// NormalizeAccountPage validates page for an account list and fills in the defaults.
+// The returned cursor is nil on the first page.
+func NormalizeAccountPage(page PageRequest) (PageRequest, *Cursor, error) {
+	return normalizePage(page, AccountSortID, accountSortFields)
+}
+
+// NormalizeTransactionPage validates page for a transaction list and fills in the defaults.
+// The returned cursor is nil on the first page.
+func NormalizeTransactionPage(page PageRequest) (PageRequest, *Cursor, error) {
+	return normalizePage(page, TransactionSortTimestamp, transactionSortFields)
+}
+
+func normalizePage(page PageRequest, defaultSort string, allowedSorts []string) (PageRequest, *Cursor, error) {
+	if page.Limit == 0 {
+		page.Limit = DefaultPageLimit
+	}
+	if page.Limit < 0 || page.Limit > MaxPageLimit {
+		return page, nil, InvalidPageLimitError(page.Limit)
+	}
+
+	if page.SortBy == "" {
+		page.SortBy = defaultSort
+	}
+	if !slices.Contains(allowedSorts, page.SortBy) {
+		return page, nil, InvalidSortFieldError(page.SortBy)
+	}
+
+	if page.Order == "" {
+		page.Order = SortAscending
+	}
+	if page.Order != SortAscending && page.Order != SortDescending {
+		return page, nil, InvalidSortOrderError(string(page.Order))
+	}
+
+	if page.Cursor == "" {
+		return page, nil, nil
+	}
+
+	cursor, err := DecodeCursor(page.Cursor)
+	if err != nil {
+		return page, nil, err
+	}
+	if cursor.SortBy != page.SortBy || cursor.Order != page.Order {
+		return page, nil, ErrInvalidCursor
+	}
+	if _, err := cursor.TypedValue(); err != nil {
+		return page, nil, err
+	}
+	return page, cursor, nil
+}
+
+// NewCursor builds the opaque cursor pointing after the item with the given sort value and ID.
+// value must be a string, float64 or time.Time depending on the sort field.
+func NewCursor(page PageRequest, value any, id string) string {
+	cursor := Cursor{SortBy: page.SortBy, Order: page.Order, ID: id}
+	switch v := value.(type) {
+	case string:
+		cursor.Value = v
+	case float64:
+		cursor.Value = strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		cursor.Value = v.UTC().Format(time.RFC3339Nano)
+	}
+
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor parses a cursor produced by NewCursor.
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// TypedValue returns the cursor sort value as string, float64 or time.Time
+// depending on the sort field it was built for.
+func (c *Cursor) TypedValue() (any, error) {
+	switch c.SortBy {
+	case AccountSortID, AccountSortOwner:
+		return c.Value, nil
+	case AccountSortBalance, TransactionSortAmount:
+		value, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return value, nil
+	case TransactionSortTimestamp:
+		value, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return value, nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
+
+// AccountSortValue returns the value account is sorted by for the given sort field.
+func AccountSortValue(account Account, sortBy string) any {
+	switch sortBy {
+	case AccountSortOwner:
+		return account.Owner
+	case AccountSortBalance:
+		return account.Balance
+	default:
+		return account.ID
+	}
+}
+
+// TransactionSortValue returns the value transaction is sorted by for the given sort field.
+func TransactionSortValue(transaction Transaction, sortBy string) any {
+	if sortBy == TransactionSortAmount {
+		return transaction.Amount
+	}
+	return transaction.Timestamp
+}
@@ -0,0 +1,71 @@
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	timestamp := time.Date(2024, 1, 31, 12, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		page  PageRequest
+		value any
+	}{
+		{PageRequest{SortBy: AccountSortID, Order: SortAscending}, "b7f1"},
+		{PageRequest{SortBy: AccountSortOwner, Order: SortDescending}, "Alex Camara"},
+		{PageRequest{SortBy: AccountSortBalance, Order: SortAscending}, 1234.56},
+		{PageRequest{SortBy: TransactionSortTimestamp, Order: SortAscending}, timestamp},
+	}
+
+	for _, test := range tests {
+		t.Run(test.page.SortBy, func(t *testing.T) {
+			cursor, err := DecodeCursor(NewCursor(test.page, test.value, "id-1"))
+			require.NoError(t, err)
+			assert.Equal(t, test.page.SortBy, cursor.SortBy)
+			assert.Equal(t, test.page.Order, cursor.Order)
+			assert.Equal(t, "id-1", cursor.ID)
+
+			value, err := cursor.TypedValue()
+			require.NoError(t, err)
+			assert.Equal(t, test.value, value)
+		})
+	}
+}
+
+func TestNormalizePageDefaults(t *testing.T) {
+	page, cursor, err := NormalizeAccountPage(PageRequest{})
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+	assert.Equal(t, PageRequest{Limit: DefaultPageLimit, SortBy: AccountSortID, Order: SortAscending}, page)
+
+	page, _, err = NormalizeTransactionPage(PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionSortTimestamp, page.SortBy)
+}
+
+func TestNormalizePageErrors(t *testing.T) {
+	balanceCursor := NewCursor(PageRequest{SortBy: AccountSortBalance, Order: SortAscending}, "not-a-number", "id-1")
+	tests := []struct {
+		name          string
+		page          PageRequest
+		expectedError error
+	}{
+		{"negative limit", PageRequest{Limit: -5}, ErrInvalidPageLimit},
+		{"limit above maximum", PageRequest{Limit: MaxPageLimit + 1}, ErrInvalidPageLimit},
+		{"transaction sort on accounts", PageRequest{SortBy: TransactionSortAmount}, ErrInvalidSortField},
+		{"unknown order", PageRequest{Order: "sideways"}, ErrInvalidSortOrder},
+		{"not base64", PageRequest{Cursor: "%%%"}, ErrInvalidCursor},
+		{"cursor for another order", PageRequest{Order: SortDescending, Cursor: NewCursor(PageRequest{SortBy: AccountSortID, Order: SortAscending}, "a", "a")}, ErrInvalidCursor},
+		{"malformed cursor value", PageRequest{SortBy: AccountSortBalance, Cursor: balanceCursor}, ErrInvalidCursor},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := NormalizeAccountPage(test.page)
+			assert.ErrorIs(t, err, test.expectedError)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const apiKeyColumns = `id, name, prefix, hash, scopes, created_at, revoked_at`
+
+func (bs *BankStore) CreateAPIKey(ctx context.Context, key bank.APIKey) (*bank.APIKey, error) {
+	if err := bank.ValidateAPIKeyInput(key.Name, key.Scopes); err != nil {
+		return nil, err
+	}
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode api key scopes: %w", err)
+	}
+	_, err = bs.db.ExecContext(ctx,
+		`INSERT INTO api_keys (id, name, prefix, hash, scopes, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Prefix, key.Hash, string(scopes), key.CreatedAt.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (bs *BankStore) GetAPIKeyByHash(ctx context.Context, hash string) (*bank.APIKey, error) {
+	row := bs.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = ?`, hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bank.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (bs *BankStore) ListAPIKeys(ctx context.Context) ([]bank.APIKey, error) {
+	rows, err := bs.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []bank.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list api keys: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking it again keeps the first revocation time.
+func (bs *BankStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (*bank.APIKey, error) {
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start api key revocation: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, revokedAt.UnixNano(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bank.APIKeyNotFoundError(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit api key revocation: %w", err)
+	}
+	return key, nil
+}
+
+// scanAPIKey decodes a row selected with apiKeyColumns.
+func scanAPIKey(row scanner) (*bank.APIKey, error) {
+	var key bank.APIKey
+	var scopes string
+	var createdAt int64
+	var revokedAt sql.NullInt64
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes of api key %s: %w", key.ID, err)
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	if revokedAt.Valid {
+		timestamp := time.Unix(0, revokedAt.Int64).UTC()
+		key.RevokedAt = &timestamp
+	}
+
+	return &key, nil
+}
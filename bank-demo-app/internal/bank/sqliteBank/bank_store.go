@@ -0,0 +1,679 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite" // Pure Go driver, registers "sqlite" without needing cgo.
+)
+
+const (
+	driverName       = "sqlite"
+	busyTimeoutMilli = 5000
+
+	accountColumns = `id, owner, balance, status, closed_at, metadata`
+)
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx so helpers can run inside or outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type BankStore struct {
+	db *sql.DB
+	// outbox, when set, lists the webhooks the deliveries of every write are queued for.
+	outbox bank.Outbox
+}
+
+// NewBankStore opens (or creates) the database file at path and migrates it to the latest schema.
+func NewBankStore(ctx context.Context, path string) (*BankStore, error) {
+	db, err := sql.Open(driverName, dataSourceName(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %w", path, err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to sqlite database %s: %w", path, err)
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BankStore{db: db}, nil
+}
+
+// dataSourceName builds the driver DSN. WAL lets readers run alongside the single writer, and
+// immediate transactions take the write lock up front so concurrent transfers wait on
+// busy_timeout instead of failing with SQLITE_BUSY when upgrading a read lock.
+func dataSourceName(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMilli))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_txlock", "immediate")
+	return "file:" + path + "?" + params.Encode()
+}
+
+// Close releases the underlying database handle.
+func (bs *BankStore) Close() error {
+	return bs.db.Close()
+}
+
+// Ping checks that the database file can still be reached.
+func (bs *BankStore) Ping(ctx context.Context) error {
+	return bs.db.PingContext(ctx)
+}
+
+// SetOutbox has the writes queue the deliveries of the events they cause in their own database
+// transaction, so an event is queued if and only if its write is committed. It must be called
+// before the store is used.
+func (bs *BankStore) SetOutbox(outbox bank.Outbox) {
+	bs.outbox = outbox
+}
+
+// subscriptions lists the webhooks before a write starts, so that the write lock isn't held
+// while they are.
+func (bs *BankStore) subscriptions(ctx context.Context) ([]bank.Webhook, error) {
+	if bs.outbox == nil {
+		return nil, nil
+	}
+	return bs.outbox.Subscriptions(ctx)
+}
+
+// queue inserts the deliveries of events for webhooks within tx.
+func queue(ctx context.Context, tx *sql.Tx, webhooks []bank.Webhook, events ...bank.Event) error {
+	deliveries, err := bank.NewWebhookDeliveries(webhooks, events, time.Now())
+	if err != nil {
+		return err
+	}
+	return insertDeliveries(ctx, tx, deliveries)
+}
+
+func (bs *BankStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	if err := bank.ValidateAccountInput(owner, initialBalance); err != nil {
+		return nil, err
+	}
+
+	account := bank.Account{
+		ID:      uuid.New().String(),
+		Owner:   owner,
+		Balance: initialBalance,
+		Status:  bank.AccountStatusOpen,
+	}
+
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start account creation: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO accounts (id, owner, balance, status) VALUES (?, ?, ?, ?)`,
+		account.ID, account.Owner, account.Balance, account.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account: %w", err)
+	}
+	if err := queue(ctx, tx, webhooks, bank.AccountCreatedEvent(&account)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account creation: %w", err)
+	}
+
+	return &account, nil
+}
+
+func (bs *BankStore) GetAccountByID(ctx context.Context, id string) (*bank.Account, error) {
+	return getAccount(ctx, bs.db, id)
+}
+
+func (bs *BankStore) UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error) {
+	if err := bank.ValidateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start account update: %w", err)
+	}
+	defer tx.Rollback()
+
+	account, err := getAccount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.IsClosed() {
+		return nil, bank.AccountClosedError(id)
+	}
+
+	updated := update.Apply(*account)
+	if err := bank.ValidateMetadata(updated.Metadata); err != nil {
+		return nil, err
+	}
+	if err := saveAccount(ctx, tx, &updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account update: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// CloseAccount closes the account, first moving its balance to payoutAccountID when one is given.
+// The payout is recorded on both accounts in the same transaction.
+func (bs *BankStore) CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error) {
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start account closure: %w", err)
+	}
+	defer tx.Rollback()
+
+	account, err := getAccount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var payout *bank.Account
+	if payoutAccountID != "" {
+		payout, err = getAccount(ctx, tx, payoutAccountID)
+		if errors.Is(err, bank.ErrAccountNotFound) {
+			return nil, bank.TransferDestinationNotFoundError(payoutAccountID)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bank.ValidateClosure(*account, payout); err != nil {
+		return nil, err
+	}
+
+	closure := &bank.AccountClosure{Account: bank.CloseAccount(*account, time.Now().UTC())}
+	if payout != nil && account.Balance != 0 {
+		payout.Deposit(account.Balance)
+		if err := saveBalance(ctx, tx, payout); err != nil {
+			return nil, err
+		}
+		reference := bank.ClosureReference(id)
+		if closure.Withdrawal, err = insertTransaction(ctx, tx, id, bank.WithdrawalTransactionType, account.Balance, reference); err != nil {
+			return nil, err
+		}
+		if closure.Deposit, err = insertTransaction(ctx, tx, payout.ID, bank.DepositTransactionType, account.Balance, reference); err != nil {
+			return nil, err
+		}
+	}
+	if err := saveAccount(ctx, tx, &closure.Account); err != nil {
+		return nil, err
+	}
+	if err := queue(ctx, tx, webhooks, bank.ClosureEvents(closure)...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account closure: %w", err)
+	}
+
+	return closure, nil
+}
+
+func (bs *BankStore) ListAccounts(ctx context.Context, filter bank.AccountFilter, page bank.PageRequest) (*bank.AccountPage, error) {
+	if err := bank.ValidateAccountFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeAccountPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var where whereClause
+	where.addAccountFilter(filter)
+	where.addKeyset(page, cursor)
+	order, limit := orderBy(page)
+
+	rows, err := bs.db.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts`+where.String()+order, append(where.args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]bank.Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode accounts: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+
+	result := &bank.AccountPage{Accounts: accounts}
+	if len(accounts) > page.Limit {
+		result.Accounts = accounts[:page.Limit]
+		last := result.Accounts[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.AccountSortValue(last, page.SortBy), last.ID)
+	}
+
+	return result, nil
+}
+
+func (bs *BankStore) PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	if err := bank.ValidateTransaction(txType, amount); err != nil {
+		return nil, err
+	}
+	if err := bank.ValidateReference(reference); err != nil {
+		return nil, err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transaction, err := performTransaction(ctx, tx, accountID, txType, amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	if err := queue(ctx, tx, webhooks, bank.TransactionCreatedEvent(transaction)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// performTransaction updates the balance and records the transaction within tx.
+func performTransaction(ctx context.Context, tx *sql.Tx, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	account, err := getAccount(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.IsClosed() {
+		return nil, bank.AccountClosedError(accountID)
+	}
+
+	if err := account.UpdateBalance(txType, amount); err != nil {
+		return nil, err
+	}
+	if err := saveBalance(ctx, tx, account); err != nil {
+		return nil, err
+	}
+
+	return insertTransaction(ctx, tx, accountID, txType, amount, reference)
+}
+
+// insertTransaction records a transaction within tx. The balance change it describes is up to the caller.
+func insertTransaction(ctx context.Context, tx *sql.Tx, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	transaction := bank.Transaction{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Type:      txType,
+		Amount:    amount,
+		Timestamp: time.Now().UTC(),
+		Reference: reference,
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO transactions (id, account_id, type, amount, timestamp, reference) VALUES (?, ?, ?, ?, ?, ?)`,
+		transaction.ID, transaction.AccountID, transaction.Type, transaction.Amount, transaction.Timestamp.UnixNano(), transaction.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+func (bs *BankStore) GetTransactionByID(ctx context.Context, id string) (*bank.Transaction, error) {
+	transactions, err := queryTransactions(ctx, bs.db, ` WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, bank.TransactionNotFoundError(id)
+	}
+
+	return &transactions[0], nil
+}
+
+func (bs *BankStore) GetTransactionsByAccountID(ctx context.Context, accountID string, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionPage, error) {
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var where whereClause
+	where.add("account_id = ?", accountID)
+	where.addTransactionFilter(filter)
+	where.addKeyset(page, cursor)
+	order, limit := orderBy(page)
+
+	transactions, err := queryTransactions(ctx, bs.db, where.String()+order, append(where.args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty page is only an error when the account has no history at all.
+	if len(transactions) == 0 {
+		var exists bool
+		err := bs.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE account_id = ?)`, accountID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find transactions: %w", err)
+		}
+		if !exists {
+			return nil, bank.NoTransactionsForAccountError(accountID)
+		}
+	}
+
+	result := &bank.TransactionPage{Transactions: transactions}
+	if len(transactions) > page.Limit {
+		result.Transactions = transactions[:page.Limit]
+		last := result.Transactions[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.TransactionSortValue(last, page.SortBy), last.ID)
+	}
+
+	return result, nil
+}
+
+func (bs *BankStore) SearchTransactions(ctx context.Context, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionSearchPage, error) {
+	if err := bank.ValidateTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+	page, cursor, err := bank.NormalizeTransactionPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var where whereClause
+	where.addTransactionFilter(filter)
+
+	summary, err := summarizeTransactions(ctx, bs.db, where.String(), where.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	where.addKeyset(page, cursor)
+	order, limit := orderBy(page)
+
+	transactions, err := queryTransactions(ctx, bs.db, where.String()+order, append(where.args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &bank.TransactionSearchPage{Transactions: transactions, Summary: summary}
+	if len(transactions) > page.Limit {
+		result.Transactions = transactions[:page.Limit]
+		last := result.Transactions[page.Limit-1]
+		result.NextCursor = bank.NewCursor(page, bank.TransactionSortValue(last, page.SortBy), last.ID)
+	}
+
+	return result, nil
+}
+
+func (bs *BankStore) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	if err := bank.ValidateTransfer(fromAccountID, toAccountID, amount); err != nil {
+		return err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transfer: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transferFunds(ctx, tx, fromAccountID, toAccountID, amount); err != nil {
+		return err
+	}
+	if err := queue(ctx, tx, webhooks, bank.TransferCompletedEvent(fromAccountID, toAccountID, amount)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transfer: %w", err)
+	}
+
+	return nil
+}
+
+// transferFunds moves amount between the accounts within tx.
+func transferFunds(ctx context.Context, tx *sql.Tx, fromAccountID, toAccountID string, amount float64) error {
+	fromAccount, err := getAccount(ctx, tx, fromAccountID)
+	if errors.Is(err, bank.ErrAccountNotFound) {
+		return bank.TransferSourceNotFoundError(fromAccountID)
+	} else if err != nil {
+		return err
+	}
+
+	toAccount, err := getAccount(ctx, tx, toAccountID)
+	if errors.Is(err, bank.ErrAccountNotFound) {
+		return bank.TransferDestinationNotFoundError(toAccountID)
+	} else if err != nil {
+		return err
+	}
+
+	if fromAccount.IsClosed() {
+		return bank.AccountClosedError(fromAccountID)
+	}
+	if toAccount.IsClosed() {
+		return bank.AccountClosedError(toAccountID)
+	}
+
+	// Withdraw from the source account and deposit to the destination account.
+	if err := fromAccount.Withdraw(amount); err != nil {
+		return err
+	}
+	toAccount.Deposit(amount)
+
+	if err := saveBalance(ctx, tx, fromAccount); err != nil {
+		return err
+	}
+	return saveBalance(ctx, tx, toAccount)
+}
+
+func (bs *BankStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	if !batch.Atomic {
+		if err := bank.ValidateBatch(batch); err != nil {
+			return nil, err
+		}
+		return bank.ExecuteBestEffort(ctx, bs, batch.Operations), nil
+	}
+
+	if err := bank.ValidateAtomicBatch(batch); err != nil {
+		return nil, err
+	}
+	webhooks, err := bs.subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every operation runs in one database transaction, so the first failure rolls back all of them.
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	transactions := make([]*bank.Transaction, len(batch.Operations))
+	for i, operation := range batch.Operations {
+		if operation.Type == bank.BatchTransfer {
+			err = transferFunds(ctx, tx, operation.FromAccountID, operation.ToAccountID, operation.Amount)
+		} else {
+			transactions[i], err = performTransaction(ctx, tx, operation.AccountID, operation.Type, operation.Amount, operation.Reference)
+		}
+		if err != nil {
+			return nil, &bank.BatchOperationError{Index: i, Err: err}
+		}
+	}
+
+	result := bank.NewAtomicBatchResult(transactions)
+	if err := queue(ctx, tx, webhooks, bank.BatchEvents(batch, result)...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return result, nil
+}
+
+func getAccount(ctx context.Context, q querier, id string) (*bank.Account, error) {
+	account, err := scanAccount(q.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, bank.AccountNotFoundError(id)
+		}
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return account, nil
+}
+
+// scanAccount decodes a row selected with accountColumns.
+func scanAccount(row scanner) (*bank.Account, error) {
+	var account bank.Account
+	var closedAt sql.NullInt64
+	var metadata string
+	if err := row.Scan(&account.ID, &account.Owner, &account.Balance, &account.Status, &closedAt, &metadata); err != nil {
+		return nil, err
+	}
+
+	if closedAt.Valid {
+		timestamp := time.Unix(0, closedAt.Int64).UTC()
+		account.ClosedAt = &timestamp
+	}
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &account.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of account %s: %w", account.ID, err)
+		}
+	}
+
+	return &account, nil
+}
+
+// saveAccount writes every mutable field of account.
+func saveAccount(ctx context.Context, q querier, account *bank.Account) error {
+	metadata := ""
+	if len(account.Metadata) > 0 {
+		encoded, err := json.Marshal(account.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode account metadata: %w", err)
+		}
+		metadata = string(encoded)
+	}
+
+	var closedAt sql.NullInt64
+	if account.ClosedAt != nil {
+		closedAt = sql.NullInt64{Int64: account.ClosedAt.UnixNano(), Valid: true}
+	}
+
+	_, err := q.ExecContext(ctx,
+		`UPDATE accounts SET owner = ?, balance = ?, status = ?, closed_at = ?, metadata = ? WHERE id = ?`,
+		account.Owner, account.Balance, account.Status, closedAt, metadata, account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update account: %w", err)
+	}
+	return nil
+}
+
+func saveBalance(ctx context.Context, q querier, account *bank.Account) error {
+	_, err := q.ExecContext(ctx, `UPDATE accounts SET balance = ? WHERE id = ?`, account.Balance, account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	return nil
+}
+
+// queryTransactions runs a SELECT over the transactions table with the given clauses appended.
+func queryTransactions(ctx context.Context, q querier, clauses string, args ...any) ([]bank.Transaction, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, account_id, type, amount, timestamp, reference FROM transactions`+clauses, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]bank.Transaction, 0)
+	for rows.Next() {
+		var transaction bank.Transaction
+		var timestamp int64
+		if err := rows.Scan(&transaction.ID, &transaction.AccountID, &transaction.Type, &transaction.Amount, &timestamp, &transaction.Reference); err != nil {
+			return nil, fmt.Errorf("failed to decode transactions: %w", err)
+		}
+		transaction.Timestamp = time.Unix(0, timestamp).UTC()
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// summarizeTransactions counts and sums the transactions selected by clauses, grouped by type.
+func summarizeTransactions(ctx context.Context, q querier, clauses string, args ...any) (bank.TransactionSummary, error) {
+	summary := bank.NewTransactionSummary()
+
+	rows, err := q.QueryContext(ctx, `SELECT type, COUNT(*), COALESCE(SUM(amount), 0) FROM transactions`+clauses+` GROUP BY type`, args...)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txType string
+		var count int
+		var sum float64
+		if err := rows.Scan(&txType, &count, &sum); err != nil {
+			return summary, fmt.Errorf("failed to summarize transactions: %w", err)
+		}
+		summary.Add(txType, count, sum)
+	}
+	if err := rows.Err(); err != nil {
+		return summary, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,170 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *BankStore {
+	bankStore, err := NewBankStore(context.Background(), filepath.Join(t.TempDir(), "bank.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { bankStore.Close() })
+	return bankStore
+}
+
+func TestCreateAndGetAccount(t *testing.T) {
+	bankStore := newTestStore(t)
+	ctx := context.Background()
+
+	account, err := bankStore.CreateAccount(ctx, "John Doe", 1000.0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, account.ID)
+
+	retrievedAccount, err := bankStore.GetAccountByID(ctx, account.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, account, retrievedAccount)
+
+	_, err = bankStore.GetAccountByID(ctx, uuid.New().String())
+	assert.True(t, errors.Is(err, bank.ErrAccountNotFound))
+}
+
+func TestListAccounts(t *testing.T) {
+	bankStore := newTestStore(t)
+	ctx := context.Background()
+
+	account1, err := bankStore.CreateAccount(ctx, "Alex Camara", 1000.0)
+	assert.NoError(t, err)
+	account2, err := bankStore.CreateAccount(ctx, "Donald Trump", 500.0)
+	assert.NoError(t, err)
+
+	page, err := bankStore.ListAccounts(ctx, bank.AccountFilter{}, bank.PageRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Accounts, 2)
+	assert.Contains(t, page.Accounts, *account1)
+	assert.Contains(t, page.Accounts, *account2)
+}
+
+func TestPerformTransaction(t *testing.T) {
+	bankStore := newTestStore(t)
+	ctx := context.Background()
+
+	account, err := bankStore.CreateAccount(ctx, "Alex Camara", 1000.0)
+	assert.NoError(t, err)
+
+	transaction, err := bankStore.PerformTransaction(ctx, account.ID, bank.DepositTransactionType, 500.0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, account.ID, transaction.AccountID)
+
+	_, err = bankStore.PerformTransaction(ctx, account.ID, bank.WithdrawalTransactionType, 2000.0, "")
+	assert.True(t, errors.Is(err, bank.ErrInsufficientFunds))
+
+	accountAfter, err := bankStore.GetAccountByID(ctx, account.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500.0, accountAfter.Balance)
+
+	page, err := bankStore.GetTransactionsByAccountID(ctx, account.ID, bank.TransactionFilter{}, bank.PageRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 1)
+	assert.Equal(t, transaction.ID, page.Transactions[0].ID)
+	assert.True(t, transaction.Timestamp.Equal(page.Transactions[0].Timestamp))
+
+	_, err = bankStore.GetTransactionsByAccountID(ctx, uuid.New().String(), bank.TransactionFilter{}, bank.PageRequest{})
+	assert.True(t, errors.Is(err, bank.ErrNoTransactionsForAccount))
+}
+
+func TestTransferFunds(t *testing.T) {
+	bankStore := newTestStore(t)
+	ctx := context.Background()
+
+	account1, err := bankStore.CreateAccount(ctx, "John Doe", 1000.0)
+	assert.NoError(t, err)
+	account2, err := bankStore.CreateAccount(ctx, "Jane Doe", 1500.0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, bankStore.TransferFunds(ctx, account1.ID, account2.ID, 200.0))
+
+	invalidID := uuid.New().String()
+	assert.True(t, errors.Is(bankStore.TransferFunds(ctx, invalidID, account2.ID, 10.0), bank.ErrTransferSourceNotFound))
+	assert.True(t, errors.Is(bankStore.TransferFunds(ctx, account1.ID, invalidID, 10.0), bank.ErrTransferDestinationNotFound))
+	assert.True(t, errors.Is(bankStore.TransferFunds(ctx, account1.ID, account2.ID, 5000.0), bank.ErrInsufficientFunds))
+
+	account1After, err := bankStore.GetAccountByID(ctx, account1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 800.0, account1After.Balance)
+	account2After, err := bankStore.GetAccountByID(ctx, account2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1700.0, account2After.Balance)
+}
+
+func TestConcurrentTransfersKeepTotalBalance(t *testing.T) {
+	bankStore := newTestStore(t)
+	ctx := context.Background()
+
+	account1, err := bankStore.CreateAccount(ctx, "John Doe", 1000.0)
+	require.NoError(t, err)
+	account2, err := bankStore.CreateAccount(ctx, "Jane Doe", 1000.0)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, bankStore.TransferFunds(ctx, account1.ID, account2.ID, 10.0))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, bankStore.TransferFunds(ctx, account2.ID, account1.ID, 5.0))
+		}()
+	}
+	wg.Wait()
+
+	account1After, err := bankStore.GetAccountByID(ctx, account1.ID)
+	require.NoError(t, err)
+	account2After, err := bankStore.GetAccountByID(ctx, account2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, account1After.Balance)
+	assert.Equal(t, 1100.0, account2After.Balance)
+}
+
+func TestReopenKeepsDataAndSkipsAppliedMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	ctx := context.Background()
+
+	bankStore, err := NewBankStore(ctx, path)
+	require.NoError(t, err)
+	account, err := bankStore.CreateAccount(ctx, "John Doe", 1000.0)
+	require.NoError(t, err)
+	require.NoError(t, bankStore.Close())
+
+	bankStore, err = NewBankStore(ctx, path)
+	require.NoError(t, err)
+	defer bankStore.Close()
+
+	retrievedAccount, err := bankStore.GetAccountByID(ctx, account.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, account, retrievedAccount)
+
+	var applied int
+	err = bankStore.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestPingFailsOnceClosed(t *testing.T) {
+	ctx := context.Background()
+	bankStore, err := NewBankStore(ctx, filepath.Join(t.TempDir(), "bank.db"))
+	require.NoError(t, err)
+
+	assert.NoError(t, bankStore.Ping(ctx))
+	require.NoError(t, bankStore.Close())
+	assert.Error(t, bankStore.Ping(ctx))
+}
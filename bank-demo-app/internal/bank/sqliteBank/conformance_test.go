@@ -0,0 +1,13 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank/storeConformance"
+	"bank-demo-app/internal/restServer"
+	"testing"
+)
+
+func TestBankStoreConformance(t *testing.T) {
+	storeConformance.Run(t, func(t *testing.T) restServer.BankStore {
+		return newTestStore(t)
+	})
+}
@@ -0,0 +1,182 @@
+package sqliteBank
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// migration is a single versioned schema change. Versions must be unique and
+// increasing, and already released migrations must never be edited.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create accounts and transactions tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS accounts (
+				id      TEXT PRIMARY KEY,
+				owner   TEXT NOT NULL,
+				balance REAL NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS transactions (
+				id         TEXT PRIMARY KEY,
+				account_id TEXT NOT NULL REFERENCES accounts(id),
+				type       TEXT NOT NULL,
+				amount     REAL NOT NULL,
+				timestamp  INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_account_id_timestamp ON transactions(account_id, timestamp)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_timestamp ON transactions(timestamp)`,
+		},
+	},
+	{
+		version:     2,
+		description: "add indexes for sorted and filtered lists",
+		statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_accounts_owner_id ON accounts(owner, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_accounts_balance_id ON accounts(balance, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_account_id_amount ON transactions(account_id, amount, id)`,
+		},
+	},
+	{
+		version:     3,
+		description: "add transaction reference and cross-account search indexes",
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN reference TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_timestamp_id ON transactions(timestamp, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_amount_id ON transactions(amount, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_transactions_type_timestamp ON transactions(type, timestamp, id)`,
+			`DROP INDEX IF EXISTS idx_transactions_timestamp`,
+		},
+	},
+	{
+		version:     4,
+		description: "add account status, closing time and metadata",
+		statements: []string{
+			`ALTER TABLE accounts ADD COLUMN status TEXT NOT NULL DEFAULT 'open'`,
+			`ALTER TABLE accounts ADD COLUMN closed_at INTEGER`,
+			`ALTER TABLE accounts ADD COLUMN metadata TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     5,
+		description: "create api_keys table",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS api_keys (
+				id         TEXT PRIMARY KEY,
+				name       TEXT NOT NULL,
+				prefix     TEXT NOT NULL,
+				hash       TEXT NOT NULL UNIQUE,
+				scopes     TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				revoked_at INTEGER
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_api_keys_created_at_id ON api_keys(created_at, id)`,
+		},
+	},
+	{
+		version:     6,
+		description: "create webhooks and webhook_deliveries tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS webhooks (
+				id         TEXT PRIMARY KEY,
+				url        TEXT NOT NULL,
+				events     TEXT NOT NULL,
+				secret     TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhooks_created_at_id ON webhooks(created_at, id)`,
+			`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id              TEXT PRIMARY KEY,
+				webhook_id      TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+				event_id        TEXT NOT NULL,
+				event_type      TEXT NOT NULL,
+				payload         TEXT NOT NULL,
+				status          TEXT NOT NULL,
+				attempts        TEXT NOT NULL,
+				next_attempt_at INTEGER NOT NULL,
+				created_at      INTEGER NOT NULL,
+				completed_at    INTEGER
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status_next_attempt_at ON webhook_deliveries(status, next_attempt_at, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_created_at ON webhook_deliveries(webhook_id, created_at, id)`,
+		},
+	},
+}
+
+// migrate applies every migration that is not yet recorded in the schema_migrations table.
+// Each migration runs in its own transaction, so a failure leaves the schema at the last good version.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+		log.Info().Int("version", m.version).Str("description", m.description).Msg("Applied SQLite migration")
+	}
+
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.description, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, m.version, time.Now().UTC().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,118 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"strings"
+	"time"
+)
+
+// sortColumns maps the bank sort fields to their columns.
+var sortColumns = map[string]string{
+	bank.AccountSortID:            "id",
+	bank.AccountSortOwner:         "owner",
+	bank.AccountSortBalance:       "balance",
+	bank.TransactionSortTimestamp: "timestamp",
+	bank.TransactionSortAmount:    "amount",
+}
+
+// whereClause accumulates AND-ed conditions and their positional arguments.
+type whereClause struct {
+	conditions []string
+	args       []any
+}
+
+func (w *whereClause) add(condition string, args ...any) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+func (w *whereClause) addAccountFilter(filter bank.AccountFilter) {
+	if filter.Owner != "" {
+		w.add("owner = ?", filter.Owner)
+	}
+	if filter.MinBalance != nil {
+		w.add("balance >= ?", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		w.add("balance <= ?", *filter.MaxBalance)
+	}
+}
+
+func (w *whereClause) addTransactionFilter(filter bank.TransactionFilter) {
+	if len(filter.AccountIDs) > 0 {
+		placeholders := strings.Repeat("?, ", len(filter.AccountIDs)-1) + "?"
+		args := make([]any, 0, len(filter.AccountIDs))
+		for _, accountID := range filter.AccountIDs {
+			args = append(args, accountID)
+		}
+		w.add("account_id IN ("+placeholders+")", args...)
+	}
+	if filter.Type != "" {
+		w.add("type = ?", filter.Type)
+	}
+	if filter.MinAmount != nil {
+		w.add("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		w.add("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		w.add("timestamp >= ?", filter.From.UnixNano())
+	}
+	if filter.To != nil {
+		w.add("timestamp < ?", filter.To.UnixNano())
+	}
+	if filter.Reference != "" {
+		// instr avoids LIKE wildcards in user input. lower() only folds ASCII letters.
+		w.add("instr(lower(reference), lower(?)) > 0", filter.Reference)
+	}
+}
+
+// addKeyset resumes after the cursor position using a row value comparison on (sort column, id),
+// which SQLite can answer straight from the matching index.
+func (w *whereClause) addKeyset(page bank.PageRequest, cursor *bank.Cursor) {
+	if cursor == nil {
+		return
+	}
+
+	operator := ">"
+	if page.Order == bank.SortDescending {
+		operator = "<"
+	}
+
+	column := sortColumns[page.SortBy]
+	// The cursor was validated by the caller, so its value has the right type.
+	value, _ := cursor.TypedValue()
+	if timestamp, ok := value.(time.Time); ok {
+		value = timestamp.UnixNano()
+	}
+
+	if column == "id" {
+		w.add("id "+operator+" ?", cursor.ID)
+		return
+	}
+	w.add("("+column+", id) "+operator+" (?, ?)", value, cursor.ID)
+}
+
+// orderBy returns the ORDER BY and LIMIT clause. One extra row is requested to know whether
+// another page follows.
+func orderBy(page bank.PageRequest) (string, int) {
+	direction := " ASC"
+	if page.Order == bank.SortDescending {
+		direction = " DESC"
+	}
+
+	column := sortColumns[page.SortBy]
+	clause := " ORDER BY " + column + direction
+	if column != "id" {
+		clause += ", id" + direction
+	}
+	return clause + " LIMIT ?", page.Limit + 1
+}
@@ -0,0 +1,257 @@
+package sqliteBank
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	webhookColumns  = `id, url, events, secret, created_at`
+	deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, completed_at`
+)
+
+func (bs *BankStore) CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error) {
+	if err := bank.ValidateWebhookInput(webhook.URL, webhook.Events); err != nil {
+		return nil, err
+	}
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+	_, err = bs.db.ExecContext(ctx,
+		`INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		webhook.ID, webhook.URL, string(events), webhook.Secret, webhook.CreatedAt.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (bs *BankStore) GetWebhook(ctx context.Context, id string) (*bank.Webhook, error) {
+	webhook, err := scanWebhook(bs.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bank.WebhookNotFoundError(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (bs *BankStore) ListWebhooks(ctx context.Context) ([]bank.Webhook, error) {
+	rows, err := bs.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []bank.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes the webhook, its deliveries are deleted along by the foreign key.
+func (bs *BankStore) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := bs.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if deleted == 0 {
+		return bank.WebhookNotFoundError(id)
+	}
+	return nil
+}
+
+func (bs *BankStore) EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error {
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start enqueuing webhook deliveries: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertDeliveries(ctx, tx, deliveries); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// insertDeliveries queues the deliveries within tx, but those of webhooks deleted in the meantime.
+func insertDeliveries(ctx context.Context, tx *sql.Tx, deliveries []bank.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		attempts, err := json.Marshal(delivery.Attempts)
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook delivery attempts: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO webhook_deliveries (`+deliveryColumns+`) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+			WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = ?)`,
+			delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventType, string(delivery.Payload),
+			delivery.Status, string(attempts), delivery.NextAttemptAt.UnixNano(), delivery.CreatedAt.UnixNano(),
+			nullableTime(delivery.CompletedAt), delivery.WebhookID)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, the longest overdue
+// first, and postpones them by lease in the same transaction so no other dispatcher claims them.
+func (bs *BankStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error) {
+	tx, err := bs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start claiming webhook deliveries: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id LIMIT ?`,
+		bank.WebhookDeliveryPending, now.UnixNano(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	nextAttemptAt := now.Add(lease).UTC()
+	ids := make([]any, 0, len(deliveries)+1)
+	ids = append(ids, nextAttemptAt.UnixNano())
+	for i := range deliveries {
+		deliveries[i].NextAttemptAt = nextAttemptAt
+		ids = append(ids, deliveries[i].ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(deliveries)), ", ")
+	_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (`+placeholders+`)`, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook delivery claim: %w", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery replaces the delivery. Deliveries of a webhook deleted in the meantime
+// are gone and stay so.
+func (bs *BankStore) UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error {
+	attempts, err := json.Marshal(delivery.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook delivery attempts: %w", err)
+	}
+	_, err = bs.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, completed_at = ? WHERE id = ?`,
+		delivery.Status, string(attempts), delivery.NextAttemptAt.UnixNano(), nullableTime(delivery.CompletedAt), delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the deliveries of the webhook matching filter, newest first.
+func (bs *BankStore) ListWebhookDeliveries(ctx context.Context, webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error) {
+	if err := bank.ValidateWebhookDeliveryFilter(&filter); err != nil {
+		return nil, err
+	}
+	if _, err := bs.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := bs.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = ? AND (? = '' OR status = ?)
+		ORDER BY created_at DESC, id DESC LIMIT ?`,
+		webhookID, filter.Status, filter.Status, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func nullableTime(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+// scanWebhook decodes a row selected with webhookColumns.
+func scanWebhook(row scanner) (*bank.Webhook, error) {
+	var webhook bank.Webhook
+	var events string
+	var createdAt int64
+	if err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return nil, fmt.Errorf("failed to decode events of webhook %s: %w", webhook.ID, err)
+	}
+	webhook.CreatedAt = time.Unix(0, createdAt).UTC()
+
+	return &webhook, nil
+}
+
+// scanDeliveries decodes and closes rows selected with deliveryColumns.
+func scanDeliveries(rows *sql.Rows) ([]bank.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []bank.WebhookDelivery{}
+	for rows.Next() {
+		var delivery bank.WebhookDelivery
+		var payload, attempts string
+		var nextAttemptAt, createdAt int64
+		var completedAt sql.NullInt64
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload,
+			&delivery.Status, &attempts, &nextAttemptAt, &createdAt, &completedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Payload = json.RawMessage(payload)
+		if err := json.Unmarshal([]byte(attempts), &delivery.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to decode attempts of webhook delivery %s: %w", delivery.ID, err)
+		}
+		delivery.NextAttemptAt = time.Unix(0, nextAttemptAt).UTC()
+		delivery.CreatedAt = time.Unix(0, createdAt).UTC()
+		if completedAt.Valid {
+			timestamp := time.Unix(0, completedAt.Int64).UTC()
+			delivery.CompletedAt = &timestamp
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,93 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *BankStoreSuite) createAPIKey(name string, scopes ...string) (*bank.APIKey, string) {
+	return s.createAPIKeyAt(time.Now(), name, scopes...)
+}
+
+func (s *BankStoreSuite) createAPIKeyAt(createdAt time.Time, name string, scopes ...string) (*bank.APIKey, string) {
+	key, token, err := bank.NewAPIKey(name, scopes, createdAt)
+	s.Require().NoError(err)
+	stored, err := s.store.CreateAPIKey(s.ctx, key)
+	s.Require().NoError(err)
+	return stored, token
+}
+
+func (s *BankStoreSuite) TestCreateAndGetAPIKey() {
+	key, token := s.createAPIKey("reporting", bank.ScopeAccountsRead, bank.ScopeTransactionsRead)
+	s.NotEmpty(key.ID)
+	s.NotContains(key.Hash, token, "the token must not be stored in clear")
+
+	retrieved, err := s.store.GetAPIKeyByHash(s.ctx, bank.HashAPIKeyToken(token))
+	s.Require().NoError(err)
+	s.Equal(key.ID, retrieved.ID)
+	s.Equal("reporting", retrieved.Name)
+	s.Equal(key.Prefix, retrieved.Prefix)
+	s.Equal([]string{bank.ScopeAccountsRead, bank.ScopeTransactionsRead}, retrieved.Scopes)
+	s.True(key.CreatedAt.Equal(retrieved.CreatedAt))
+	s.Nil(retrieved.RevokedAt)
+}
+
+func (s *BankStoreSuite) TestGetAPIKeyByUnknownHash() {
+	key, err := s.store.GetAPIKeyByHash(s.ctx, bank.HashAPIKeyToken("bk_unknown-token-value"))
+	s.ErrorIs(err, bank.ErrAPIKeyNotFound)
+	s.Nil(key)
+}
+
+func (s *BankStoreSuite) TestCreateAPIKeyRejectsInvalidInput() {
+	key, err := s.store.CreateAPIKey(s.ctx, bank.APIKey{ID: uuid.NewString(), Name: "ops", Hash: "hash", Scopes: []string{"accounts:delete"}})
+	s.ErrorIs(err, bank.ErrInvalidAPIKey)
+	s.Nil(key)
+
+	keys, err := s.store.ListAPIKeys(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(keys, "rejected keys must not be stored")
+}
+
+func (s *BankStoreSuite) TestListAPIKeys() {
+	keys, err := s.store.ListAPIKeys(s.ctx)
+	s.Require().NoError(err)
+	s.NotNil(keys, "an empty store must list an empty slice, not nil")
+	s.Empty(keys)
+
+	createdAt := time.Now()
+	second, _ := s.createAPIKeyAt(createdAt.Add(time.Second), "second", bank.ScopeTransfersWrite)
+	first, _ := s.createAPIKeyAt(createdAt, "first", bank.ScopeAdmin)
+
+	keys, err = s.store.ListAPIKeys(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 2)
+	s.Equal(first.ID, keys[0].ID, "keys must be listed oldest first")
+	s.Equal(second.ID, keys[1].ID)
+}
+
+func (s *BankStoreSuite) TestRevokeAPIKey() {
+	key, token := s.createAPIKey("partner", bank.ScopeTransfersWrite)
+	revokedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	revoked, err := s.store.RevokeAPIKey(s.ctx, key.ID, revokedAt)
+	s.Require().NoError(err)
+	s.Require().NotNil(revoked.RevokedAt)
+	s.True(revokedAt.Equal(*revoked.RevokedAt))
+
+	// Revoked keys are still found, so the caller can tell them apart from unknown tokens.
+	retrieved, err := s.store.GetAPIKeyByHash(s.ctx, bank.HashAPIKeyToken(token))
+	s.Require().NoError(err)
+	s.True(retrieved.IsRevoked())
+
+	again, err := s.store.RevokeAPIKey(s.ctx, key.ID, revokedAt.Add(time.Hour))
+	s.Require().NoError(err)
+	s.True(revokedAt.Equal(*again.RevokedAt), "revoking twice must keep the first revocation time")
+}
+
+func (s *BankStoreSuite) TestRevokeUnknownAPIKey() {
+	key, err := s.store.RevokeAPIKey(s.ctx, uuid.NewString(), time.Now())
+	s.ErrorIs(err, bank.ErrAPIKeyNotFound)
+	s.Nil(key)
+}
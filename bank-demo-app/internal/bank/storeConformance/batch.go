@@ -0,0 +1,179 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+func (s *BankStoreSuite) TestAtomicBatch() {
+	alex := s.createAccount("Alex Camara", 100.0)
+	john := s.createAccount("John Doe", 0)
+
+	result, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: alex.ID, Amount: 50.0, Reference: "Top up"},
+		{Type: bank.BatchTransfer, FromAccountID: alex.ID, ToAccountID: john.ID, Amount: 150.0},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 25.0},
+	}})
+	s.Require().NoError(err)
+	s.True(result.Atomic)
+	s.Equal(3, result.Succeeded)
+	s.Zero(result.Failed)
+	s.Require().Len(result.Results, 3)
+	for i, operation := range result.Results {
+		s.Equal(i, operation.Index)
+		s.Equal(bank.BatchOperationSucceeded, operation.Status)
+		s.NoError(operation.Err)
+	}
+	s.Require().NotNil(result.Results[0].Transaction)
+	s.Equal("Top up", result.Results[0].Transaction.Reference)
+	s.Nil(result.Results[1].Transaction, "transfers don't record transactions")
+	s.Require().NotNil(result.Results[2].Transaction)
+
+	s.Equal(0.0, s.balanceOf(alex.ID))
+	s.Equal(125.0, s.balanceOf(john.ID))
+
+	stored, err := s.store.GetTransactionByID(s.ctx, result.Results[2].Transaction.ID)
+	s.Require().NoError(err)
+	s.Equal(bank.WithdrawalTransactionType, stored.Type)
+}
+
+func (s *BankStoreSuite) TestAtomicBatchRollsBackOnFirstFailure() {
+	alex := s.createAccount("Alex Camara", 100.0)
+	john := s.createAccount("John Doe", 10.0)
+	existing, err := s.store.PerformTransaction(s.ctx, alex.ID, bank.DepositTransactionType, 1.0, "")
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name          string
+		failing       bank.BatchOperation
+		expectedError error
+	}{
+		{"insufficient funds", bank.BatchOperation{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 1000.0}, bank.ErrInsufficientFunds},
+		{"unknown account", bank.BatchOperation{Type: bank.BatchDeposit, AccountID: uuid.NewString(), Amount: 1.0}, bank.ErrAccountNotFound},
+		{"unknown transfer destination", bank.BatchOperation{Type: bank.BatchTransfer, FromAccountID: alex.ID, ToAccountID: uuid.NewString(), Amount: 1.0}, bank.ErrTransferDestinationNotFound},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			result, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+				{Type: bank.BatchDeposit, AccountID: alex.ID, Amount: 50.0},
+				{Type: bank.BatchTransfer, FromAccountID: alex.ID, ToAccountID: john.ID, Amount: 120.0},
+				{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 5.0},
+				test.failing,
+				{Type: bank.BatchDeposit, AccountID: john.ID, Amount: 1.0},
+			}})
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(result)
+
+			var operationErr *bank.BatchOperationError
+			s.Require().ErrorAs(err, &operationErr)
+			s.Equal(3, operationErr.Index)
+
+			s.Equal(101.0, s.balanceOf(alex.ID))
+			s.Equal(10.0, s.balanceOf(john.ID))
+
+			transactions, err := s.transactionsOf(alex.ID)
+			s.Require().NoError(err)
+			s.Equal([]string{existing.ID}, transactionIDs(transactions), "rolled back operations must not leave history")
+			_, err = s.transactionsOf(john.ID)
+			s.ErrorIs(err, bank.ErrNoTransactionsForAccount)
+		})
+	}
+}
+
+func (s *BankStoreSuite) TestAtomicBatchRejectsClosedAccounts() {
+	alex := s.createAccount("Alex Camara", 100.0)
+	closed := s.createAccount("John Doe", 0)
+	_, err := s.store.CloseAccount(s.ctx, closed.ID, "")
+	s.Require().NoError(err)
+
+	_, err = s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchWithdrawal, AccountID: alex.ID, Amount: 10.0},
+		{Type: bank.BatchTransfer, FromAccountID: alex.ID, ToAccountID: closed.ID, Amount: 10.0},
+	}})
+	s.ErrorIs(err, bank.ErrAccountClosed)
+	s.Equal(100.0, s.balanceOf(alex.ID))
+}
+
+func (s *BankStoreSuite) TestAtomicBatchValidatesEveryOperationFirst() {
+	alex := s.createAccount("Alex Camara", 100.0)
+
+	_, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: alex.ID, Amount: 10.0},
+		{Type: "refund", AccountID: alex.ID, Amount: 10.0},
+	}})
+	s.ErrorIs(err, bank.ErrInvalidBatch)
+	var operationErr *bank.BatchOperationError
+	s.Require().ErrorAs(err, &operationErr)
+	s.Equal(1, operationErr.Index)
+	s.Equal(100.0, s.balanceOf(alex.ID))
+}
+
+func (s *BankStoreSuite) TestBestEffortBatch() {
+	alex := s.createAccount("Alex Camara", 100.0)
+	john := s.createAccount("John Doe", 0)
+
+	result, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: alex.ID, Amount: 50.0},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 10.0},
+		{Type: bank.BatchTransfer, FromAccountID: alex.ID, ToAccountID: john.ID, Amount: 30.0},
+		{Type: "refund", AccountID: alex.ID, Amount: 10.0},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 10.0},
+	}})
+	s.Require().NoError(err)
+	s.False(result.Atomic)
+	s.Equal(3, result.Succeeded)
+	s.Equal(2, result.Failed)
+
+	s.Require().Len(result.Results, 5)
+	expected := []struct {
+		status string
+		err    error
+	}{
+		{bank.BatchOperationSucceeded, nil},
+		{bank.BatchOperationFailed, bank.ErrInsufficientFunds},
+		{bank.BatchOperationSucceeded, nil},
+		{bank.BatchOperationFailed, bank.ErrInvalidBatch},
+		{bank.BatchOperationSucceeded, nil},
+	}
+	for i, operation := range result.Results {
+		s.Equal(i, operation.Index)
+		s.Equal(expected[i].status, operation.Status)
+		if expected[i].err != nil {
+			s.ErrorIs(operation.Err, expected[i].err)
+			s.Nil(operation.Transaction)
+		} else {
+			s.NoError(operation.Err)
+		}
+	}
+
+	s.Equal(120.0, s.balanceOf(alex.ID))
+	s.Equal(20.0, s.balanceOf(john.ID))
+}
+
+func (s *BankStoreSuite) TestBatchRejectsInvalidSize() {
+	for _, atomic := range []bool{true, false} {
+		_, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: atomic})
+		s.ErrorIs(err, bank.ErrInvalidBatch)
+
+		operations := make([]bank.BatchOperation, bank.MaxBatchOperations+1)
+		_, err = s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: atomic, Operations: operations})
+		s.ErrorIs(err, bank.ErrInvalidBatch)
+	}
+}
+
+func (s *BankStoreSuite) TestCanceledContextIsRejectedByAtomicBatch() {
+	alex := s.createAccount("Alex Camara", 100.0)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	result, err := s.store.ExecuteBatch(ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: alex.ID, Amount: 10.0},
+	}})
+	s.ErrorIs(err, context.Canceled)
+	s.Nil(result)
+	s.Equal(100.0, s.balanceOf(alex.ID))
+}
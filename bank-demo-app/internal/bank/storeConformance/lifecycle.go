@@ -0,0 +1,236 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func (s *BankStoreSuite) TestCreatedAccountIsOpen() {
+	account := s.createAccount("Alex Camara", 10.0)
+	s.Equal(bank.AccountStatusOpen, account.Status)
+	s.Nil(account.ClosedAt)
+	s.Empty(account.Metadata)
+}
+
+func (s *BankStoreSuite) TestUpdateAccount() {
+	account := s.createAccount("Alex Camara", 100.0)
+
+	updated, err := s.store.UpdateAccount(s.ctx, account.ID, bank.AccountUpdate{
+		Owner:    stringPtr("Alex Cámara"),
+		Metadata: map[string]*string{"branch": stringPtr("BCN"), "tier": stringPtr("gold")},
+	})
+	s.Require().NoError(err)
+	s.Equal("Alex Cámara", updated.Owner)
+	s.Equal(100.0, updated.Balance)
+	s.Equal(map[string]string{"branch": "BCN", "tier": "gold"}, updated.Metadata)
+
+	// Merge: untouched keys stay, null removes.
+	updated, err = s.store.UpdateAccount(s.ctx, account.ID, bank.AccountUpdate{
+		Metadata: map[string]*string{"tier": nil, "segment": stringPtr("retail")},
+	})
+	s.Require().NoError(err)
+	s.Equal("Alex Cámara", updated.Owner, "a nil owner leaves it unchanged")
+	s.Equal(map[string]string{"branch": "BCN", "segment": "retail"}, updated.Metadata)
+
+	retrieved, err := s.store.GetAccountByID(s.ctx, account.ID)
+	s.Require().NoError(err)
+	s.Equal(updated, retrieved)
+
+	// Replace: only the given keys survive.
+	updated, err = s.store.UpdateAccount(s.ctx, account.ID, bank.AccountUpdate{
+		Owner:           stringPtr("Alex Camara"),
+		ReplaceMetadata: true,
+	})
+	s.Require().NoError(err)
+	s.Empty(updated.Metadata)
+
+	retrieved, err = s.store.GetAccountByID(s.ctx, account.ID)
+	s.Require().NoError(err)
+	s.Equal("Alex Camara", retrieved.Owner)
+	s.Empty(retrieved.Metadata)
+}
+
+func (s *BankStoreSuite) TestUpdateAccountErrors() {
+	account := s.createAccount("Alex Camara", 100.0)
+	full := make(map[string]*string, bank.MaxMetadataKeys)
+	for i := 0; i < bank.MaxMetadataKeys; i++ {
+		full[strings.Repeat("k", i+1)] = stringPtr("v")
+	}
+	_, err := s.store.UpdateAccount(s.ctx, account.ID, bank.AccountUpdate{Metadata: full})
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name          string
+		accountID     string
+		update        bank.AccountUpdate
+		expectedError error
+	}{
+		{"unknown account", uuid.New().String(), bank.AccountUpdate{Owner: stringPtr("John Doe")}, bank.ErrAccountNotFound},
+		{"empty owner", account.ID, bank.AccountUpdate{Owner: stringPtr("")}, bank.ErrEmptyOwnerName},
+		{"invalid key", account.ID, bank.AccountUpdate{Metadata: map[string]*string{"a.b": stringPtr("v")}}, bank.ErrInvalidMetadata},
+		{"value too long", account.ID, bank.AccountUpdate{Metadata: map[string]*string{"k": stringPtr(strings.Repeat("v", bank.MaxMetadataValueLength+1))}}, bank.ErrInvalidMetadata},
+		{"too many keys after merge", account.ID, bank.AccountUpdate{Metadata: map[string]*string{"extra": stringPtr("v")}}, bank.ErrInvalidMetadata},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			updated, err := s.store.UpdateAccount(s.ctx, test.accountID, test.update)
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(updated)
+		})
+	}
+
+	retrieved, err := s.store.GetAccountByID(s.ctx, account.ID)
+	s.Require().NoError(err)
+	s.Equal("Alex Camara", retrieved.Owner)
+	s.Len(retrieved.Metadata, bank.MaxMetadataKeys)
+}
+
+func (s *BankStoreSuite) TestCloseAccountWithZeroBalance() {
+	account := s.createAccount("Alex Camara", 100.0)
+	_, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 100.0, "")
+	s.Require().NoError(err)
+
+	closure, err := s.store.CloseAccount(s.ctx, account.ID, "")
+	s.Require().NoError(err)
+	closed := closure.Account
+	s.Equal(bank.AccountStatusClosed, closed.Status)
+	s.Require().NotNil(closed.ClosedAt)
+	s.Nil(closure.Withdrawal, "nothing is paid out of an empty account")
+
+	retrieved, err := s.store.GetAccountByID(s.ctx, account.ID)
+	s.Require().NoError(err)
+	s.True(retrieved.IsClosed())
+	s.True(closed.ClosedAt.Equal(*retrieved.ClosedAt))
+
+	transactions, err := s.transactionsOf(account.ID)
+	s.NoError(err, "the history of a closed account stays queryable")
+	s.Len(transactions, 1)
+	s.Len(s.listAccounts(), 1, "closed accounts are still listed")
+}
+
+func (s *BankStoreSuite) TestCloseAccountWithPayout() {
+	account := s.createAccount("Alex Camara", 250.0)
+	payout := s.createAccount("Alex Camara", 50.0)
+
+	closure, err := s.store.CloseAccount(s.ctx, account.ID, payout.ID)
+	s.Require().NoError(err)
+	s.True(closure.Account.IsClosed())
+	s.Equal(0.0, closure.Account.Balance)
+	s.Equal(0.0, s.balanceOf(account.ID))
+	s.Equal(300.0, s.balanceOf(payout.ID))
+
+	reference := bank.ClosureReference(account.ID)
+	s.Require().NotNil(closure.Withdrawal)
+	s.Require().NotNil(closure.Deposit)
+
+	withdrawals, err := s.transactionsOf(account.ID)
+	s.Require().NoError(err, "the payout is recorded on the closed account")
+	s.Require().Len(withdrawals, 1)
+	s.Equal(*closure.Withdrawal, withdrawals[0])
+	s.Equal(bank.WithdrawalTransactionType, withdrawals[0].Type)
+	s.Equal(250.0, withdrawals[0].Amount)
+	s.Equal(reference, withdrawals[0].Reference)
+
+	deposits, err := s.transactionsOf(payout.ID)
+	s.Require().NoError(err, "the payout is recorded on the payout account")
+	s.Require().Len(deposits, 1)
+	s.Equal(*closure.Deposit, deposits[0])
+	s.Equal(bank.DepositTransactionType, deposits[0].Type)
+	s.Equal(250.0, deposits[0].Amount)
+	s.Equal(reference, deposits[0].Reference)
+}
+
+func (s *BankStoreSuite) TestCloseEmptyAccountWithPayoutRecordsNothing() {
+	account := s.createAccount("Alex Camara", 0)
+	payout := s.createAccount("Alex Camara", 50.0)
+
+	closure, err := s.store.CloseAccount(s.ctx, account.ID, payout.ID)
+	s.Require().NoError(err)
+	s.Nil(closure.Withdrawal)
+	s.Nil(closure.Deposit)
+	s.Equal(50.0, s.balanceOf(payout.ID))
+
+	_, err = s.transactionsOf(payout.ID)
+	s.ErrorIs(err, bank.ErrNoTransactionsForAccount)
+}
+
+func (s *BankStoreSuite) TestCloseAccountErrors() {
+	funded := s.createAccount("Alex Camara", 100.0)
+	empty := s.createAccount("John Doe", 0)
+	closedPayout := s.createAccount("Jane Doe", 0)
+	_, err := s.store.CloseAccount(s.ctx, closedPayout.ID, "")
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name          string
+		accountID     string
+		payoutID      string
+		expectedError error
+	}{
+		{"unknown account", uuid.New().String(), "", bank.ErrAccountNotFound},
+		{"balance without payout", funded.ID, "", bank.ErrAccountBalanceNotZero},
+		{"unknown payout", funded.ID, uuid.New().String(), bank.ErrTransferDestinationNotFound},
+		{"payout to itself", funded.ID, funded.ID, bank.ErrSameSourceDestination},
+		{"closed payout", funded.ID, closedPayout.ID, bank.ErrAccountClosed},
+		{"already closed", closedPayout.ID, "", bank.ErrAccountClosed},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			account, err := s.store.CloseAccount(s.ctx, test.accountID, test.payoutID)
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(account)
+		})
+	}
+
+	s.Equal(100.0, s.balanceOf(funded.ID))
+	retrieved, err := s.store.GetAccountByID(s.ctx, empty.ID)
+	s.Require().NoError(err)
+	s.False(retrieved.IsClosed())
+}
+
+func (s *BankStoreSuite) TestClosedAccountRejectsChanges() {
+	closed := s.createAccount("Alex Camara", 0)
+	open := s.createAccount("John Doe", 100.0)
+	_, err := s.store.CloseAccount(s.ctx, closed.ID, "")
+	s.Require().NoError(err)
+
+	transaction, err := s.store.PerformTransaction(s.ctx, closed.ID, bank.DepositTransactionType, 10.0, "")
+	s.ErrorIs(err, bank.ErrAccountClosed)
+	s.Nil(transaction)
+
+	s.ErrorIs(s.store.TransferFunds(s.ctx, open.ID, closed.ID, 10.0), bank.ErrAccountClosed)
+	s.ErrorIs(s.store.TransferFunds(s.ctx, closed.ID, open.ID, 10.0), bank.ErrAccountClosed)
+
+	updated, err := s.store.UpdateAccount(s.ctx, closed.ID, bank.AccountUpdate{Owner: stringPtr("Someone Else")})
+	s.ErrorIs(err, bank.ErrAccountClosed)
+	s.Nil(updated)
+
+	s.Equal(0.0, s.balanceOf(closed.ID))
+	s.Equal(100.0, s.balanceOf(open.ID))
+}
+
+func (s *BankStoreSuite) TestCanceledContextIsRejectedByLifecycle() {
+	account := s.createAccount("Alex Camara", 0)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	_, err := s.store.UpdateAccount(ctx, account.ID, bank.AccountUpdate{Owner: stringPtr("John Doe")})
+	s.ErrorIs(err, context.Canceled)
+	_, err = s.store.CloseAccount(ctx, account.ID, "")
+	s.ErrorIs(err, context.Canceled)
+
+	retrieved, err := s.store.GetAccountByID(s.ctx, account.ID)
+	s.Require().NoError(err)
+	s.Equal("Alex Camara", retrieved.Owner)
+	s.False(retrieved.IsClosed())
+}
@@ -0,0 +1,250 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"time"
+)
+
+// collectAccounts follows NextCursor from the first page to the last one.
+func (s *BankStoreSuite) collectAccounts(filter bank.AccountFilter, page bank.PageRequest) ([]bank.Account, int) {
+	var accounts []bank.Account
+	pages := 0
+	for {
+		result, err := s.store.ListAccounts(s.ctx, filter, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(result.Accounts), page.Limit)
+		accounts = append(accounts, result.Accounts...)
+		pages++
+		if result.NextCursor == "" {
+			return accounts, pages
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// collectTransactions follows NextCursor from the first page to the last one.
+func (s *BankStoreSuite) collectTransactions(accountID string, filter bank.TransactionFilter, page bank.PageRequest) []bank.Transaction {
+	var transactions []bank.Transaction
+	for {
+		result, err := s.store.GetTransactionsByAccountID(s.ctx, accountID, filter, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(result.Transactions), page.Limit)
+		transactions = append(transactions, result.Transactions...)
+		if result.NextCursor == "" {
+			return transactions
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+func accountIDs(accounts []bank.Account) []string {
+	ids := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		ids = append(ids, account.ID)
+	}
+	return ids
+}
+
+func transactionIDs(transactions []bank.Transaction) []string {
+	ids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		ids = append(ids, transaction.ID)
+	}
+	return ids
+}
+
+func (s *BankStoreSuite) TestListAccountsPagination() {
+	balances := []float64{300, 100, 500, 200, 400}
+	for _, balance := range balances {
+		s.createAccount("Alex Camara", balance)
+	}
+
+	accounts, pages := s.collectAccounts(bank.AccountFilter{}, bank.PageRequest{Limit: 2, SortBy: bank.AccountSortBalance, Order: bank.SortDescending})
+	s.Equal(3, pages)
+	s.Require().Len(accounts, len(balances))
+	for i, expected := range []float64{500, 400, 300, 200, 100} {
+		s.Equal(expected, accounts[i].Balance)
+	}
+}
+
+func (s *BankStoreSuite) TestListAccountsPaginationIsStableOnTies() {
+	for i := 0; i < 7; i++ {
+		s.createAccount("Same Owner", 100.0)
+	}
+
+	all := s.listAccounts()
+	paged, _ := s.collectAccounts(bank.AccountFilter{}, bank.PageRequest{Limit: 3, SortBy: bank.AccountSortOwner})
+	s.Len(paged, len(all))
+	s.ElementsMatch(accountIDs(all), accountIDs(paged), "pages must neither repeat nor skip accounts")
+	s.IsIncreasing(accountIDs(paged), "ties must be broken by ID")
+}
+
+func (s *BankStoreSuite) TestListAccountsDefaultOrderIsByID() {
+	for i := 0; i < 4; i++ {
+		s.createAccount("Alex Camara", float64(i))
+	}
+	s.IsIncreasing(accountIDs(s.listAccounts()))
+}
+
+func (s *BankStoreSuite) TestListAccountsFilters() {
+	alex := s.createAccount("Alex Camara", 50.0)
+	s.createAccount("John Doe", 150.0)
+	jane := s.createAccount("Jane Doe", 250.0)
+	rich := s.createAccount("Alex Camara", 1000.0)
+
+	minBalance, maxBalance := 100.0, 250.0
+	page, err := s.store.ListAccounts(s.ctx, bank.AccountFilter{Owner: "Alex Camara"}, bank.PageRequest{})
+	s.Require().NoError(err)
+	s.ElementsMatch([]bank.Account{*alex, *rich}, page.Accounts)
+
+	page, err = s.store.ListAccounts(s.ctx, bank.AccountFilter{MinBalance: &minBalance, MaxBalance: &maxBalance}, bank.PageRequest{SortBy: bank.AccountSortBalance})
+	s.Require().NoError(err)
+	s.Require().Len(page.Accounts, 2)
+	s.Equal(150.0, page.Accounts[0].Balance, "balance bounds are inclusive")
+	s.Equal(*jane, page.Accounts[1])
+
+	page, err = s.store.ListAccounts(s.ctx, bank.AccountFilter{Owner: "Nobody"}, bank.PageRequest{})
+	s.Require().NoError(err)
+	s.Empty(page.Accounts)
+}
+
+func (s *BankStoreSuite) TestListAccountsInvalidPage() {
+	s.createAccount("Alex Camara", 100.0)
+	minBalance, maxBalance := 200.0, 100.0
+
+	tests := []struct {
+		name          string
+		filter        bank.AccountFilter
+		page          bank.PageRequest
+		expectedError error
+	}{
+		{"negative limit", bank.AccountFilter{}, bank.PageRequest{Limit: -1}, bank.ErrInvalidPageLimit},
+		{"limit too large", bank.AccountFilter{}, bank.PageRequest{Limit: bank.MaxPageLimit + 1}, bank.ErrInvalidPageLimit},
+		{"unknown sort", bank.AccountFilter{}, bank.PageRequest{SortBy: "timestamp"}, bank.ErrInvalidSortField},
+		{"unknown order", bank.AccountFilter{}, bank.PageRequest{Order: "up"}, bank.ErrInvalidSortOrder},
+		{"garbage cursor", bank.AccountFilter{}, bank.PageRequest{Cursor: "not-a-cursor"}, bank.ErrInvalidCursor},
+		{"empty range", bank.AccountFilter{MinBalance: &minBalance, MaxBalance: &maxBalance}, bank.PageRequest{}, bank.ErrInvalidFilter},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			page, err := s.store.ListAccounts(s.ctx, test.filter, test.page)
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(page)
+		})
+	}
+}
+
+func (s *BankStoreSuite) TestCursorIsBoundToItsSort() {
+	for i := 0; i < 3; i++ {
+		s.createAccount("Alex Camara", float64(i))
+	}
+
+	page, err := s.store.ListAccounts(s.ctx, bank.AccountFilter{}, bank.PageRequest{Limit: 1, SortBy: bank.AccountSortBalance})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(page.NextCursor)
+
+	_, err = s.store.ListAccounts(s.ctx, bank.AccountFilter{}, bank.PageRequest{Limit: 1, SortBy: bank.AccountSortOwner, Cursor: page.NextCursor})
+	s.ErrorIs(err, bank.ErrInvalidCursor)
+	_, err = s.store.ListAccounts(s.ctx, bank.AccountFilter{}, bank.PageRequest{Limit: 1, SortBy: bank.AccountSortBalance, Order: bank.SortDescending, Cursor: page.NextCursor})
+	s.ErrorIs(err, bank.ErrInvalidCursor)
+}
+
+func (s *BankStoreSuite) TestTransactionsPaginationAndSorting() {
+	account := s.createAccount("Alex Camara", 0)
+
+	var created []*bank.Transaction
+	for _, amount := range []float64{30, 10, 50, 20, 40} {
+		transaction, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, amount, "")
+		s.Require().NoError(err)
+		created = append(created, transaction)
+	}
+
+	byTime := s.collectTransactions(account.ID, bank.TransactionFilter{}, bank.PageRequest{Limit: 2})
+	s.Equal(transactionIDs(derefTransactions(created)), transactionIDs(byTime))
+
+	byAmount := s.collectTransactions(account.ID, bank.TransactionFilter{}, bank.PageRequest{Limit: 2, SortBy: bank.TransactionSortAmount, Order: bank.SortDescending})
+	s.Require().Len(byAmount, 5)
+	for i, expected := range []float64{50, 40, 30, 20, 10} {
+		s.Equal(expected, byAmount[i].Amount)
+	}
+}
+
+func (s *BankStoreSuite) TestTransactionsFilters() {
+	account := s.createAccount("Alex Camara", 1000.0)
+
+	var created []*bank.Transaction
+	operations := []struct {
+		txType string
+		amount float64
+	}{
+		{bank.DepositTransactionType, 100},
+		{bank.WithdrawalTransactionType, 200},
+		{bank.DepositTransactionType, 300},
+		{bank.WithdrawalTransactionType, 400},
+	}
+	for _, operation := range operations {
+		transaction, err := s.store.PerformTransaction(s.ctx, account.ID, operation.txType, operation.amount, "")
+		s.Require().NoError(err)
+		created = append(created, transaction)
+		// Keep timestamps distinct even on stores with millisecond precision.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	minAmount, maxAmount := 200.0, 300.0
+	tests := []struct {
+		name     string
+		filter   bank.TransactionFilter
+		expected []*bank.Transaction
+	}{
+		{"by type", bank.TransactionFilter{Type: bank.WithdrawalTransactionType}, []*bank.Transaction{created[1], created[3]}},
+		{"by amount range", bank.TransactionFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}, []*bank.Transaction{created[1], created[2]}},
+		{"by time range", bank.TransactionFilter{From: &created[1].Timestamp, To: &created[3].Timestamp}, []*bank.Transaction{created[1], created[2]}},
+		{"combined", bank.TransactionFilter{Type: bank.DepositTransactionType, MinAmount: &minAmount}, []*bank.Transaction{created[2]}},
+		{"exact amount", bank.TransactionFilter{Type: bank.DepositTransactionType, MinAmount: &maxAmount, MaxAmount: &maxAmount}, []*bank.Transaction{created[2]}},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			transactions := s.collectTransactions(account.ID, test.filter, bank.PageRequest{Limit: 1})
+			s.Equal(transactionIDs(derefTransactions(test.expected)), transactionIDs(transactions))
+		})
+	}
+
+	tooHigh := 10000.0
+	page, err := s.store.GetTransactionsByAccountID(s.ctx, account.ID, bank.TransactionFilter{MinAmount: &tooHigh}, bank.PageRequest{})
+	s.NoError(err, "an account with history must not fail when the filter matches nothing")
+	s.Empty(page.Transactions)
+}
+
+func (s *BankStoreSuite) TestTransactionsInvalidFilter() {
+	account := s.createAccount("Alex Camara", 1000.0)
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		filter        bank.TransactionFilter
+		expectedError error
+	}{
+		{"unknown type", bank.TransactionFilter{Type: "refund"}, bank.ErrInvalidTransaction},
+		{"empty time range", bank.TransactionFilter{From: &now, To: &now}, bank.ErrInvalidFilter},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			_, err := s.store.GetTransactionsByAccountID(s.ctx, account.ID, test.filter, bank.PageRequest{})
+			s.ErrorIs(err, test.expectedError)
+		})
+	}
+
+	_, err := s.store.GetTransactionsByAccountID(s.ctx, account.ID, bank.TransactionFilter{}, bank.PageRequest{SortBy: bank.AccountSortBalance})
+	s.ErrorIs(err, bank.ErrInvalidSortField)
+}
+
+func derefTransactions(transactions []*bank.Transaction) []bank.Transaction {
+	values := make([]bank.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		values = append(values, *transaction)
+	}
+	return values
+}
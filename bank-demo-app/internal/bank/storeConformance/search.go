@@ -0,0 +1,157 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seedSearch creates two accounts with a mix of transactions, spaced so their timestamps are distinct
+// even on stores with millisecond precision.
+func (s *BankStoreSuite) seedSearch() (*bank.Account, *bank.Account, []*bank.Transaction) {
+	alex := s.createAccount("Alex Camara", 100000.0)
+	john := s.createAccount("John Doe", 100000.0)
+	s.createAccount("Jane Doe", 100000.0)
+
+	operations := []struct {
+		account   *bank.Account
+		txType    string
+		amount    float64
+		reference string
+	}{
+		{alex, bank.DepositTransactionType, 500, "Salary March"},
+		{john, bank.WithdrawalTransactionType, 15000, "Car purchase"},
+		{alex, bank.WithdrawalTransactionType, 12000, "rent MARCH"},
+		{john, bank.DepositTransactionType, 20000, ""},
+		{alex, bank.WithdrawalTransactionType, 50, "coffee"},
+	}
+
+	var created []*bank.Transaction
+	for _, operation := range operations {
+		transaction, err := s.store.PerformTransaction(s.ctx, operation.account.ID, operation.txType, operation.amount, operation.reference)
+		s.Require().NoError(err)
+		created = append(created, transaction)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	return alex, john, created
+}
+
+// collectSearch follows NextCursor from the first page to the last one and checks that every page
+// carries the same summary.
+func (s *BankStoreSuite) collectSearch(filter bank.TransactionFilter, page bank.PageRequest) ([]bank.Transaction, bank.TransactionSummary) {
+	var transactions []bank.Transaction
+	var summary *bank.TransactionSummary
+	for {
+		result, err := s.store.SearchTransactions(s.ctx, filter, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(result.Transactions), page.Limit)
+		if summary != nil {
+			s.Equal(*summary, result.Summary, "the summary covers every match, not the current page")
+		}
+		summary = &result.Summary
+		transactions = append(transactions, result.Transactions...)
+		if result.NextCursor == "" {
+			return transactions, *summary
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+func (s *BankStoreSuite) TestPerformTransactionKeepsReference() {
+	account := s.createAccount("Alex Camara", 100.0)
+
+	transaction, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 10.0, "Invoice 42")
+	s.Require().NoError(err)
+	s.Equal("Invoice 42", transaction.Reference)
+
+	retrieved, err := s.store.GetTransactionByID(s.ctx, transaction.ID)
+	s.Require().NoError(err)
+	s.Equal("Invoice 42", retrieved.Reference)
+
+	transaction, err = s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 10.0, strings.Repeat("x", bank.MaxReferenceLength+1))
+	s.ErrorIs(err, bank.ErrReferenceTooLong)
+	s.Nil(transaction)
+	s.Equal(110.0, s.balanceOf(account.ID))
+}
+
+func (s *BankStoreSuite) TestSearchTransactions() {
+	alex, john, created := s.seedSearch()
+
+	minAmount := 10000.0
+	tests := []struct {
+		name     string
+		filter   bank.TransactionFilter
+		expected []*bank.Transaction
+	}{
+		{"everything", bank.TransactionFilter{}, created},
+		{"large withdrawals", bank.TransactionFilter{Type: bank.WithdrawalTransactionType, MinAmount: &minAmount}, []*bank.Transaction{created[1], created[2]}},
+		{"one account", bank.TransactionFilter{AccountIDs: []string{alex.ID}}, []*bank.Transaction{created[0], created[2], created[4]}},
+		{"several accounts", bank.TransactionFilter{AccountIDs: []string{john.ID, alex.ID, john.ID}}, created},
+		{"unknown account", bank.TransactionFilter{AccountIDs: []string{uuid.New().String()}}, nil},
+		{"reference ignores case", bank.TransactionFilter{Reference: "march"}, []*bank.Transaction{created[0], created[2]}},
+		{"reference is literal", bank.TransactionFilter{Reference: "c.*"}, nil},
+		{"time range", bank.TransactionFilter{From: &created[1].Timestamp, To: &created[4].Timestamp}, []*bank.Transaction{created[1], created[2], created[3]}},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			transactions, summary := s.collectSearch(test.filter, bank.PageRequest{Limit: 2})
+			s.Equal(transactionIDs(derefTransactions(test.expected)), transactionIDs(transactions))
+			s.Equal(bank.SummarizeTransactions(derefTransactions(test.expected)), summary)
+		})
+	}
+}
+
+func (s *BankStoreSuite) TestSearchTransactionsSummary() {
+	s.seedSearch()
+
+	page, err := s.store.SearchTransactions(s.ctx, bank.TransactionFilter{}, bank.PageRequest{Limit: 1, SortBy: bank.TransactionSortAmount, Order: bank.SortDescending})
+	s.Require().NoError(err)
+	s.Require().Len(page.Transactions, 1)
+	s.Equal(20000.0, page.Transactions[0].Amount)
+	s.NotEmpty(page.NextCursor)
+
+	s.Equal(5, page.Summary.Count)
+	s.Equal(bank.TransactionTypeSummary{Count: 2, Sum: 20500}, page.Summary.ByType[bank.DepositTransactionType])
+	s.Equal(bank.TransactionTypeSummary{Count: 3, Sum: 27050}, page.Summary.ByType[bank.WithdrawalTransactionType])
+}
+
+func (s *BankStoreSuite) TestSearchTransactionsEmpty() {
+	page, err := s.store.SearchTransactions(s.ctx, bank.TransactionFilter{}, bank.PageRequest{})
+	s.Require().NoError(err)
+	s.NotNil(page.Transactions)
+	s.Empty(page.Transactions)
+	s.Empty(page.NextCursor)
+	s.Equal(bank.NewTransactionSummary(), page.Summary)
+}
+
+func (s *BankStoreSuite) TestSearchTransactionsInvalidFilter() {
+	tooMany := make([]string, bank.MaxFilterAccountIDs+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New().String()
+	}
+
+	tests := []struct {
+		name          string
+		filter        bank.TransactionFilter
+		page          bank.PageRequest
+		expectedError error
+	}{
+		{"too many accounts", bank.TransactionFilter{AccountIDs: tooMany}, bank.PageRequest{}, bank.ErrInvalidFilter},
+		{"empty account ID", bank.TransactionFilter{AccountIDs: []string{""}}, bank.PageRequest{}, bank.ErrInvalidFilter},
+		{"reference too long", bank.TransactionFilter{Reference: strings.Repeat("x", bank.MaxReferenceLength+1)}, bank.PageRequest{}, bank.ErrReferenceTooLong},
+		{"unknown type", bank.TransactionFilter{Type: "refund"}, bank.PageRequest{}, bank.ErrInvalidTransaction},
+		{"account sort", bank.TransactionFilter{}, bank.PageRequest{SortBy: bank.AccountSortOwner}, bank.ErrInvalidSortField},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			page, err := s.store.SearchTransactions(s.ctx, test.filter, test.page)
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(page)
+		})
+	}
+}
@@ -0,0 +1,404 @@
+// Package storeConformance holds a behavioural test suite that every restServer.BankStore
+// implementation must pass, so the HTTP layer can treat all backends the same way.
+//
+// Validation-only errors that stores never return on their own (ErrNegativeAmount) are covered by the
+// bank package tests instead.
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/restServer"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+const concurrentWorkers = 20
+
+// Factory returns a new, empty store. It is called once per test so tests never share state.
+type Factory func(t *testing.T) restServer.BankStore
+
+// Run executes the whole conformance suite against the stores built by newStore.
+func Run(t *testing.T, newStore Factory) {
+	suite.Run(t, &BankStoreSuite{newStore: newStore})
+}
+
+type BankStoreSuite struct {
+	suite.Suite
+	newStore Factory
+	store    restServer.BankStore
+	ctx      context.Context
+}
+
+func (s *BankStoreSuite) SetupTest() {
+	s.store = s.newStore(s.T())
+	s.ctx = context.Background()
+}
+
+func (s *BankStoreSuite) createAccount(owner string, balance float64) *bank.Account {
+	account, err := s.store.CreateAccount(s.ctx, owner, balance)
+	s.Require().NoError(err)
+	s.Require().NotNil(account)
+	return account
+}
+
+// listAccounts returns the first page of accounts with the default sort, large enough for every test.
+func (s *BankStoreSuite) listAccounts() []bank.Account {
+	page, err := s.store.ListAccounts(s.ctx, bank.AccountFilter{}, bank.PageRequest{Limit: bank.MaxPageLimit})
+	s.Require().NoError(err)
+	return page.Accounts
+}
+
+// transactionsOf returns the first page of the account transactions, oldest first.
+func (s *BankStoreSuite) transactionsOf(accountID string) ([]bank.Transaction, error) {
+	page, err := s.store.GetTransactionsByAccountID(s.ctx, accountID, bank.TransactionFilter{}, bank.PageRequest{Limit: bank.MaxPageLimit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Transactions, nil
+}
+
+func (s *BankStoreSuite) balanceOf(accountID string) float64 {
+	account, err := s.store.GetAccountByID(s.ctx, accountID)
+	s.Require().NoError(err)
+	return account.Balance
+}
+
+// Account operations.
+
+func (s *BankStoreSuite) TestCreateAccount() {
+	account, err := s.store.CreateAccount(s.ctx, "Alex Camara", 1000.0)
+	s.NoError(err)
+	s.NotEmpty(account.ID)
+	s.Equal("Alex Camara", account.Owner)
+	s.Equal(1000.0, account.Balance)
+
+	other := s.createAccount("Alex Camara", 1000.0)
+	s.NotEqual(account.ID, other.ID, "accounts with the same owner must get different IDs")
+}
+
+func (s *BankStoreSuite) TestCreateAccountWithZeroBalance() {
+	account := s.createAccount("John Doe", 0)
+	s.Equal(0.0, s.balanceOf(account.ID))
+}
+
+func (s *BankStoreSuite) TestCreateAccountRejectsInvalidInput() {
+	account, err := s.store.CreateAccount(s.ctx, "", 100.0)
+	s.ErrorIs(err, bank.ErrEmptyOwnerName)
+	s.Nil(account)
+
+	account, err = s.store.CreateAccount(s.ctx, "John Doe", -1.0)
+	s.ErrorIs(err, bank.ErrNegativeInitialBalance)
+	s.Nil(account)
+
+	s.Empty(s.listAccounts(), "rejected accounts must not be stored")
+}
+
+func (s *BankStoreSuite) TestGetAccountByID() {
+	account := s.createAccount("John Doe", 250.5)
+
+	retrieved, err := s.store.GetAccountByID(s.ctx, account.ID)
+	s.NoError(err)
+	s.Equal(account, retrieved)
+}
+
+func (s *BankStoreSuite) TestGetAccountByIDNotFound() {
+	account, err := s.store.GetAccountByID(s.ctx, uuid.New().String())
+	s.ErrorIs(err, bank.ErrAccountNotFound)
+	s.Nil(account)
+}
+
+func (s *BankStoreSuite) TestListAccountsEmpty() {
+	page, err := s.store.ListAccounts(s.ctx, bank.AccountFilter{}, bank.PageRequest{})
+	s.NoError(err)
+	s.NotNil(page.Accounts, "an empty store must list an empty slice, not nil")
+	s.Empty(page.Accounts)
+	s.Empty(page.NextCursor)
+}
+
+func (s *BankStoreSuite) TestListAccounts() {
+	account1 := s.createAccount("Alex Camara", 1000.0)
+	account2 := s.createAccount("Donald Trump", 500.0)
+
+	s.ElementsMatch([]bank.Account{*account1, *account2}, s.listAccounts())
+}
+
+// Transaction operations.
+
+func (s *BankStoreSuite) TestPerformDepositAndWithdrawal() {
+	account := s.createAccount("Alex Camara", 1000.0)
+
+	deposit, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 500.0, "")
+	s.Require().NoError(err)
+	s.NotEmpty(deposit.ID)
+	s.Equal(account.ID, deposit.AccountID)
+	s.Equal(bank.DepositTransactionType, deposit.Type)
+	s.Equal(500.0, deposit.Amount)
+	s.False(deposit.Timestamp.IsZero())
+
+	withdrawal, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 1500.0, "")
+	s.Require().NoError(err)
+	s.NotEqual(deposit.ID, withdrawal.ID)
+
+	s.Equal(0.0, s.balanceOf(account.ID), "withdrawing the whole balance must be allowed")
+}
+
+func (s *BankStoreSuite) TestPerformTransactionUnknownAccount() {
+	transaction, err := s.store.PerformTransaction(s.ctx, uuid.New().String(), bank.DepositTransactionType, 10.0, "")
+	s.ErrorIs(err, bank.ErrAccountNotFound)
+	s.Nil(transaction)
+}
+
+func (s *BankStoreSuite) TestPerformTransactionInsufficientFunds() {
+	account := s.createAccount("Alex Camara", 100.0)
+
+	transaction, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 100.01, "")
+	s.ErrorIs(err, bank.ErrInsufficientFunds)
+	s.Nil(transaction)
+
+	s.Equal(100.0, s.balanceOf(account.ID))
+	_, err = s.transactionsOf(account.ID)
+	s.ErrorIs(err, bank.ErrNoTransactionsForAccount, "failed transactions must not be recorded")
+}
+
+func (s *BankStoreSuite) TestPerformTransactionRejectsInvalidInput() {
+	account := s.createAccount("Alex Camara", 100.0)
+
+	tests := []struct {
+		name          string
+		txType        string
+		amount        float64
+		expectedError error
+	}{
+		{"invalid type", "invalid", 10.0, bank.ErrInvalidTransaction},
+		{"missing type", "", 10.0, bank.ErrTransactionTypeRequired},
+		{"zero amount", bank.DepositTransactionType, 0, bank.ErrZeroTransactionAmount},
+		{"negative amount", bank.WithdrawalTransactionType, -10.0, bank.ErrZeroTransactionAmount},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			transaction, err := s.store.PerformTransaction(s.ctx, account.ID, test.txType, test.amount, "")
+			s.ErrorIs(err, test.expectedError)
+			s.Nil(transaction)
+		})
+	}
+
+	s.Equal(100.0, s.balanceOf(account.ID))
+}
+
+func (s *BankStoreSuite) TestGetTransactionsByAccountID() {
+	account := s.createAccount("Alex Camara", 1000.0)
+	other := s.createAccount("John Doe", 1000.0)
+
+	first, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 100.0, "")
+	s.Require().NoError(err)
+	second, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 50.0, "")
+	s.Require().NoError(err)
+	_, err = s.store.PerformTransaction(s.ctx, other.ID, bank.DepositTransactionType, 1.0, "")
+	s.Require().NoError(err)
+
+	transactions, err := s.transactionsOf(account.ID)
+	s.Require().NoError(err)
+	s.Require().Len(transactions, 2)
+	s.Equal(first.ID, transactions[0].ID, "transactions must be returned oldest first")
+	s.Equal(second.ID, transactions[1].ID)
+	for _, transaction := range transactions {
+		s.Equal(account.ID, transaction.AccountID)
+	}
+	s.True(first.Timestamp.Equal(transactions[0].Timestamp))
+}
+
+func (s *BankStoreSuite) TestGetTransactionByID() {
+	account := s.createAccount("Alex Camara", 1000.0)
+	other := s.createAccount("John Doe", 1000.0)
+
+	_, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 100.0, "")
+	s.Require().NoError(err)
+	withdrawal, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 50.0, "")
+	s.Require().NoError(err)
+	_, err = s.store.PerformTransaction(s.ctx, other.ID, bank.DepositTransactionType, 1.0, "")
+	s.Require().NoError(err)
+
+	retrieved, err := s.store.GetTransactionByID(s.ctx, withdrawal.ID)
+	s.Require().NoError(err)
+	s.Equal(withdrawal.ID, retrieved.ID)
+	s.Equal(account.ID, retrieved.AccountID)
+	s.Equal(bank.WithdrawalTransactionType, retrieved.Type)
+	s.Equal(50.0, retrieved.Amount)
+	s.True(withdrawal.Timestamp.Equal(retrieved.Timestamp))
+}
+
+func (s *BankStoreSuite) TestGetTransactionByIDNotFound() {
+	transaction, err := s.store.GetTransactionByID(s.ctx, uuid.New().String())
+	s.ErrorIs(err, bank.ErrTransactionNotFound)
+	s.Nil(transaction)
+}
+
+func (s *BankStoreSuite) TestGetTransactionsByAccountIDWithoutTransactions() {
+	account := s.createAccount("Alex Camara", 1000.0)
+
+	transactions, err := s.transactionsOf(account.ID)
+	s.ErrorIs(err, bank.ErrNoTransactionsForAccount)
+	s.Empty(transactions)
+
+	transactions, err = s.transactionsOf(uuid.New().String())
+	s.ErrorIs(err, bank.ErrNoTransactionsForAccount)
+	s.Empty(transactions)
+}
+
+// Transfer operations.
+
+func (s *BankStoreSuite) TestTransferFunds() {
+	from := s.createAccount("John Doe", 1000.0)
+	to := s.createAccount("Jane Doe", 1500.0)
+
+	s.NoError(s.store.TransferFunds(s.ctx, from.ID, to.ID, 1000.0))
+
+	s.Equal(0.0, s.balanceOf(from.ID))
+	s.Equal(2500.0, s.balanceOf(to.ID))
+}
+
+func (s *BankStoreSuite) TestTransferFundsErrors() {
+	from := s.createAccount("John Doe", 1000.0)
+	to := s.createAccount("Jane Doe", 1500.0)
+	unknownID := uuid.New().String()
+
+	tests := []struct {
+		name          string
+		fromID        string
+		toID          string
+		amount        float64
+		expectedError error
+	}{
+		{"source not found", unknownID, to.ID, 10.0, bank.ErrTransferSourceNotFound},
+		{"destination not found", from.ID, unknownID, 10.0, bank.ErrTransferDestinationNotFound},
+		{"insufficient funds", from.ID, to.ID, 1000.01, bank.ErrInsufficientFunds},
+		{"same account", from.ID, from.ID, 10.0, bank.ErrSameSourceDestination},
+		{"zero amount", from.ID, to.ID, 0, bank.ErrZeroTransactionAmount},
+		{"negative amount", from.ID, to.ID, -10.0, bank.ErrZeroTransactionAmount},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.ErrorIs(s.store.TransferFunds(s.ctx, test.fromID, test.toID, test.amount), test.expectedError)
+		})
+	}
+
+	s.Equal(1000.0, s.balanceOf(from.ID), "failed transfers must not move money")
+	s.Equal(1500.0, s.balanceOf(to.ID))
+}
+
+// Context handling.
+
+func (s *BankStoreSuite) TestCanceledContextIsRejected() {
+	from := s.createAccount("John Doe", 1000.0)
+	to := s.createAccount("Jane Doe", 1000.0)
+	existing, err := s.store.PerformTransaction(s.ctx, to.ID, bank.DepositTransactionType, 0.5, "")
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	account, err := s.store.CreateAccount(ctx, "Alex Camara", 10.0)
+	s.ErrorIs(err, context.Canceled)
+	s.Nil(account)
+
+	_, err = s.store.GetAccountByID(ctx, from.ID)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.store.ListAccounts(ctx, bank.AccountFilter{}, bank.PageRequest{})
+	s.ErrorIs(err, context.Canceled)
+
+	transaction, err := s.store.PerformTransaction(ctx, from.ID, bank.DepositTransactionType, 10.0, "")
+	s.ErrorIs(err, context.Canceled)
+	s.Nil(transaction)
+
+	_, err = s.store.GetTransactionByID(ctx, existing.ID)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.store.GetTransactionsByAccountID(ctx, from.ID, bank.TransactionFilter{}, bank.PageRequest{})
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.store.SearchTransactions(ctx, bank.TransactionFilter{}, bank.PageRequest{})
+	s.ErrorIs(err, context.Canceled)
+
+	s.ErrorIs(s.store.TransferFunds(ctx, from.ID, to.ID, 10.0), context.Canceled)
+
+	s.Len(s.listAccounts(), 2, "canceled calls must not create accounts")
+	s.Equal(1000.0, s.balanceOf(from.ID), "canceled calls must not move money")
+	s.Equal(1000.5, s.balanceOf(to.ID))
+}
+
+// Concurrency invariants.
+
+func (s *BankStoreSuite) TestConcurrentDepositsAreNotLost() {
+	account := s.createAccount("Alex Camara", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.store.PerformTransaction(s.ctx, account.ID, bank.DepositTransactionType, 10.0, "")
+			s.NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	s.Equal(concurrentWorkers*10.0, s.balanceOf(account.ID))
+	transactions, err := s.transactionsOf(account.ID)
+	s.NoError(err)
+	s.Len(transactions, concurrentWorkers)
+}
+
+func (s *BankStoreSuite) TestConcurrentWithdrawalsNeverOverdraw() {
+	account := s.createAccount("Alex Camara", 100.0)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.store.PerformTransaction(s.ctx, account.ID, bank.WithdrawalTransactionType, 10.0, "")
+			if err != nil {
+				s.ErrorIs(err, bank.ErrInsufficientFunds)
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	s.Equal(10, succeeded)
+	s.Equal(0.0, s.balanceOf(account.ID))
+}
+
+func (s *BankStoreSuite) TestConcurrentTransfersPreserveTotalBalance() {
+	account1 := s.createAccount("John Doe", 1000.0)
+	account2 := s.createAccount("Jane Doe", 1000.0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.NoError(s.store.TransferFunds(s.ctx, account1.ID, account2.ID, 10.0))
+		}()
+		go func() {
+			defer wg.Done()
+			s.NoError(s.store.TransferFunds(s.ctx, account2.ID, account1.ID, 5.0))
+		}()
+	}
+	wg.Wait()
+
+	s.Equal(1000.0-concurrentWorkers*5.0, s.balanceOf(account1.ID))
+	s.Equal(1000.0+concurrentWorkers*5.0, s.balanceOf(account2.ID))
+}
@@ -0,0 +1,228 @@
+package storeConformance
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/restServer"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *BankStoreSuite) createWebhook(createdAt time.Time, events ...string) *bank.Webhook {
+	webhook, err := bank.NewWebhook("https://example.com/hooks", events, createdAt)
+	s.Require().NoError(err)
+	stored, err := s.store.CreateWebhook(s.ctx, webhook)
+	s.Require().NoError(err)
+	return stored
+}
+
+// enqueueDelivery queues a transfer.completed event for webhookID, due at createdAt.
+func (s *BankStoreSuite) enqueueDelivery(webhookID string, createdAt time.Time) bank.WebhookDelivery {
+	event := bank.WebhookEvent{ID: uuid.NewString(), Type: bank.EventTransferCompleted, CreatedAt: createdAt.UTC().Truncate(time.Millisecond)}
+	delivery := bank.NewWebhookDelivery(webhookID, event, []byte(`{"type":"transfer.completed"}`))
+	s.Require().NoError(s.store.EnqueueWebhookDeliveries(s.ctx, []bank.WebhookDelivery{delivery}))
+	return delivery
+}
+
+func (s *BankStoreSuite) TestCreateAndGetWebhook() {
+	webhook := s.createWebhook(time.Now(), bank.EventAccountCreated, bank.EventTransferCompleted)
+
+	retrieved, err := s.store.GetWebhook(s.ctx, webhook.ID)
+	s.Require().NoError(err)
+	s.Equal("https://example.com/hooks", retrieved.URL)
+	s.Equal([]string{bank.EventAccountCreated, bank.EventTransferCompleted}, retrieved.Events)
+	s.Equal(webhook.Secret, retrieved.Secret, "the secret is needed to sign deliveries")
+	s.True(webhook.CreatedAt.Equal(retrieved.CreatedAt))
+
+	_, err = s.store.GetWebhook(s.ctx, uuid.NewString())
+	s.ErrorIs(err, bank.ErrWebhookNotFound)
+}
+
+func (s *BankStoreSuite) TestCreateWebhookRejectsInvalidInput() {
+	webhook, err := s.store.CreateWebhook(s.ctx, bank.Webhook{ID: uuid.NewString(), URL: "ftp://example.com", Events: []string{bank.EventAll}})
+	s.ErrorIs(err, bank.ErrInvalidWebhook)
+	s.Nil(webhook)
+
+	webhooks, err := s.store.ListWebhooks(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(webhooks, "rejected webhooks must not be stored")
+}
+
+func (s *BankStoreSuite) TestListWebhooks() {
+	webhooks, err := s.store.ListWebhooks(s.ctx)
+	s.Require().NoError(err)
+	s.NotNil(webhooks, "an empty store must list an empty slice, not nil")
+	s.Empty(webhooks)
+
+	createdAt := time.Now()
+	second := s.createWebhook(createdAt.Add(time.Second), bank.EventAll)
+	first := s.createWebhook(createdAt, bank.EventAll)
+
+	webhooks, err = s.store.ListWebhooks(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(webhooks, 2)
+	s.Equal(first.ID, webhooks[0].ID, "webhooks must be listed oldest first")
+	s.Equal(second.ID, webhooks[1].ID)
+}
+
+func (s *BankStoreSuite) TestDeleteWebhookDeletesItsDeliveries() {
+	webhook := s.createWebhook(time.Now(), bank.EventAll)
+	other := s.createWebhook(time.Now(), bank.EventAll)
+	s.enqueueDelivery(webhook.ID, time.Now())
+	kept := s.enqueueDelivery(other.ID, time.Now())
+
+	s.Require().NoError(s.store.DeleteWebhook(s.ctx, webhook.ID))
+	_, err := s.store.GetWebhook(s.ctx, webhook.ID)
+	s.ErrorIs(err, bank.ErrWebhookNotFound)
+	s.ErrorIs(s.store.DeleteWebhook(s.ctx, webhook.ID), bank.ErrWebhookNotFound)
+
+	claimed, err := s.store.ClaimWebhookDeliveries(s.ctx, time.Now(), 10, time.Minute)
+	s.Require().NoError(err)
+	s.Require().Len(claimed, 1, "the deliveries of a deleted webhook must not be delivered")
+	s.Equal(kept.ID, claimed[0].ID)
+}
+
+func (s *BankStoreSuite) TestClaimWebhookDeliveries() {
+	webhook := s.createWebhook(time.Now(), bank.EventAll)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	late := s.enqueueDelivery(webhook.ID, now.Add(-time.Minute))
+	due := s.enqueueDelivery(webhook.ID, now)
+	s.enqueueDelivery(webhook.ID, now.Add(time.Minute))
+
+	claimed, err := s.store.ClaimWebhookDeliveries(s.ctx, now, 10, time.Minute)
+	s.Require().NoError(err)
+	s.Require().Len(claimed, 2, "only deliveries due at now must be claimed")
+	s.Equal(late.ID, claimed[0].ID, "the longest overdue delivery must be claimed first")
+	s.Equal(due.ID, claimed[1].ID)
+	s.True(now.Add(time.Minute).Equal(claimed[0].NextAttemptAt), "claimed deliveries must be postponed by the lease")
+	s.JSONEq(`{"type":"transfer.completed"}`, string(claimed[0].Payload))
+
+	claimed, err = s.store.ClaimWebhookDeliveries(s.ctx, now, 10, time.Minute)
+	s.Require().NoError(err)
+	s.Empty(claimed, "leased deliveries must not be claimed again")
+
+	claimed, err = s.store.ClaimWebhookDeliveries(s.ctx, now.Add(time.Minute), 1, time.Minute)
+	s.Require().NoError(err)
+	s.Len(claimed, 1, "at most limit deliveries must be claimed")
+}
+
+func (s *BankStoreSuite) TestUpdateWebhookDelivery() {
+	webhook := s.createWebhook(time.Now(), bank.EventAll)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	delivery := s.enqueueDelivery(webhook.ID, now)
+
+	delivery.Status = bank.WebhookDeliverySucceeded
+	delivery.Attempts = append(delivery.Attempts,
+		bank.WebhookAttempt{At: now, Error: "connection refused", DurationMS: 3},
+		bank.WebhookAttempt{At: now.Add(time.Second), StatusCode: 204, DurationMS: 12})
+	completedAt := now.Add(time.Second)
+	delivery.CompletedAt = &completedAt
+	s.Require().NoError(s.store.UpdateWebhookDelivery(s.ctx, delivery))
+
+	deliveries, err := s.store.ListWebhookDeliveries(s.ctx, webhook.ID, bank.WebhookDeliveryFilter{})
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	updated := deliveries[0]
+	s.Equal(bank.WebhookDeliverySucceeded, updated.Status)
+	s.Require().Len(updated.Attempts, 2)
+	s.Equal("connection refused", updated.Attempts[0].Error)
+	s.Equal(204, updated.Attempts[1].StatusCode)
+	s.Equal(int64(12), updated.Attempts[1].DurationMS)
+	s.True(updated.Attempts[1].At.Equal(now.Add(time.Second)))
+	s.Require().NotNil(updated.CompletedAt)
+	s.True(completedAt.Equal(*updated.CompletedAt))
+
+	claimed, err := s.store.ClaimWebhookDeliveries(s.ctx, now.Add(time.Hour), 10, time.Minute)
+	s.Require().NoError(err)
+	s.Empty(claimed, "completed deliveries must not be claimed")
+
+	s.Require().NoError(s.store.DeleteWebhook(s.ctx, webhook.ID))
+	s.NoError(s.store.UpdateWebhookDelivery(s.ctx, delivery), "updating a deleted delivery is not an error")
+}
+
+func (s *BankStoreSuite) TestListWebhookDeliveries() {
+	webhook := s.createWebhook(time.Now(), bank.EventAll)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	older := s.enqueueDelivery(webhook.ID, now.Add(-time.Minute))
+	newer := s.enqueueDelivery(webhook.ID, now)
+	dead := s.enqueueDelivery(webhook.ID, now.Add(-time.Hour))
+	dead.Status = bank.WebhookDeliveryDead
+	s.Require().NoError(s.store.UpdateWebhookDelivery(s.ctx, dead))
+	s.enqueueDelivery(s.createWebhook(time.Now(), bank.EventAll).ID, now)
+
+	deliveries, err := s.store.ListWebhookDeliveries(s.ctx, webhook.ID, bank.WebhookDeliveryFilter{})
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 3, "only the deliveries of the webhook must be listed")
+	s.Equal([]string{newer.ID, older.ID, dead.ID}, []string{deliveries[0].ID, deliveries[1].ID, deliveries[2].ID}, "deliveries must be listed newest first")
+
+	deliveries, err = s.store.ListWebhookDeliveries(s.ctx, webhook.ID, bank.WebhookDeliveryFilter{Status: bank.WebhookDeliveryDead})
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	s.Equal(dead.ID, deliveries[0].ID)
+
+	deliveries, err = s.store.ListWebhookDeliveries(s.ctx, webhook.ID, bank.WebhookDeliveryFilter{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	s.Equal(newer.ID, deliveries[0].ID)
+
+	_, err = s.store.ListWebhookDeliveries(s.ctx, webhook.ID, bank.WebhookDeliveryFilter{Status: "failed"})
+	s.ErrorIs(err, bank.ErrInvalidFilter)
+	_, err = s.store.ListWebhookDeliveries(s.ctx, uuid.NewString(), bank.WebhookDeliveryFilter{})
+	s.ErrorIs(err, bank.ErrWebhookNotFound)
+}
+
+// listedOutbox hands out the webhooks it holds as the subscriptions.
+type listedOutbox []bank.Webhook
+
+func (o listedOutbox) Subscriptions(context.Context) ([]bank.Webhook, error) {
+	return o, nil
+}
+
+func (s *BankStoreSuite) TestOutboxQueuesDeliveriesWithTheirWrites() {
+	outboxStore, ok := s.store.(restServer.OutboxStore)
+	if !ok {
+		s.T().Skip("the store has no outbox, its events are published after the writes")
+	}
+	transfers := s.createWebhook(time.Now(), bank.EventTransferCompleted)
+	everything := s.createWebhook(time.Now(), bank.EventAll)
+	deleted := s.createWebhook(time.Now(), bank.EventAll)
+	s.Require().NoError(s.store.DeleteWebhook(s.ctx, deleted.ID))
+	outboxStore.SetOutbox(listedOutbox{*transfers, *everything, *deleted})
+
+	alex := s.createAccount("Alex Camara", 100)
+	john := s.createAccount("John Doe", 0)
+	s.Require().NoError(s.store.TransferFunds(s.ctx, alex.ID, john.ID, 25))
+	_, err := s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: john.ID, Amount: 5},
+		{Type: bank.BatchTransfer, FromAccountID: john.ID, ToAccountID: alex.ID, Amount: 10},
+	}})
+	s.Require().NoError(err)
+
+	// Failed writes queue nothing, atomic batches undone by a later operation included.
+	s.ErrorIs(s.store.TransferFunds(s.ctx, alex.ID, john.ID, 1000), bank.ErrInsufficientFunds)
+	_, err = s.store.ExecuteBatch(s.ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: john.ID, Amount: 5},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 1000},
+	}})
+	s.Require().Error(err)
+
+	eventTypes := func(webhookID string) []string {
+		deliveries, err := s.store.ListWebhookDeliveries(s.ctx, webhookID, bank.WebhookDeliveryFilter{})
+		s.Require().NoError(err)
+		types := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			types = append(types, delivery.EventType)
+		}
+		return types
+	}
+	s.ElementsMatch([]string{bank.EventTransferCompleted, bank.EventTransferCompleted}, eventTypes(transfers.ID))
+	s.ElementsMatch([]string{
+		bank.EventAccountCreated, bank.EventAccountCreated,
+		bank.EventTransferCompleted, bank.EventTransactionCreated, bank.EventTransferCompleted,
+	}, eventTypes(everything.ID))
+
+	claimed, err := s.store.ClaimWebhookDeliveries(s.ctx, time.Now().Add(time.Hour), 100, time.Minute)
+	s.Require().NoError(err)
+	s.Len(claimed, 7, "webhooks deleted since they were listed must get no deliveries")
+}
@@ -0,0 +1,48 @@
+package bank
+
+// TransactionTypeSummary aggregates the transactions of one type.
+type TransactionTypeSummary struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// TransactionSummary aggregates every transaction matching a search, not only the returned page.
+// ByType always has an entry for each transaction type, even when its count is zero.
+type TransactionSummary struct {
+	Count  int                               `json:"count"`
+	ByType map[string]TransactionTypeSummary `json:"by_type"`
+}
+
+// TransactionSearchPage is one page of a cross-account search together with the summary of all matches.
+type TransactionSearchPage struct {
+	Transactions []Transaction
+	NextCursor   string
+	Summary      TransactionSummary
+}
+
+func NewTransactionSummary() TransactionSummary {
+	return TransactionSummary{
+		ByType: map[string]TransactionTypeSummary{
+			DepositTransactionType:    {},
+			WithdrawalTransactionType: {},
+		},
+	}
+}
+
+// Add accounts for count transactions of txType adding up to sum.
+func (s *TransactionSummary) Add(txType string, count int, sum float64) {
+	typeSummary := s.ByType[txType]
+	typeSummary.Count += count
+	typeSummary.Sum += sum
+	s.ByType[txType] = typeSummary
+	s.Count += count
+}
+
+// SummarizeTransactions builds the summary of transactions.
+func SummarizeTransactions(transactions []Transaction) TransactionSummary {
+	summary := NewTransactionSummary()
+	for _, transaction := range transactions {
+		summary.Add(transaction.Type, 1, transaction.Amount)
+	}
+	return summary
+}
@@ -1,9 +1,20 @@
 package bank
 
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// MaxReferenceLength bounds the free-text reference attached to a transaction.
+const MaxReferenceLength = 140
+
 func ValidateAccountInput(owner string, initialBalance float64) error {
 	if owner == "" {
 		return ErrEmptyOwnerName
 	}
+	if !isFinite(initialBalance) {
+		return ErrAmountNotFinite
+	}
 	if initialBalance < 0 {
 		return ErrNegativeInitialBalance
 	}
@@ -11,16 +22,32 @@ func ValidateAccountInput(owner string, initialBalance float64) error {
 }
 
 func ValidateTransaction(txType string, amount float64) error {
+	if txType == "" {
+		return ErrTransactionTypeRequired
+	}
 	if txType != DepositTransactionType && txType != WithdrawalTransactionType {
 		return InvalidTransactionError(txType)
 	}
+	if !isFinite(amount) {
+		return ErrAmountNotFinite
+	}
 	if amount <= 0 {
 		return ErrZeroTransactionAmount
 	}
 	return nil
 }
 
+func ValidateReference(reference string) error {
+	if length := utf8.RuneCountInString(reference); length > MaxReferenceLength {
+		return ReferenceTooLongError(length)
+	}
+	return nil
+}
+
 func ValidateTransfer(fromAccountID, toAccountID string, amount float64) error {
+	if !isFinite(amount) {
+		return ErrAmountNotFinite
+	}
 	if amount <= 0 {
 		return ErrZeroTransactionAmount
 	}
@@ -29,3 +56,8 @@ func ValidateTransfer(fromAccountID, toAccountID string, amount float64) error {
 	}
 	return nil
 }
+
+// isFinite rejects NaN and infinite amounts, which every comparison with a limit lets through.
+func isFinite(amount float64) bool {
+	return !math.IsNaN(amount) && !math.IsInf(amount, 0)
+}
@@ -2,6 +2,8 @@ package bank
 
 import (
 	"errors"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +18,7 @@ func TestValidateAccountInput(t *testing.T) {
 		{"John Doe", 1000.0, nil},
 		{"", 1000.0, ErrEmptyOwnerName},
 		{"John Doe", -500.0, ErrNegativeInitialBalance},
+		{"John Doe", math.Inf(1), ErrAmountNotFinite},
 	}
 
 	for _, test := range tests {
@@ -30,6 +33,12 @@ func TestValidateAccountInput(t *testing.T) {
 	}
 }
 
+func TestValidateReference(t *testing.T) {
+	assert.NoError(t, ValidateReference(""))
+	assert.NoError(t, ValidateReference(strings.Repeat("é", MaxReferenceLength)), "length is counted in characters")
+	assert.ErrorIs(t, ValidateReference(strings.Repeat("a", MaxReferenceLength+1)), ErrReferenceTooLong)
+}
+
 func TestValidateTransaction(t *testing.T) {
 	tests := []struct {
 		txType        string
@@ -39,8 +48,11 @@ func TestValidateTransaction(t *testing.T) {
 		{DepositTransactionType, 500.0, nil},
 		{WithdrawalTransactionType, 500.0, nil},
 		{"invalid", 500.0, ErrInvalidTransaction},
+		{"", 500.0, ErrTransactionTypeRequired},
 		{DepositTransactionType, 0.0, ErrZeroTransactionAmount},
 		{WithdrawalTransactionType, 0.0, ErrZeroTransactionAmount},
+		{DepositTransactionType, math.NaN(), ErrAmountNotFinite},
+		{WithdrawalTransactionType, math.Inf(-1), ErrAmountNotFinite},
 	}
 
 	for _, test := range tests {
@@ -65,6 +77,7 @@ func TestValidateTransfer(t *testing.T) {
 		{"1", "2", 500.0, nil},
 		{"1", "1", 500.0, ErrSameSourceDestination},
 		{"1", "2", -500.0, ErrZeroTransactionAmount},
+		{"1", "2", math.NaN(), ErrAmountNotFinite},
 	}
 
 	for _, test := range tests {
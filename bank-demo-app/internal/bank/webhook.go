@@ -0,0 +1,262 @@
+package bank
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook events. EventAll subscribes a webhook to every event, those added later included.
+const (
+	EventAccountCreated     = "account.created"
+	EventTransactionCreated = "transaction.created"
+	EventTransferCompleted  = "transfer.completed"
+	EventAll                = "*"
+)
+
+var WebhookEvents = []string{
+	EventAccountCreated,
+	EventTransactionCreated,
+	EventTransferCompleted,
+}
+
+// Webhook delivery statuses. Pending deliveries wait in the queue for their next attempt, dead
+// ones failed every attempt and are only kept for the delivery log.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+var WebhookDeliveryStatuses = []string{
+	WebhookDeliveryPending,
+	WebhookDeliverySucceeded,
+	WebhookDeliveryDead,
+}
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 32
+)
+
+// Webhook subscribes URL to Events. Deliveries are signed with Secret, which unlike API key
+// tokens is stored in clear since the server needs it to sign.
+type Webhook struct {
+	ID        string    `json:"id" bson:"_id"`
+	URL       string    `json:"url" bson:"url"`
+	Events    []string  `json:"events" bson:"events"`
+	Secret    string    `json:"-" bson:"secret"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Subscribes reports whether the webhook receives events of eventType.
+func (w *Webhook) Subscribes(eventType string) bool {
+	return slices.Contains(w.Events, eventType) || slices.Contains(w.Events, EventAll)
+}
+
+// NewWebhook builds a webhook with a generated signing secret. The secret must be handed to the
+// receiver, which verifies the signature of every delivery with it.
+func NewWebhook(rawURL string, events []string, createdAt time.Time) (Webhook, error) {
+	if err := ValidateWebhookInput(rawURL, events); err != nil {
+		return Webhook{}, err
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return Webhook{
+		ID:        uuid.New().String(),
+		URL:       rawURL,
+		Events:    slices.Clone(events),
+		Secret:    webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		CreatedAt: createdAt.UTC(),
+	}, nil
+}
+
+// CompareWebhooks orders webhooks by creation time, then ID, the order in which stores list them.
+func CompareWebhooks(a, b Webhook) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func ValidateWebhookInput(rawURL string, events []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return InvalidWebhookError("url must be an absolute http or https URL")
+	}
+	if parsed.User != nil {
+		return InvalidWebhookError("url must not carry credentials")
+	}
+	if len(events) == 0 {
+		return InvalidWebhookError("at least one event is required")
+	}
+	for _, event := range events {
+		if event != EventAll && !slices.Contains(WebhookEvents, event) {
+			return InvalidWebhookError(fmt.Sprintf("unknown event %q", event))
+		}
+	}
+	return nil
+}
+
+// WebhookEvent is the body of a delivery. Its ID stays the same across the retries of a
+// delivery, so receivers can ignore events they already processed.
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Event is a change made by a store write that webhooks subscribe to, before it is given an ID
+// and encoded as a WebhookEvent.
+type Event struct {
+	Type string
+	Data any
+}
+
+// TransferCompleted is the data of a transfer.completed event. Transfers don't record
+// transactions, so this is all there is to tell about them.
+type TransferCompleted struct {
+	FromAccountID string  `json:"from_account_id"`
+	ToAccountID   string  `json:"to_account_id"`
+	Amount        float64 `json:"amount"`
+}
+
+func AccountCreatedEvent(account *Account) Event {
+	return Event{Type: EventAccountCreated, Data: account}
+}
+
+func TransactionCreatedEvent(transaction *Transaction) Event {
+	return Event{Type: EventTransactionCreated, Data: transaction}
+}
+
+func TransferCompletedEvent(fromAccountID, toAccountID string, amount float64) Event {
+	return Event{Type: EventTransferCompleted, Data: TransferCompleted{FromAccountID: fromAccountID, ToAccountID: toAccountID, Amount: amount}}
+}
+
+// ClosureEvents are the transactions paying out the balance of a closed account, none when it
+// was empty.
+func ClosureEvents(closure *AccountClosure) []Event {
+	if closure.Withdrawal == nil {
+		return nil
+	}
+	return []Event{TransactionCreatedEvent(closure.Withdrawal), TransactionCreatedEvent(closure.Deposit)}
+}
+
+// BatchEvents are the events of the operations of batch that succeeded, in the batch order.
+func BatchEvents(batch Batch, result *BatchResult) []Event {
+	var events []Event
+	for _, operationResult := range result.Results {
+		if operationResult.Err != nil {
+			continue
+		}
+		operation := batch.Operations[operationResult.Index]
+		if operation.Type == BatchTransfer {
+			events = append(events, TransferCompletedEvent(operation.FromAccountID, operation.ToAccountID, operation.Amount))
+		} else {
+			events = append(events, TransactionCreatedEvent(operationResult.Transaction))
+		}
+	}
+	return events
+}
+
+// Outbox hands stores the webhooks events are delivered to, so that they queue the deliveries of
+// a write in the same transaction as the write itself: neither is committed without the other.
+type Outbox interface {
+	Subscriptions(ctx context.Context) ([]Webhook, error)
+}
+
+// NewWebhookDeliveries builds one delivery of each event for every webhook subscribed to it,
+// created at now.
+func NewWebhookDeliveries(webhooks []Webhook, events []Event, now time.Time) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	for _, event := range events {
+		webhookEvent := WebhookEvent{ID: uuid.New().String(), Type: event.Type, CreatedAt: now.UTC(), Data: event.Data}
+		var payload []byte
+		for _, webhook := range webhooks {
+			if !webhook.Subscribes(event.Type) {
+				continue
+			}
+			if payload == nil {
+				var err error
+				if payload, err = json.Marshal(webhookEvent); err != nil {
+					return nil, fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+				}
+			}
+			deliveries = append(deliveries, NewWebhookDelivery(webhook.ID, webhookEvent, payload))
+		}
+	}
+	return deliveries, nil
+}
+
+// WebhookDelivery is one event queued for one webhook, along with the log of its attempts.
+// Payload is the exact body sent, so every retry carries the same bytes.
+type WebhookDelivery struct {
+	ID            string           `json:"id" bson:"_id"`
+	WebhookID     string           `json:"webhook_id" bson:"webhook_id"`
+	EventID       string           `json:"event_id" bson:"event_id"`
+	EventType     string           `json:"event_type" bson:"event_type"`
+	Payload       json.RawMessage  `json:"payload" bson:"payload"`
+	Status        string           `json:"status" bson:"status"`
+	Attempts      []WebhookAttempt `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time        `json:"created_at" bson:"created_at"`
+	CompletedAt   *time.Time       `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// WebhookAttempt records one request of a delivery: the status the receiver answered with, or
+// the error when it couldn't be reached.
+type WebhookAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// NewWebhookDelivery queues the encoded event for webhookID, due right away.
+func NewWebhookDelivery(webhookID string, event WebhookEvent, payload []byte) WebhookDelivery {
+	return WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       payload,
+		Status:        WebhookDeliveryPending,
+		Attempts:      []WebhookAttempt{},
+		NextAttemptAt: event.CreatedAt,
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+// WebhookDeliveryFilter narrows the delivery log of a webhook to one status. Deliveries are
+// listed newest first, Limit at most.
+type WebhookDeliveryFilter struct {
+	Status string
+	Limit  int
+}
+
+// ValidateWebhookDeliveryFilter checks the status and applies the default limit.
+func ValidateWebhookDeliveryFilter(filter *WebhookDeliveryFilter) error {
+	if filter.Status != "" && !slices.Contains(WebhookDeliveryStatuses, filter.Status) {
+		return InvalidFilterError(fmt.Sprintf("unknown delivery status %q", filter.Status))
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxPageLimit {
+		return InvalidPageLimitError(filter.Limit)
+	}
+	return nil
+}
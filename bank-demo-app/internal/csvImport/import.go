@@ -0,0 +1,121 @@
+package csvImport
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"errors"
+	"io"
+)
+
+// ExternalReferenceKey is the metadata key that keeps the legacy ID of an imported account.
+const ExternalReferenceKey = "external_reference"
+
+// Store is the part of restServer.BankStore an import needs.
+type Store interface {
+	CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error)
+	UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error)
+	ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error)
+}
+
+// ImportedAccount links the account created for the row at Line to its legacy ID.
+type ImportedAccount struct {
+	Line              int
+	ExternalReference string
+	Account           *bank.Account
+}
+
+type Result struct {
+	DryRun       bool
+	Accounts     int
+	Transactions int
+	Imported     []ImportedAccount
+	Errors       []*RowError
+}
+
+// Committed reports whether every account and transaction of the file was imported.
+func (r *Result) Committed() bool {
+	return !r.DryRun && len(r.Errors) == 0
+}
+
+// Import parses the file and, unless dryRun is set or a row is invalid, creates its accounts in
+// file order. Each account is created with its initial balance and external reference, then its
+// transactions are applied as one atomic batch.
+//
+// Rows are fully validated before anything is written, so a file with errors imports nothing. A
+// store failure while importing stops at the failing row: the accounts created up to it, its own
+// included, are kept and listed in Result.Imported, and the failure is reported in Result.Errors.
+// Nothing matches rows against accounts imported before, so importing the same file again creates
+// its accounts again: to resume a failed import, drop the rows listed in Result.Imported first.
+func Import(ctx context.Context, store Store, r io.Reader, dryRun bool) (*Result, error) {
+	plan, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		DryRun:       dryRun,
+		Accounts:     len(plan.Accounts),
+		Transactions: plan.Transactions,
+		Errors:       plan.Errors,
+	}
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	result.Imported = make([]ImportedAccount, 0, len(plan.Accounts))
+	for _, account := range plan.Accounts {
+		created, err := importAccount(ctx, store, account)
+		if created != nil {
+			result.Imported = append(result.Imported, ImportedAccount{Line: account.Line, ExternalReference: account.ExternalReference, Account: created})
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// importAccount returns the created account, if any, and the row that failed.
+func importAccount(ctx context.Context, store Store, account Account) (*bank.Account, *RowError) {
+	created, err := store.CreateAccount(ctx, account.Owner, account.InitialBalance)
+	if err != nil {
+		return nil, &RowError{Line: account.Line, Err: err}
+	}
+
+	externalReference := account.ExternalReference
+	referenced, err := store.UpdateAccount(ctx, created.ID, bank.AccountUpdate{
+		Metadata: map[string]*string{ExternalReferenceKey: &externalReference},
+	})
+	if err != nil {
+		// The account exists, without its external reference.
+		return created, &RowError{Line: account.Line, Err: err}
+	}
+	created = referenced
+
+	if len(account.Transactions) == 0 {
+		return created, nil
+	}
+
+	operations := make([]bank.BatchOperation, 0, len(account.Transactions))
+	for _, transaction := range account.Transactions {
+		operations = append(operations, bank.BatchOperation{
+			Type:      transaction.Type,
+			AccountID: created.ID,
+			Amount:    transaction.Amount,
+			Reference: transaction.Reference,
+		})
+	}
+	if _, err := store.ExecuteBatch(ctx, bank.Batch{Atomic: true, Operations: operations}); err != nil {
+		line := account.Line
+		var operationErr *bank.BatchOperationError
+		if errors.As(err, &operationErr) {
+			line = account.Transactions[operationErr.Index].Line
+			err = operationErr.Err
+		}
+		return created, &RowError{Line: line, Err: err}
+	}
+
+	return created, nil
+}
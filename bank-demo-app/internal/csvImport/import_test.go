@@ -0,0 +1,203 @@
+package csvImport
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validFile = `external_reference,owner,initial_balance,transaction_type,amount,reference
+L-1,Alex Camara,100.50,,,
+L-2,John Doe,,,,
+L-1,,,deposit,50,Legacy interest
+L-1,,,withdrawal,150.5,Closing fee
+L-2,,,deposit,10,
+`
+
+func TestParse(t *testing.T) {
+	plan, err := Parse(strings.NewReader(validFile))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Errors)
+	assert.Equal(t, 3, plan.Transactions)
+	require.Len(t, plan.Accounts, 2)
+
+	assert.Equal(t, Account{
+		Line:              2,
+		ExternalReference: "L-1",
+		Owner:             "Alex Camara",
+		InitialBalance:    100.5,
+		Transactions: []Transaction{
+			{Line: 4, Type: bank.DepositTransactionType, Amount: 50, Reference: "Legacy interest"},
+			{Line: 5, Type: bank.WithdrawalTransactionType, Amount: 150.5, Reference: "Closing fee"},
+		},
+	}, plan.Accounts[0])
+	assert.Equal(t, 0.0, plan.Accounts[1].InitialBalance, "an empty initial balance is zero")
+}
+
+func TestParseAcceptsAnyColumnOrder(t *testing.T) {
+	plan, err := Parse(strings.NewReader("\ufeffOwner, Initial_Balance, External_Reference\nAlex Camara, 10, L-1\n"))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Errors)
+	require.Len(t, plan.Accounts, 1)
+	assert.Equal(t, "L-1", plan.Accounts[0].ExternalReference)
+	assert.Equal(t, 10.0, plan.Accounts[0].InitialBalance)
+}
+
+func TestParseReportsEveryInvalidRow(t *testing.T) {
+	file := `external_reference,owner,initial_balance,transaction_type,amount,reference
+L-1,Alex Camara,100,,,
+,John Doe,10,,,
+L-2,,10,,,
+L-3,Jane Doe,-5,,,
+L-4,Jane Doe,ten,,,
+L-1,Someone Else,0,,,
+L-1,,,refund,10,
+L-1,,,deposit,,
+L-1,,,withdrawal,500,
+L-9,,,deposit,10,
+L-2,,,deposit,10,
+L-1,,,withdrawal,100,
+`
+	plan, err := Parse(strings.NewReader(file))
+	require.NoError(t, err)
+
+	expected := []struct {
+		line   int
+		column string
+		err    error
+	}{
+		{3, ColumnExternalReference, bank.ErrInvalidImportRow},
+		{4, "", bank.ErrEmptyOwnerName},
+		{5, "", bank.ErrNegativeInitialBalance},
+		{6, ColumnInitialBalance, bank.ErrInvalidImportRow},
+		{7, ColumnExternalReference, bank.ErrInvalidImportRow},
+		{8, "", bank.ErrInvalidTransaction},
+		{9, "", bank.ErrZeroTransactionAmount},
+		{10, ColumnAmount, bank.ErrInsufficientFunds},
+		{11, ColumnExternalReference, bank.ErrInvalidImportRow},
+	}
+	require.Len(t, plan.Errors, len(expected), "line 12 belongs to an invalid account and line 13 is valid")
+	for i, rowErr := range plan.Errors {
+		assert.Equal(t, expected[i].line, rowErr.Line)
+		assert.Equal(t, expected[i].column, rowErr.Column)
+		assert.ErrorIs(t, rowErr, expected[i].err)
+	}
+
+	require.Len(t, plan.Accounts, 1)
+	assert.Len(t, plan.Accounts[0].Transactions, 1)
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"empty", ""},
+		{"header only", "external_reference,owner,initial_balance\n"},
+		{"missing column", "external_reference,owner\nL-1,Alex Camara\n"},
+		{"unknown column", "external_reference,owner,initial_balance,iban\nL-1,Alex Camara,0,ES00\n"},
+		{"duplicate column", "external_reference,owner,initial_balance,owner\nL-1,Alex Camara,0,Alex\n"},
+		{"wrong field count", "external_reference,owner,initial_balance\nL-1,Alex Camara\n"},
+		{"malformed quotes", "external_reference,owner,initial_balance\nL-1,\"Alex,0\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := Parse(strings.NewReader(test.file))
+			assert.ErrorIs(t, err, bank.ErrInvalidImportFile)
+			assert.Nil(t, plan)
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+
+	result, err := Import(ctx, store, strings.NewReader(validFile), false)
+	require.NoError(t, err)
+	assert.True(t, result.Committed())
+	require.Len(t, result.Imported, 2)
+
+	alex, err := store.GetAccountByID(ctx, result.Imported[0].Account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alex Camara", alex.Owner)
+	assert.Equal(t, 0.0, alex.Balance)
+	assert.Equal(t, map[string]string{ExternalReferenceKey: "L-1"}, alex.Metadata)
+
+	page, err := store.GetTransactionsByAccountID(ctx, alex.ID, bank.TransactionFilter{}, bank.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 2)
+	assert.Equal(t, "Legacy interest", page.Transactions[0].Reference)
+
+	john, err := store.GetAccountByID(ctx, result.Imported[1].Account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, john.Balance)
+}
+
+func TestImportDryRunAndInvalidFilesWriteNothing(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+
+	result, err := Import(ctx, store, strings.NewReader(validFile), true)
+	require.NoError(t, err)
+	assert.False(t, result.Committed())
+	assert.Equal(t, 2, result.Accounts)
+	assert.Equal(t, 3, result.Transactions)
+	assert.Empty(t, result.Imported)
+
+	invalid := validFile + "L-2,,,withdrawal,1000,\n"
+	result, err = Import(ctx, store, strings.NewReader(invalid), false)
+	require.NoError(t, err)
+	assert.False(t, result.Committed())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 7, result.Errors[0].Line)
+	assert.Empty(t, result.Imported)
+
+	page, err := store.ListAccounts(ctx, bank.AccountFilter{}, bank.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Accounts)
+}
+
+func TestImportStopsAtStoreFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := Import(ctx, memoryBank.NewBankStore(), strings.NewReader(validFile), false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Imported)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Line)
+	assert.ErrorIs(t, result.Errors[0], context.Canceled)
+}
+
+// failingUpdateStore creates accounts but fails to set their metadata.
+type failingUpdateStore struct {
+	Store
+}
+
+func (s failingUpdateStore) UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestImportKeepsAccountCreatedBeforeFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+
+	result, err := Import(ctx, failingUpdateStore{Store: store}, strings.NewReader(validFile), false)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Line)
+	require.Len(t, result.Imported, 1, "the account created for the failing row is reported")
+	assert.Equal(t, "L-1", result.Imported[0].ExternalReference)
+
+	account, err := store.GetAccountByID(ctx, result.Imported[0].Account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.5, account.Balance)
+}
@@ -0,0 +1,292 @@
+// Package csvImport loads accounts and their opening transactions from a CSV file.
+//
+// The file has a header row naming its columns, in any order:
+//
+//	external_reference,owner,initial_balance,transaction_type,amount,reference
+//
+// A row without transaction_type creates an account identified by external_reference, the ID it had
+// in the legacy system. A row with transaction_type is a deposit or withdrawal of amount on the account
+// with the same external_reference, which must be declared on an earlier row. The last three columns
+// are optional when the file only holds accounts.
+package csvImport
+
+import (
+	"bank-demo-app/internal/bank"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	ColumnExternalReference = "external_reference"
+	ColumnOwner             = "owner"
+	ColumnInitialBalance    = "initial_balance"
+	ColumnTransactionType   = "transaction_type"
+	ColumnAmount            = "amount"
+	ColumnReference         = "reference"
+
+	// MaxRows bounds the number of data rows in a single import.
+	MaxRows = 10000
+)
+
+var (
+	requiredColumns = []string{ColumnExternalReference, ColumnOwner, ColumnInitialBalance}
+	optionalColumns = []string{ColumnTransactionType, ColumnAmount, ColumnReference}
+)
+
+// Account is an account row with the transaction rows that follow it in the file.
+type Account struct {
+	Line              int
+	ExternalReference string
+	Owner             string
+	InitialBalance    float64
+	Transactions      []Transaction
+}
+
+type Transaction struct {
+	Line      int
+	Type      string
+	Amount    float64
+	Reference string
+}
+
+// RowError reports why the row at Line was rejected. Column is set when a single cell is at fault.
+type RowError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("line %d, column %s: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Plan is a parsed file. Accounts only holds valid rows; when Errors isn't empty the file
+// must not be imported.
+type Plan struct {
+	Accounts     []Account
+	Transactions int
+	Errors       []*RowError
+}
+
+// Parse reads and validates every row of the file. Problems with the file as a whole, such as a
+// missing column or malformed CSV, are returned as an error; problems with single rows are
+// collected in Plan.Errors so every one of them can be reported at once.
+func Parse(r io.Reader) (*Plan, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, bank.InvalidImportFileError("the file is empty")
+	}
+	if err != nil {
+		return nil, bank.InvalidImportFileError(err.Error())
+	}
+	columns, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &planParser{
+		plan:     &Plan{},
+		accounts: make(map[string]*accountState),
+	}
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, bank.InvalidImportFileError(err.Error())
+		}
+		if rows++; rows > MaxRows {
+			return nil, bank.InvalidImportFileError(fmt.Sprintf("at most %d rows are allowed", MaxRows))
+		}
+
+		line, _ := reader.FieldPos(0)
+		parser.parseRow(line, columns.row(record))
+	}
+
+	if rows == 0 {
+		return nil, bank.InvalidImportFileError("the file has no rows")
+	}
+
+	return parser.plan, nil
+}
+
+// columnIndex maps column names to their position in the header.
+type columnIndex map[string]int
+
+func parseHeader(header []string) (columnIndex, error) {
+	columns := make(columnIndex, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Spreadsheets often export a byte order mark.
+		}
+		if !knownColumn(name) {
+			return nil, bank.InvalidImportFileError(fmt.Sprintf("unknown column %q", name))
+		}
+		if _, exists := columns[name]; exists {
+			return nil, bank.InvalidImportFileError(fmt.Sprintf("duplicate column %q", name))
+		}
+		columns[name] = i
+	}
+
+	for _, name := range requiredColumns {
+		if _, exists := columns[name]; !exists {
+			return nil, bank.InvalidImportFileError(fmt.Sprintf("missing column %q", name))
+		}
+	}
+	return columns, nil
+}
+
+func knownColumn(name string) bool {
+	return slices.Contains(requiredColumns, name) || slices.Contains(optionalColumns, name)
+}
+
+// row returns the trimmed cells of record by column name. Absent columns read as empty.
+func (columns columnIndex) row(record []string) map[string]string {
+	cells := make(map[string]string, len(columns))
+	for name, i := range columns {
+		if i < len(record) {
+			cells[name] = strings.TrimSpace(record[i])
+		}
+	}
+	return cells
+}
+
+// accountState tracks an account declared in the file. Balance replays its transactions so
+// withdrawals that would overdraw it are caught before anything is imported.
+type accountState struct {
+	index   int // Position in Plan.Accounts, -1 when the account row itself is invalid.
+	balance float64
+}
+
+type planParser struct {
+	plan     *Plan
+	accounts map[string]*accountState
+}
+
+func (p *planParser) parseRow(line int, cells map[string]string) {
+	externalReference := cells[ColumnExternalReference]
+	if externalReference == "" {
+		p.reject(line, ColumnExternalReference, bank.InvalidImportRowError("external_reference is required"))
+		return
+	}
+
+	if cells[ColumnTransactionType] == "" && cells[ColumnAmount] == "" {
+		p.parseAccount(line, externalReference, cells)
+		return
+	}
+	p.parseTransaction(line, externalReference, cells)
+}
+
+func (p *planParser) parseAccount(line int, externalReference string, cells map[string]string) {
+	if _, exists := p.accounts[externalReference]; exists {
+		p.reject(line, ColumnExternalReference, bank.InvalidImportRowError(fmt.Sprintf("duplicate external_reference %q", externalReference)))
+		return
+	}
+	// Register the account even when the row is invalid, so its transactions aren't reported as orphans.
+	state := &accountState{index: -1}
+	p.accounts[externalReference] = state
+
+	initialBalance, err := parseAmount(cells[ColumnInitialBalance])
+	if err != nil {
+		p.reject(line, ColumnInitialBalance, err)
+		return
+	}
+	owner := cells[ColumnOwner]
+	if err := bank.ValidateAccountInput(owner, initialBalance); err != nil {
+		p.reject(line, "", err)
+		return
+	}
+	if err := bank.ValidateMetadata(map[string]string{ExternalReferenceKey: externalReference}); err != nil {
+		p.reject(line, ColumnExternalReference, err)
+		return
+	}
+
+	state.index = len(p.plan.Accounts)
+	state.balance = initialBalance
+	p.plan.Accounts = append(p.plan.Accounts, Account{
+		Line:              line,
+		ExternalReference: externalReference,
+		Owner:             owner,
+		InitialBalance:    initialBalance,
+	})
+}
+
+func (p *planParser) parseTransaction(line int, externalReference string, cells map[string]string) {
+	state, exists := p.accounts[externalReference]
+	if !exists {
+		p.reject(line, ColumnExternalReference, bank.InvalidImportRowError(fmt.Sprintf("no account with external_reference %q is declared above this row", externalReference)))
+		return
+	}
+
+	amount, err := parseAmount(cells[ColumnAmount])
+	if err != nil {
+		p.reject(line, ColumnAmount, err)
+		return
+	}
+	transaction := Transaction{
+		Line:      line,
+		Type:      cells[ColumnTransactionType],
+		Amount:    amount,
+		Reference: cells[ColumnReference],
+	}
+	if err := bank.ValidateTransaction(transaction.Type, transaction.Amount); err != nil {
+		p.reject(line, "", err)
+		return
+	}
+	if err := bank.ValidateReference(transaction.Reference); err != nil {
+		p.reject(line, ColumnReference, err)
+		return
+	}
+	if state.index < 0 {
+		// The account row was already reported, the transaction itself is fine.
+		return
+	}
+
+	account := &p.plan.Accounts[state.index]
+	if transaction.Type == bank.WithdrawalTransactionType {
+		if transaction.Amount > state.balance {
+			p.reject(line, ColumnAmount, bank.InsufficientFundsError(externalReference, state.balance, transaction.Amount))
+			return
+		}
+		state.balance -= transaction.Amount
+	} else {
+		state.balance += transaction.Amount
+	}
+	account.Transactions = append(account.Transactions, transaction)
+	p.plan.Transactions++
+}
+
+func (p *planParser) reject(line int, column string, err error) {
+	p.plan.Errors = append(p.plan.Errors, &RowError{Line: line, Column: column, Err: err})
+}
+
+// parseAmount reads a decimal number. An empty cell is zero so the initial balance can be left out.
+func parseAmount(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, bank.InvalidImportRowError(fmt.Sprintf("%q is not a number", value))
+	}
+	return amount, nil
+}
@@ -1,25 +1,479 @@
 package inputParams
 
 import (
+	"bank-demo-app/internal/bank"
 	"bank-demo-app/internal/mongodb"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported BankStore backends for the --store flag.
+const (
+	MemoryStore = "memory"
+	MongoStore  = "mongo"
+	SqliteStore = "sqlite"
+)
+
+// Supported log formats for the --log-format flag.
+const (
+	JSONLogFormat    = "json"
+	ConsoleLogFormat = "console"
 )
 
+// AdminAPIKeyEnv holds the token of an admin API key to create at startup. Like the MongoDB password
+// it has no flag, use --admin-api-key-file to read it from a file instead.
+const AdminAPIKeyEnv = "BANK_ADMIN_API_KEY"
+
+// ImportCommand is the subcommand that imports a CSV file instead of starting the server.
+const ImportCommand = "import"
+
+// MigrateCommand is the subcommand that applies or lists the MongoDB migrations.
+const MigrateCommand = "migrate"
+
+// Actions of the migrate subcommand.
+const (
+	MigrateUp     = "up"
+	MigrateStatus = "status"
+)
+
+// AppConfig is the configuration of the server. Every setting has a default, which the config
+// file, the environment and the flags override in that order, see loadConfig.
 type AppConfig struct {
-	InMemory  bool
-	MongoConf mongodb.MongoConfig
+	Server   ServerConfig
+	Log      LogConfig
+	Webhooks WebhookConfig
+
+	InMemory   bool
+	Store      string
+	SqlitePath string
+	MongoConf  mongodb.MongoConfig
+
+	// AdminAPIKey is the token of an admin API key created at startup when the store lacks it.
+	// AdminAPIKeyFile holds it instead, see AdminToken.
+	AdminAPIKey     string
+	AdminAPIKeyFile string
+
+	// JWTKeysDir holds the keys customer tokens are signed with, customer tokens are rejected when empty.
+	JWTKeysDir  string
+	JWTIssuer   string
+	JWTAudience string
+
+	// RateLimitFile holds the per-route rate limits, requests aren't limited when empty.
+	RateLimitFile string
+	// RateLimitStore keeps the buckets in memory, per instance, or in Mongo, shared by every instance.
+	RateLimitStore string
+
+	// TraceOutput is where spans are written: stdout or a file path. Tracing is off when empty.
+	TraceOutput string
+
+	// StartupRetries is how many more times opening an unreachable store is tried before giving up.
+	StartupRetries int
+
+	// PrintConfig asks to print the configuration, secrets redacted, instead of starting the server.
+	PrintConfig bool
+}
+
+type ServerConfig struct {
+	Address           string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// MaxBodySize is the largest JSON request body accepted, in bytes.
+	MaxBodySize int
+	// ShutdownTimeout is how long in-flight requests are given to finish on shutdown.
+	ShutdownTimeout time.Duration
+	// MetricsAddress is where the Prometheus metrics are served, on a listener of their own so
+	// they never reach the public API port. Empty disables them.
+	MetricsAddress string
+
+	// TLSCertFile and TLSKeyFile serve HTTPS instead of HTTP when set. TLSClientCAFile verifies
+	// client certificates, which TLSClientIdentities maps to API identities.
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSClientCAFile      string
+	TLSRequireClientCert bool
+	TLSClientIdentities  string
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// WebhookConfig configures how webhook deliveries are sent and retried.
+type WebhookConfig struct {
+	// MaxAttempts is how many times a delivery is sent before it is marked dead.
+	MaxAttempts int
+	// Timeout bounds each request to a webhook URL.
+	Timeout time.Duration
+	// InitialBackoff is the wait before the first retry, doubled for each one after it up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// ImportConfig configures the import subcommand: the store to import into and the file to read.
+type ImportConfig struct {
+	AppConfig
+	File   string
+	DryRun bool
+}
+
+// MigrateConfig configures the migrate subcommand: the database to migrate and what to do.
+type MigrateConfig struct {
+	AppConfig
+	Action string
+}
+
+// DefaultAppConfig returns the configuration used when nothing else is given.
+func DefaultAppConfig() *AppConfig {
+	return &AppConfig{
+		Server: ServerConfig{
+			Address:           ":8080",
+			MetricsAddress:    "127.0.0.1:9090",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxBodySize:       1 << 20,
+			ShutdownTimeout:   5 * time.Second,
+		},
+		Log: LogConfig{Level: "info", Format: JSONLogFormat},
+		Webhooks: WebhookConfig{
+			MaxAttempts:    8,
+			Timeout:        10 * time.Second,
+			InitialBackoff: 10 * time.Second,
+			MaxBackoff:     time.Hour,
+		},
+		InMemory:       true,
+		SqlitePath:     "bank.db",
+		MongoConf:      mongodb.MongoConfig{Host: "localhost", Port: "27017"},
+		RateLimitStore: MemoryStore,
+		StartupRetries: 5,
+	}
 }
 
 func ParseInputParams() (*AppConfig, error) {
-	config := new(AppConfig)
-	flag.BoolVar(&config.InMemory, "in-memory", true, "Run the application in memory (no database)")
-	config.MongoConf.AddFlagsParams()
-	flag.Parse()
+	return parseAppConfig(flag.CommandLine, os.Args[1:], os.LookupEnv)
+}
 
-	// Add more params check at production systems. For this test, DBName is enough.
-	if !config.InMemory && config.MongoConf.DbName == "" {
-		return &AppConfig{}, nil
+func parseAppConfig(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*AppConfig, error) {
+	config := DefaultAppConfig()
+	printConfig := flags.Bool("print-config", false, "Print the effective configuration, secrets redacted, and exit.")
+	if err := loadConfig(config, flags, args, lookupEnv, allSettings); err != nil {
+		return nil, err
 	}
+	config.PrintConfig = *printConfig
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
+
+// ParseImportParams parses the arguments that follow the import subcommand.
+func ParseImportParams(args []string) (*ImportConfig, error) {
+	return parseImportConfig(flag.NewFlagSet(ImportCommand, flag.ContinueOnError), args, os.LookupEnv)
+}
+
+func parseImportConfig(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*ImportConfig, error) {
+	config := &ImportConfig{AppConfig: *DefaultAppConfig()}
+	flags.BoolVar(&config.DryRun, "dry-run", false, "Only validate the file, nothing is imported.")
+	if err := loadConfig(&config.AppConfig, flags, args, lookupEnv, storeSettings); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 1 {
+		return nil, fmt.Errorf("usage: %s [flags] <file.csv>", ImportCommand)
+	}
+	config.File = flags.Arg(0)
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ParseMigrateParams parses the arguments that follow the migrate subcommand.
+func ParseMigrateParams(args []string) (*MigrateConfig, error) {
+	return parseMigrateConfig(flag.NewFlagSet(MigrateCommand, flag.ContinueOnError), args, os.LookupEnv)
+}
+
+func parseMigrateConfig(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*MigrateConfig, error) {
+	// Only MongoDB has migrations, so it is the store unless told otherwise.
+	config := &MigrateConfig{AppConfig: *DefaultAppConfig()}
+	config.Store = MongoStore
+	if err := loadConfig(&config.AppConfig, flags, args, lookupEnv, storeSettings); err != nil {
+		return nil, err
+	}
+	usage := fmt.Errorf("usage: %s [flags] %s|%s", MigrateCommand, MigrateUp, MigrateStatus)
+	if flags.NArg() != 1 {
+		return nil, usage
+	}
+	config.Action = flags.Arg(0)
+	if config.Action != MigrateUp && config.Action != MigrateStatus {
+		return nil, usage
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.Store != MongoStore {
+		return nil, fmt.Errorf("only the %s store has migrations, got %s", MongoStore, config.Store)
+	}
+	return config, nil
+}
+
+// validate resolves the store and checks every setting, reporting all the invalid ones at once.
+func (config *AppConfig) validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		s, _ := settingByKey(key)
+		errs = append(errs, fmt.Errorf("%s: %s", s.describe(), fmt.Sprintf(format, args...)))
+	}
+
+	if config.Store == "" {
+		config.Store = MongoStore
+		if config.InMemory {
+			config.Store = MemoryStore
+		}
+	}
+	switch config.Store {
+	case MemoryStore:
+		config.InMemory = true
+	case MongoStore:
+		config.InMemory = false
+	case SqliteStore:
+		config.InMemory = false
+		if config.SqlitePath == "" {
+			invalid("sqlite.path", "is required when the store is %s", SqliteStore)
+		}
+	default:
+		invalid("store", "unknown store %q, expected %s, %s or %s", config.Store, MemoryStore, MongoStore, SqliteStore)
+	}
+
+	if config.Store == MongoStore {
+		config.validateMongo(invalid, "the store")
+	} else if config.RateLimitStore == MongoStore {
+		config.validateMongo(invalid, "the rate limit store")
+	}
+
+	if _, port, err := net.SplitHostPort(config.Server.Address); err != nil || !validPort(port) {
+		invalid("server.address", "must be host:port, such as :8080 or 127.0.0.1:8080, got %q", config.Server.Address)
+	}
+	if config.Server.MetricsAddress != "" {
+		if _, port, err := net.SplitHostPort(config.Server.MetricsAddress); err != nil || !validPort(port) {
+			invalid("server.metrics_address", "must be host:port, such as 127.0.0.1:9090, got %q", config.Server.MetricsAddress)
+		} else if config.Server.MetricsAddress == config.Server.Address {
+			invalid("server.metrics_address", "must differ from server.address")
+		}
+	}
+	if config.Server.ReadTimeout < 0 {
+		invalid("server.read_timeout", "cannot be negative")
+	}
+	if config.Server.ReadHeaderTimeout < 0 {
+		invalid("server.read_header_timeout", "cannot be negative")
+	}
+	if config.Server.MaxBodySize <= 0 {
+		invalid("server.max_body_size", "must be positive")
+	}
+	if config.Server.WriteTimeout < 0 {
+		invalid("server.write_timeout", "cannot be negative")
+	}
+	if config.Server.IdleTimeout < 0 {
+		invalid("server.idle_timeout", "cannot be negative")
+	}
+	if config.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout", "must be greater than zero")
+	}
+	config.validateTLS(invalid)
+
+	switch strings.ToLower(config.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("log.level", "unknown level %q, expected debug, info, warn or error", config.Log.Level)
+	}
+	if config.Log.Format != JSONLogFormat && config.Log.Format != ConsoleLogFormat {
+		invalid("log.format", "unknown format %q, expected %s or %s", config.Log.Format, JSONLogFormat, ConsoleLogFormat)
+	}
+
+	if config.StartupRetries < 0 {
+		invalid("startup.retries", "cannot be negative")
+	}
+
+	if config.RateLimitStore != MemoryStore && config.RateLimitStore != MongoStore {
+		invalid("rate_limit.store", "unknown rate limit store %q, expected %s or %s", config.RateLimitStore, MemoryStore, MongoStore)
+	}
+
+	if config.AdminAPIKey != "" && config.AdminAPIKeyFile != "" {
+		invalid("auth.admin_api_key_file", "cannot be combined with an admin API key")
+	} else if token, err := config.AdminToken(); err != nil {
+		invalid("auth.admin_api_key_file", "%v", err)
+	} else if token != "" && len(token) < bank.MinAPIKeyTokenLength {
+		invalid("auth.admin_api_key", "must have at least %d characters", bank.MinAPIKeyTokenLength)
+	}
+
+	if config.Webhooks.MaxAttempts <= 0 {
+		invalid("webhooks.max_attempts", "must be positive")
+	}
+	if config.Webhooks.Timeout <= 0 {
+		invalid("webhooks.timeout", "must be greater than zero")
+	}
+	if config.Webhooks.InitialBackoff <= 0 {
+		invalid("webhooks.initial_backoff", "must be greater than zero")
+	}
+	if config.Webhooks.MaxBackoff < config.Webhooks.InitialBackoff {
+		invalid("webhooks.max_backoff", "cannot be shorter than webhooks.initial_backoff")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// AdminToken returns the token of the admin API key to create at startup, read from AdminAPIKeyFile
+// when set. It is empty when none was configured.
+func (config *AppConfig) AdminToken() (string, error) {
+	if config.AdminAPIKeyFile == "" {
+		return config.AdminAPIKey, nil
+	}
+	content, err := os.ReadFile(config.AdminAPIKeyFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// validateTLS checks the HTTPS settings: mutual TLS needs HTTPS, and client identities need
+// verified client certificates.
+func (config *AppConfig) validateTLS(invalid func(key, format string, args ...any)) {
+	server := &config.Server
+	if (server.TLSCertFile == "") != (server.TLSKeyFile == "") {
+		invalid("server.tls_key_file", "must be given together with server.tls_cert_file")
+	}
+	if server.TLSClientCAFile != "" && server.TLSCertFile == "" {
+		invalid("server.tls_client_ca_file", "needs HTTPS, set server.tls_cert_file")
+	}
+	if server.TLSRequireClientCert && server.TLSClientCAFile == "" {
+		invalid("server.tls_require_client_cert", "needs the client CAs in server.tls_client_ca_file")
+	}
+	if server.TLSClientIdentities != "" && server.TLSClientCAFile == "" {
+		invalid("server.tls_client_identities", "needs the client CAs in server.tls_client_ca_file")
+	}
+	for _, file := range []struct{ key, path string }{
+		{"server.tls_cert_file", server.TLSCertFile},
+		{"server.tls_key_file", server.TLSKeyFile},
+		{"server.tls_client_ca_file", server.TLSClientCAFile},
+		{"server.tls_client_identities", server.TLSClientIdentities},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			invalid(file.key, "%v", err)
+		}
+	}
+}
+
+// validateMongo checks the MongoDB settings when user, the store or the rate limit store, needs them.
+// The database defaults to the one named in the URI.
+func (config *AppConfig) validateMongo(invalid func(key, format string, args ...any), user string) {
+	mongo := &config.MongoConf
+	if mongo.URI != "" {
+		database, err := mongodb.URIDatabase(mongo.URI)
+		if err != nil {
+			invalid("mongo.uri", "%v", err)
+		} else if mongo.DbName == "" {
+			mongo.DbName = database
+		}
+	} else {
+		if mongo.Host == "" {
+			invalid("mongo.host", "cannot be empty")
+		}
+		if !validPort(mongo.Port) {
+			invalid("mongo.port", "must be a port number, got %q", mongo.Port)
+		}
+	}
+	if mongo.DbName == "" {
+		invalid("mongo.database", "is required when %s is %s, unless the URI names it", user, MongoStore)
+	}
+
+	if mongo.Passwd != "" && mongo.PasswdFile != "" {
+		invalid("mongo.password_file", "cannot be combined with a password")
+	}
+	if (mongo.Passwd != "" || mongo.PasswdFile != "") && mongo.User == "" {
+		invalid("mongo.user", "is required with a password")
+	}
+	for _, file := range []struct{ key, path string }{
+		{"mongo.password_file", mongo.PasswdFile},
+		{"mongo.tls_ca_file", mongo.TLSCAFile},
+		{"mongo.tls_cert_file", mongo.TLSCertFile},
+		{"mongo.tls_key_file", mongo.TLSKeyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			invalid(file.key, "%v", err)
+		}
+	}
+	if mongo.TLSKeyFile != "" && mongo.TLSCertFile == "" {
+		invalid("mongo.tls_key_file", "needs a client certificate in mongo.tls_cert_file")
+	}
+
+	if mongo.MinPoolSize < 0 {
+		invalid("mongo.min_pool_size", "cannot be negative")
+	}
+	if mongo.MaxPoolSize < 0 {
+		invalid("mongo.max_pool_size", "cannot be negative")
+	} else if mongo.MaxPoolSize > 0 && mongo.MinPoolSize > mongo.MaxPoolSize {
+		invalid("mongo.min_pool_size", "cannot be greater than mongo.max_pool_size")
+	}
+	if mongo.ConnectTimeout < 0 {
+		invalid("mongo.connect_timeout", "cannot be negative")
+	}
+	if mongo.ServerSelectionTimeout < 0 {
+		invalid("mongo.server_selection_timeout", "cannot be negative")
+	}
+}
+
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number >= 0 && number <= 65535
+}
+
+// Print writes the configuration as YAML, in the config file layout so it can be saved as one.
+// Secrets are redacted.
+func (config *AppConfig) Print(w io.Writer) error {
+	document := make(map[string]any)
+	for _, s := range allSettings {
+		if s.key == "" {
+			continue
+		}
+		section := document
+		path := strings.Split(s.key, ".")
+		for _, name := range path[:len(path)-1] {
+			if section[name] == nil {
+				section[name] = make(map[string]any)
+			}
+			section = section[name].(map[string]any)
+		}
+		section[path[len(path)-1]] = printableValue(s, config)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,387 @@
+package inputParams
+
+import (
+	"bank-demo-app/internal/mongodb"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the config file when --config isn't given.
+const ConfigFileEnv = "BANK_CONFIG"
+
+// redacted replaces the value of secret settings in the printed configuration.
+const redacted = "<redacted>"
+
+// setting is one configuration value. It is read from the config file under key, then from the
+// environment variable env and then from --flag, each source overriding the previous one. A
+// setting without key or env can only be given as a flag, one without flag never is.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	// redact hides the secret parts of the value when printed, secret hides all of it.
+	redact func(value string) string
+	// field points to the value in config: a *string, *int, *bool or *time.Duration.
+	field func(config *AppConfig) any
+}
+
+// storeSettings select and configure the BankStore, they are shared by the server and the
+// import subcommand.
+var storeSettings = []setting{
+	{flag: "in-memory", usage: "Run the application in memory (no database). Deprecated, use --store.",
+		field: func(c *AppConfig) any { return &c.InMemory }},
+	{key: "store", env: "BANK_STORE", flag: "store", usage: "Storage backend: memory, mongo or sqlite. Overrides --in-memory when set.",
+		field: func(c *AppConfig) any { return &c.Store }},
+	{key: "sqlite.path", env: "BANK_SQLITE_PATH", flag: "sqlite-path", usage: "SQLite database file used when --store=sqlite.",
+		field: func(c *AppConfig) any { return &c.SqlitePath }},
+	{key: "mongo.uri", env: "MONGODB_URI", flag: "mongo-uri", usage: "MongoDB connection string, mongodb:// or mongodb+srv://. Replaces --mongo-host and --mongo-port, the database defaults to the one in its path.", redact: mongodb.RedactURI,
+		field: func(c *AppConfig) any { return &c.MongoConf.URI }},
+	{key: "mongo.host", env: "MONGODB_HOST", flag: "mongo-host", usage: "MongoDB server host.",
+		field: func(c *AppConfig) any { return &c.MongoConf.Host }},
+	{key: "mongo.port", env: "MONGODB_PORT", flag: "mongo-port", usage: "MongoDB server port.",
+		field: func(c *AppConfig) any { return &c.MongoConf.Port }},
+	{key: "mongo.database", env: "MONGODB_DATABASE", flag: "mongo-db", usage: "MongoDB database name.",
+		field: func(c *AppConfig) any { return &c.MongoConf.DbName }},
+	{key: "mongo.user", env: "MONGODB_USER", flag: "mongo-user", usage: "MongoDB username, replaces the credentials of --mongo-uri.",
+		field: func(c *AppConfig) any { return &c.MongoConf.User }},
+	// The password has no flag, flags show in the process list.
+	{key: "mongo.password", env: "MONGODB_PASSWD", secret: true,
+		field: func(c *AppConfig) any { return &c.MongoConf.Passwd }},
+	{key: "mongo.password_file", env: "MONGODB_PASSWD_FILE", flag: "mongo-passwd-file", usage: "File holding the MongoDB password, such as a mounted secret.",
+		field: func(c *AppConfig) any { return &c.MongoConf.PasswdFile }},
+	{key: "mongo.auth_source", env: "MONGODB_AUTH_SOURCE", flag: "mongo-auth-source", usage: "Database the MongoDB user is defined in, admin by default.",
+		field: func(c *AppConfig) any { return &c.MongoConf.AuthSource }},
+	{key: "mongo.replica_set", env: "MONGODB_REPLICA_SET", flag: "mongo-replica-set", usage: "Name of the MongoDB replica set.",
+		field: func(c *AppConfig) any { return &c.MongoConf.ReplicaSet }},
+	{key: "mongo.tls", env: "MONGODB_TLS", flag: "mongo-tls", usage: "Connect to MongoDB over TLS. Implied by --mongo-tls-ca-file and --mongo-tls-cert-file.",
+		field: func(c *AppConfig) any { return &c.MongoConf.TLS }},
+	{key: "mongo.tls_ca_file", env: "MONGODB_TLS_CA_FILE", flag: "mongo-tls-ca-file", usage: "PEM bundle of the CAs MongoDB certificates are verified against, the system ones when empty.",
+		field: func(c *AppConfig) any { return &c.MongoConf.TLSCAFile }},
+	{key: "mongo.tls_cert_file", env: "MONGODB_TLS_CERT_FILE", flag: "mongo-tls-cert-file", usage: "PEM client certificate presented to MongoDB, with its key unless --mongo-tls-key-file is set.",
+		field: func(c *AppConfig) any { return &c.MongoConf.TLSCertFile }},
+	{key: "mongo.tls_key_file", env: "MONGODB_TLS_KEY_FILE", flag: "mongo-tls-key-file", usage: "PEM key of the MongoDB client certificate.",
+		field: func(c *AppConfig) any { return &c.MongoConf.TLSKeyFile }},
+	{key: "mongo.min_pool_size", env: "MONGODB_MIN_POOL_SIZE", flag: "mongo-min-pool-size", usage: "Connections kept open to each MongoDB server. 0 keeps the driver default.",
+		field: func(c *AppConfig) any { return &c.MongoConf.MinPoolSize }},
+	{key: "mongo.max_pool_size", env: "MONGODB_MAX_POOL_SIZE", flag: "mongo-max-pool-size", usage: "Maximum connections to each MongoDB server. 0 keeps the driver default.",
+		field: func(c *AppConfig) any { return &c.MongoConf.MaxPoolSize }},
+	{key: "mongo.connect_timeout", env: "MONGODB_CONNECT_TIMEOUT", flag: "mongo-connect-timeout", usage: "Timeout of opening a connection to MongoDB. 0 keeps the driver default.",
+		field: func(c *AppConfig) any { return &c.MongoConf.ConnectTimeout }},
+	{key: "mongo.server_selection_timeout", env: "MONGODB_SERVER_SELECTION_TIMEOUT", flag: "mongo-server-selection-timeout", usage: "How long an operation waits for a suitable MongoDB server. 0 keeps the driver default.",
+		field: func(c *AppConfig) any { return &c.MongoConf.ServerSelectionTimeout }},
+	{key: "log.level", env: "BANK_LOG_LEVEL", flag: "log-level", usage: "Minimum level logged: debug, info, warn or error.",
+		field: func(c *AppConfig) any { return &c.Log.Level }},
+	{key: "log.format", env: "BANK_LOG_FORMAT", flag: "log-format", usage: "Log format: json, or console for humans.",
+		field: func(c *AppConfig) any { return &c.Log.Format }},
+	{key: "startup.retries", env: "BANK_STARTUP_RETRIES", flag: "startup-retries", usage: "Times to retry, with exponential backoff, connecting to an unreachable database at startup before exiting. 0 fails fast.",
+		field: func(c *AppConfig) any { return &c.StartupRetries }},
+}
+
+// serverSettings configure the API server.
+var serverSettings = []setting{
+	{key: "server.address", env: "BANK_SERVER_ADDRESS", flag: "address", usage: "Address the server listens on, as host:port. The host may be empty to listen on every interface.",
+		field: func(c *AppConfig) any { return &c.Server.Address }},
+	{key: "server.metrics_address", env: "BANK_METRICS_ADDRESS", flag: "metrics-address", usage: "Address /metrics is served on, apart from the API since it is unauthenticated. Empty disables it.",
+		field: func(c *AppConfig) any { return &c.Server.MetricsAddress }},
+	{key: "server.read_timeout", env: "BANK_SERVER_READ_TIMEOUT", flag: "read-timeout", usage: "Maximum time to read a request, body included. 0 disables it.",
+		field: func(c *AppConfig) any { return &c.Server.ReadTimeout }},
+	{key: "server.read_header_timeout", env: "BANK_SERVER_READ_HEADER_TIMEOUT", flag: "read-header-timeout", usage: "Maximum time to read the headers of a request, so slow clients can't hold connections open. 0 uses the read timeout.",
+		field: func(c *AppConfig) any { return &c.Server.ReadHeaderTimeout }},
+	{key: "server.write_timeout", env: "BANK_SERVER_WRITE_TIMEOUT", flag: "write-timeout", usage: "Maximum time to handle a request and write its response. 0 disables it.",
+		field: func(c *AppConfig) any { return &c.Server.WriteTimeout }},
+	{key: "server.idle_timeout", env: "BANK_SERVER_IDLE_TIMEOUT", flag: "idle-timeout", usage: "How long an idle keep-alive connection is kept open. 0 disables it.",
+		field: func(c *AppConfig) any { return &c.Server.IdleTimeout }},
+	{key: "server.max_body_size", env: "BANK_SERVER_MAX_BODY_SIZE", flag: "max-body-size", usage: "Maximum size in bytes of a JSON request body, larger ones are rejected with 413. CSV imports have their own 10 MiB limit.",
+		field: func(c *AppConfig) any { return &c.Server.MaxBodySize }},
+	{key: "server.shutdown_timeout", env: "BANK_SERVER_SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "How long in-flight requests are given to finish when the server stops.",
+		field: func(c *AppConfig) any { return &c.Server.ShutdownTimeout }},
+	{key: "server.tls_cert_file", env: "BANK_TLS_CERT_FILE", flag: "tls-cert-file", usage: "PEM certificate, with its chain, to serve HTTPS with. Reloaded when the file changes or on SIGHUP.",
+		field: func(c *AppConfig) any { return &c.Server.TLSCertFile }},
+	{key: "server.tls_key_file", env: "BANK_TLS_KEY_FILE", flag: "tls-key-file", usage: "PEM key of --tls-cert-file.",
+		field: func(c *AppConfig) any { return &c.Server.TLSKeyFile }},
+	{key: "server.tls_client_ca_file", env: "BANK_TLS_CLIENT_CA_FILE", flag: "tls-client-ca-file", usage: "PEM bundle of the CAs client certificates are verified against, enabling mutual TLS.",
+		field: func(c *AppConfig) any { return &c.Server.TLSClientCAFile }},
+	{key: "server.tls_require_client_cert", env: "BANK_TLS_REQUIRE_CLIENT_CERT", flag: "tls-require-client-cert", usage: "Reject connections without a valid client certificate, instead of only verifying those sent.",
+		field: func(c *AppConfig) any { return &c.Server.TLSRequireClientCert }},
+	{key: "server.tls_client_identities", env: "BANK_TLS_CLIENT_IDENTITIES", flag: "tls-client-identities", usage: "JSON file mapping client certificate subjects to the API identity, name and scopes, they authenticate as.",
+		field: func(c *AppConfig) any { return &c.Server.TLSClientIdentities }},
+	// The admin key has no flag either, it would show in the process list.
+	{key: "auth.admin_api_key", env: AdminAPIKeyEnv, secret: true,
+		field: func(c *AppConfig) any { return &c.AdminAPIKey }},
+	{key: "auth.admin_api_key_file", env: "BANK_ADMIN_API_KEY_FILE", flag: "admin-api-key-file", usage: "File holding the token of an admin API key to create at startup if missing, such as a mounted secret.",
+		field: func(c *AppConfig) any { return &c.AdminAPIKeyFile }},
+	{key: "jwt.keys_dir", env: "BANK_JWT_KEYS_DIR", flag: "jwt-keys-dir", usage: "Directory of <kid>.secret (HMAC) and <kid>.pem (RSA public) keys that verify customer tokens.",
+		field: func(c *AppConfig) any { return &c.JWTKeysDir }},
+	{key: "jwt.issuer", env: "BANK_JWT_ISSUER", flag: "jwt-issuer", usage: "Issuer (iss) customer tokens must carry, not checked when empty.",
+		field: func(c *AppConfig) any { return &c.JWTIssuer }},
+	{key: "jwt.audience", env: "BANK_JWT_AUDIENCE", flag: "jwt-audience", usage: "Audience (aud) customer tokens must include, not checked when empty.",
+		field: func(c *AppConfig) any { return &c.JWTAudience }},
+	{key: "rate_limit.config", env: "BANK_RATE_LIMIT_CONFIG", flag: "rate-limit-config", usage: "JSON file of per-route rate limits, requests aren't limited when empty.",
+		field: func(c *AppConfig) any { return &c.RateLimitFile }},
+	{key: "rate_limit.store", env: "BANK_RATE_LIMIT_STORE", flag: "rate-limit-store", usage: "Where rate limit buckets are kept: memory (per instance) or mongo (shared by every instance).",
+		field: func(c *AppConfig) any { return &c.RateLimitStore }},
+	{key: "tracing.output", env: "BANK_TRACE_OUTPUT", flag: "trace-output", usage: "Write trace spans as JSON lines to stdout or to this file. Tracing is off when empty.",
+		field: func(c *AppConfig) any { return &c.TraceOutput }},
+	{key: "webhooks.max_attempts", env: "BANK_WEBHOOK_MAX_ATTEMPTS", flag: "webhook-max-attempts", usage: "Times a webhook delivery is sent before it is marked dead.",
+		field: func(c *AppConfig) any { return &c.Webhooks.MaxAttempts }},
+	{key: "webhooks.timeout", env: "BANK_WEBHOOK_TIMEOUT", flag: "webhook-timeout", usage: "Maximum time of a request to a webhook URL.",
+		field: func(c *AppConfig) any { return &c.Webhooks.Timeout }},
+	{key: "webhooks.initial_backoff", env: "BANK_WEBHOOK_INITIAL_BACKOFF", flag: "webhook-initial-backoff", usage: "Wait before retrying a failed webhook delivery, doubled after each further failure.",
+		field: func(c *AppConfig) any { return &c.Webhooks.InitialBackoff }},
+	{key: "webhooks.max_backoff", env: "BANK_WEBHOOK_MAX_BACKOFF", flag: "webhook-max-backoff", usage: "Longest wait between two attempts of a webhook delivery.",
+		field: func(c *AppConfig) any { return &c.Webhooks.MaxBackoff }},
+}
+
+// allSettings is every setting, the config file and the environment may hold any of them.
+var allSettings = append(append([]setting{}, storeSettings...), serverSettings...)
+
+// settingByKey returns the setting read from key in the config file.
+func settingByKey(key string) (setting, bool) {
+	for _, s := range allSettings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// describe names the setting and every way to give it, for error messages.
+func (s setting) describe() string {
+	var sources []string
+	if s.flag != "" {
+		sources = append(sources, "--"+s.flag)
+	}
+	if s.env != "" {
+		sources = append(sources, "$"+s.env)
+	}
+	if s.key == "" {
+		return sources[0]
+	}
+	return fmt.Sprintf("%s (%s)", s.key, strings.Join(sources, ", "))
+}
+
+// loadConfig layers the configuration into config, which holds the defaults: the config file
+// named by --config or $BANK_CONFIG, then the environment, then the flags given in args. Only
+// flagSettings can be given as flags, and flags holds the extra flags of the caller.
+func loadConfig(config *AppConfig, flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool), flagSettings []setting) error {
+	// Flags are parsed first, into a copy, so they can name the config file yet override it.
+	fromFlags := *config
+	byFlag := make(map[string]setting, len(flagSettings))
+	for _, s := range flagSettings {
+		if s.flag == "" {
+			continue
+		}
+		flags.Var(fieldValue{s.field(&fromFlags)}, s.flag, s.usage+envUsage(s))
+		byFlag[s.flag] = s
+	}
+	configFile := flags.String("config", "", "YAML or TOML configuration file, see config.example.yaml. Defaults to $"+ConfigFileEnv+".")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv(ConfigFileEnv)
+	}
+	if *configFile != "" {
+		if err := readConfigFile(config, *configFile); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, s := range allSettings {
+		if s.env == "" {
+			continue
+		}
+		if raw, found := lookupEnv(s.env); found {
+			if err := setField(s.field(config), raw); err != nil {
+				errs = append(errs, fmt.Errorf("$%s: %w", s.env, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if s, isSetting := byFlag[f.Name]; isSetting {
+			// The flag value was already checked when parsed, so it can't fail here.
+			_ = setField(s.field(config), formatField(s.field(&fromFlags)))
+		}
+	})
+	return nil
+}
+
+func envUsage(s setting) string {
+	if s.env == "" {
+		return ""
+	}
+	return " ($" + s.env + ")"
+}
+
+// readConfigFile applies the settings of a YAML or TOML file, told apart by its extension. Keys
+// are nested by section, so mongo.host is the host key of the mongo section.
+func readConfigFile(config *AppConfig, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	flat := make(map[string]any)
+	flattenKeys("", values, flat)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		s, known := settingByKey(key)
+		if !known {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+			continue
+		}
+		if _, isList := flat[key].([]any); isList {
+			errs = append(errs, fmt.Errorf("%s: %s: must be a single value, not a list", path, key))
+			continue
+		}
+		if err := setField(s.field(config), scalarString(flat[key])); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flattenKeys(prefix string, values map[string]any, flat map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if section, isSection := value.(map[string]any); isSection {
+			flattenKeys(key, section, flat)
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// scalarString turns a value decoded from the config file back into text, so it is parsed the
+// same way as environment variables and flags.
+func scalarString(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// fieldValue is the flag.Value of a setting.
+type fieldValue struct {
+	field any
+}
+
+func (v fieldValue) String() string {
+	if v.field == nil {
+		return ""
+	}
+	return formatField(v.field)
+}
+
+func (v fieldValue) Set(raw string) error {
+	return setField(v.field, raw)
+}
+
+func (v fieldValue) IsBoolFlag() bool {
+	_, isBool := v.field.(*bool)
+	return isBool
+}
+
+func setField(field any, raw string) error {
+	switch field := field.(type) {
+	case *string:
+		*field = raw
+	case *int:
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", raw)
+		}
+		*field = value
+	case *bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", raw)
+		}
+		*field = value
+	case *time.Duration:
+		value, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 1m, got %q", raw)
+		}
+		*field = value
+	default:
+		panic(fmt.Sprintf("inputParams: unsupported setting type %T", field))
+	}
+	return nil
+}
+
+func formatField(field any) string {
+	switch field := field.(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *time.Duration:
+		return field.String()
+	default:
+		panic(fmt.Sprintf("inputParams: unsupported setting type %T", field))
+	}
+}
+
+// printableValue is the value of a setting as written by PrintConfig, typed so the output can
+// be read back as a config file.
+func printableValue(s setting, config *AppConfig) any {
+	field := s.field(config)
+	if s.secret && formatField(field) != "" {
+		return redacted
+	}
+	if s.redact != nil {
+		return s.redact(formatField(field))
+	}
+	switch field := field.(type) {
+	case *string:
+		return *field
+	case *int:
+		return *field
+	case *bool:
+		return *field
+	default:
+		return formatField(field)
+	}
+}
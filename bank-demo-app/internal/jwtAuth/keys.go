@@ -0,0 +1,164 @@
+// Package jwtAuth verifies the signed JSON Web Tokens (RFC 7519) that identify end customers.
+//
+// Tokens are signed with HMAC (HS256, HS384, HS512) or RSA (RS256, RS384, RS512) keys read from a
+// directory. Each file is a key named after the kid that tokens carry in their header:
+//
+//	<kid>.secret  an HMAC secret, used as is apart from surrounding whitespace
+//	<kid>.pem     an RSA public key, PKIX ("PUBLIC KEY") or PKCS #1 ("RSA PUBLIC KEY")
+//
+// Keys are rotated by adding the new key next to the old one, issuing tokens with its kid and
+// removing the old file once the tokens signed with it have expired.
+package jwtAuth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	secretKeyExtension = ".secret"
+	rsaKeyExtension    = ".pem"
+
+	// minSecretLength follows RFC 7518, HMAC keys must be at least as long as the SHA-256 output.
+	minSecretLength = 32
+)
+
+// verificationKey holds either an HMAC secret or an RSA public key.
+type verificationKey struct {
+	secret []byte
+	rsa    *rsa.PublicKey
+}
+
+// KeySet is the set of keys tokens can be signed with, indexed by kid. It is safe for concurrent use.
+type KeySet struct {
+	dir string
+
+	mu   sync.RWMutex
+	keys map[string]verificationKey
+}
+
+// LoadKeySet reads every key in dir. A directory without keys is an error, since no token could
+// ever be verified.
+func LoadKeySet(dir string) (*KeySet, error) {
+	keySet := &KeySet{dir: dir}
+	if err := keySet.Reload(); err != nil {
+		return nil, err
+	}
+	return keySet, nil
+}
+
+// Reload reads the directory again, picking up added and removed keys. On error the keys loaded
+// before are kept.
+func (ks *KeySet) Reload() error {
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read JWT key directory %s: %w", ks.dir, err)
+	}
+
+	keys := make(map[string]verificationKey)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		extension := filepath.Ext(entry.Name())
+		kid := strings.TrimSuffix(entry.Name(), extension)
+		if extension != secretKeyExtension && extension != rsaKeyExtension {
+			continue
+		}
+
+		key, err := readKey(filepath.Join(ks.dir, entry.Name()), extension)
+		if err != nil {
+			return err
+		}
+		keys[kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no JWT keys found in %s, expected <kid>%s or <kid>%s files", ks.dir, secretKeyExtension, rsaKeyExtension)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the keys every interval until ctx is done, so rotated keys are picked up
+// without a restart.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload JWT keys, keeping the previous ones")
+			}
+		}
+	}
+}
+
+func (ks *KeySet) key(kid string) (verificationKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, exists := ks.keys[kid]
+	return key, exists
+}
+
+func readKey(path, extension string) (verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("failed to read JWT key %s: %w", path, err)
+	}
+
+	if extension == secretKeyExtension {
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < minSecretLength {
+			return verificationKey{}, fmt.Errorf("JWT secret %s must have at least %d bytes", path, minSecretLength)
+		}
+		return verificationKey{secret: secret}, nil
+	}
+
+	publicKey, err := parseRSAPublicKey(data)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+	}
+	return verificationKey{rsa: publicKey}, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, isRSA := key.(*rsa.PublicKey)
+		if !isRSA {
+			return nil, errors.New("only RSA public keys are supported")
+		}
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
@@ -0,0 +1,185 @@
+package jwtAuth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// maxTokenLength bounds the work spent on a token before its signature is checked.
+	maxTokenLength = 8 << 10
+
+	// DefaultLeeway absorbs clock skew between the token issuer and this server.
+	DefaultLeeway = 30 * time.Second
+)
+
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrTokenNotValidYet     = errors.New("token is not valid yet")
+	ErrInvalidClaims        = errors.New("invalid token claims")
+)
+
+// algorithms are the supported JWS algorithms. The key type is fixed by the algorithm family, so
+// an RSA public key can never be used as an HMAC secret.
+var algorithms = []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512"}
+
+// Claims are the verified claims of a token.
+type Claims struct {
+	Subject string
+	// Owner is the account owner the customer holds their accounts under, Subject when the token
+	// has no owner claim.
+	Owner string
+	// Scopes come from the space separated scope claim.
+	Scopes    []string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+}
+
+// tokenClaims are the registered claims plus the ones this server reads on top of them.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Owner string `json:"owner"`
+	Scope string `json:"scope"`
+}
+
+// Validate is called by the parser after the registered claims are checked.
+func (c tokenClaims) Validate() error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: sub is required", ErrInvalidClaims)
+	}
+	return nil
+}
+
+// Options are the claims every token must carry. Empty values aren't checked.
+type Options struct {
+	Issuer   string
+	Audience string
+	// Leeway defaults to DefaultLeeway.
+	Leeway time.Duration
+}
+
+type Verifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+	now    func() time.Time
+}
+
+func NewVerifier(keys *KeySet, options Options) *Verifier {
+	if options.Leeway == 0 {
+		options.Leeway = DefaultLeeway
+	}
+	verifier := &Verifier{keys: keys, now: time.Now}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(options.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return verifier.now() }),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	verifier.parser = jwt.NewParser(parserOptions...)
+	return verifier
+}
+
+// IsJWT tells tokens in the JWS compact form apart from opaque tokens such as API keys.
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the signature of token with the key named by its kid, then its expiry, issuer and
+// audience, and returns its claims. Tokens without exp or sub are rejected.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	if len(token) > maxTokenLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrMalformedToken, maxTokenLength)
+	}
+
+	var claims tokenClaims
+	parsed, err := v.parser.ParseWithClaims(token, &claims, v.keyFor)
+	if err != nil {
+		return nil, translateError(parsed, err)
+	}
+
+	verified := &Claims{
+		Subject:  claims.Subject,
+		Owner:    claims.Owner,
+		Scopes:   strings.Fields(claims.Scope),
+		Issuer:   claims.Issuer,
+		Audience: claims.Audience,
+		// The parser requires exp, so it is set.
+		ExpiresAt: claims.ExpiresAt.Time.UTC(),
+	}
+	if verified.Owner == "" {
+		verified.Owner = verified.Subject
+	}
+	return verified, nil
+}
+
+// keyFor returns the key named by the kid of token, provided its type matches the algorithm family.
+func (v *Verifier) keyFor(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, exists := v.keys.key(kid)
+	if !exists {
+		return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if key.secret == nil {
+			return nil, fmt.Errorf("%w: kid %q is not an HMAC key", ErrUnsupportedAlgorithm, kid)
+		}
+		return key.secret, nil
+	case *jwt.SigningMethodRSA:
+		if key.rsa == nil {
+			return nil, fmt.Errorf("%w: kid %q is not an RSA key", ErrUnsupportedAlgorithm, kid)
+		}
+		return key.rsa, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, token.Method.Alg())
+	}
+}
+
+// translateError maps the parser errors to the errors of this package, keeping the parser message.
+func translateError(token *jwt.Token, err error) error {
+	// Errors raised by keyFor and Validate already carry one of ours.
+	for _, own := range []error{ErrUnknownKey, ErrUnsupportedAlgorithm, ErrInvalidClaims} {
+		if errors.Is(err, own) {
+			return err
+		}
+	}
+
+	var translated error
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		translated = ErrMalformedToken
+	case errors.Is(err, jwt.ErrTokenExpired):
+		translated = ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		translated = ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid) && token != nil && token.Method != nil && !slices.Contains(algorithms, token.Method.Alg()):
+		// The parser reports algorithms outside WithValidMethods, such as none, as invalid signatures.
+		translated = ErrUnsupportedAlgorithm
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		translated = ErrInvalidSignature
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		// The token names an algorithm the library doesn't know.
+		translated = ErrUnsupportedAlgorithm
+	default:
+		translated = ErrInvalidClaims
+	}
+	return fmt.Errorf("%w: %v", translated, err)
+}
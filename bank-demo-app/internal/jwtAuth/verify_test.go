@@ -0,0 +1,195 @@
+package jwtAuth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var testNow = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+// sign builds a compact JWS of claims. key is an HMAC secret, an *rsa.PrivateKey or
+// jwt.UnsafeAllowNoneSignatureType for alg none.
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), jwt.MapClaims(claims))
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "customer-42",
+		"owner": "Alex Camara",
+		"scope": "accounts:read transfers:write",
+		"iss":   "https://auth.example.com",
+		"aud":   "bank-demo-app",
+		"exp":   testNow.Add(time.Hour).Unix(),
+	}
+}
+
+func writeRSAKey(t *testing.T, dir, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+rsaKeyExtension), block, 0o600))
+	return key
+}
+
+func newTestVerifier(t *testing.T) (*Verifier, string, *rsa.PrivateKey) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hmac-1"+secretKeyExtension), []byte(testSecret+"\n"), 0o600))
+	rsaKey := writeRSAKey(t, dir, "rsa-1")
+
+	keys, err := LoadKeySet(dir)
+	require.NoError(t, err)
+	verifier := NewVerifier(keys, Options{Issuer: "https://auth.example.com", Audience: "bank-demo-app"})
+	verifier.now = func() time.Time { return testNow }
+	return verifier, dir, rsaKey
+}
+
+func TestVerify(t *testing.T) {
+	verifier, _, rsaKey := newTestVerifier(t)
+
+	tokens := map[string]string{
+		"HS256": sign(t, "HS256", "hmac-1", []byte(testSecret), validClaims()),
+		"HS512": sign(t, "HS512", "hmac-1", []byte(testSecret), validClaims()),
+		"RS256": sign(t, "RS256", "rsa-1", rsaKey, validClaims()),
+	}
+	for alg, token := range tokens {
+		t.Run(alg, func(t *testing.T) {
+			assert.True(t, IsJWT(token))
+			claims, err := verifier.Verify(token)
+			require.NoError(t, err)
+			assert.Equal(t, "customer-42", claims.Subject)
+			assert.Equal(t, "Alex Camara", claims.Owner)
+			assert.Equal(t, []string{"accounts:read", "transfers:write"}, claims.Scopes)
+			assert.Equal(t, []string{"bank-demo-app"}, claims.Audience)
+		})
+	}
+}
+
+func TestVerifyOwnerDefaultsToSubject(t *testing.T) {
+	verifier, _, _ := newTestVerifier(t)
+	claims := validClaims()
+	delete(claims, "owner")
+	claims["aud"] = []string{"other", "bank-demo-app"}
+
+	verified, err := verifier.Verify(sign(t, "HS256", "hmac-1", []byte(testSecret), claims))
+	require.NoError(t, err)
+	assert.Equal(t, "customer-42", verified.Owner)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	verifier, _, rsaKey := newTestVerifier(t)
+	secret := []byte(testSecret)
+	withClaim := func(name string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	// Swap the payload of a valid token for another one, keeping the original signature.
+	valid := strings.Split(sign(t, "HS256", "hmac-1", secret, validClaims()), ".")
+	forged := strings.Split(sign(t, "HS256", "hmac-1", secret, withClaim("owner", "Mallory")), ".")
+	tampered := valid[0] + "." + forged[1] + "." + valid[2]
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedError error
+	}{
+		{"not a jwt", "bk_opaque-api-key", ErrMalformedToken},
+		{"bad header", base64.RawURLEncoding.EncodeToString([]byte("{")) + ".e30.c2ln", ErrMalformedToken},
+		{"alg none", sign(t, "none", "hmac-1", jwt.UnsafeAllowNoneSignatureType, validClaims()), ErrUnsupportedAlgorithm},
+		{"unknown kid", sign(t, "HS256", "hmac-2", secret, validClaims()), ErrUnknownKey},
+		{"wrong secret", sign(t, "HS256", "hmac-1", []byte(strings.Repeat("x", 32)), validClaims()), ErrInvalidSignature},
+		{"tampered payload", tampered, ErrInvalidSignature},
+		{"hmac alg with rsa key", sign(t, "HS256", "rsa-1", secret, validClaims()), ErrUnsupportedAlgorithm},
+		{"rsa alg with hmac key", sign(t, "RS256", "hmac-1", rsaKey, validClaims()), ErrUnsupportedAlgorithm},
+		{"expired", sign(t, "HS256", "hmac-1", secret, withClaim("exp", testNow.Add(-time.Minute).Unix())), ErrTokenExpired},
+		{"not valid yet", sign(t, "HS256", "hmac-1", secret, withClaim("nbf", testNow.Add(time.Hour).Unix())), ErrTokenNotValidYet},
+		{"missing exp", sign(t, "HS256", "hmac-1", secret, withClaim("exp", nil)), ErrInvalidClaims},
+		{"missing sub", sign(t, "HS256", "hmac-1", secret, withClaim("sub", nil)), ErrInvalidClaims},
+		{"wrong issuer", sign(t, "HS256", "hmac-1", secret, withClaim("iss", "https://evil.example.com")), ErrInvalidClaims},
+		{"wrong audience", sign(t, "HS256", "hmac-1", secret, withClaim("aud", "other")), ErrInvalidClaims},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := verifier.Verify(test.token)
+			assert.ErrorIs(t, err, test.expectedError)
+			assert.Nil(t, claims)
+		})
+	}
+}
+
+func TestVerifyAcceptsExpiryWithinLeeway(t *testing.T) {
+	verifier, _, _ := newTestVerifier(t)
+	claims := validClaims()
+	claims["exp"] = testNow.Add(-DefaultLeeway / 2).Unix()
+
+	_, err := verifier.Verify(sign(t, "HS256", "hmac-1", []byte(testSecret), claims))
+	assert.NoError(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	verifier, dir, _ := newTestVerifier(t)
+	newKey := writeRSAKey(t, dir, "rsa-2")
+	token := sign(t, "RS256", "rsa-2", newKey, validClaims())
+
+	_, err := verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrUnknownKey, "keys are only read on reload")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go verifier.keys.Watch(ctx, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, err := verifier.Verify(token)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "hmac-1"+secretKeyExtension)))
+	assert.Eventually(t, func() bool {
+		_, err := verifier.Verify(sign(t, "HS256", "hmac-1", []byte(testSecret), validClaims()))
+		return err != nil
+	}, time.Second, 10*time.Millisecond, "removed keys must stop verifying tokens")
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	_, err := LoadKeySet(t.TempDir())
+	assert.ErrorContains(t, err, "no JWT keys found")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "short"+secretKeyExtension), []byte("too-short"), 0o600))
+	_, err = LoadKeySet(dir)
+	assert.ErrorContains(t, err, "at least 32 bytes")
+
+	dir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken"+rsaKeyExtension), []byte("not pem"), 0o600))
+	_, err = LoadKeySet(dir)
+	assert.ErrorContains(t, err, "no PEM block")
+}
@@ -2,11 +2,12 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 // Struct that stores a connection to MongoDataBase.
@@ -22,21 +23,27 @@ func NewMongoDBClient(cfg *MongoConfig) *MongoDBClient {
 
 // ConnectMongoClient connects the initialized object to the given database.
 func (mb *MongoDBClient) ConnectMongoClient(ctx context.Context) error {
-
-	clientOptions := options.Client().ApplyURI(mb.Config.GetURL())
+	clientOptions, err := mb.Config.ClientOptions()
+	if err != nil {
+		return err
+	}
+	// Every command is traced as a client span, child of the span of the operation sending it.
+	clientOptions.SetMonitor(otelmongo.NewMonitor())
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return fmt.Errorf("error connecting to MongoDB url: %v", err)
+		return fmt.Errorf("error connecting to MongoDB url %s: %v", RedactURI(mb.Config.GetURL()), err)
 	}
 
 	mb.Client = client
 
-	if !mb.checkConnection(ctx) {
-		return fmt.Errorf("mongodb connection check failed")
+	if err := mb.CheckConnection(ctx); err != nil {
+		client.Disconnect(context.Background())
+		mb.Client = nil
+		return fmt.Errorf("mongodb connection check failed: %w", err)
 	}
 
-	log.Info().Msg("Connected to MongoDB on url " + mb.Config.GetURL())
+	log.Info().Msg("Connected to MongoDB on url " + RedactURI(mb.Config.GetURL()))
 
 	return nil
 }
@@ -44,22 +51,21 @@ func (mb *MongoDBClient) ConnectMongoClient(ctx context.Context) error {
 func (mb *MongoDBClient) GetCollections(colls []string) error {
 	mb.Collections = make(map[string]*mongo.Collection)
 	for _, coll := range colls {
-		mb.Collections[coll] = mb.Client.Database(mb.Config.DbName).Collection(coll)
+		mb.Collections[coll] = mb.Database().Collection(coll)
 	}
 	return nil
 }
 
-// Function to check if the client is successfully connected to database.
-func (mb *MongoDBClient) checkConnection(ctx context.Context) bool {
+// Database returns the configured database of the connected client.
+func (mb *MongoDBClient) Database() *mongo.Database {
+	return mb.Client.Database(mb.Config.DbName)
+}
 
+// CheckConnection pings the server, failing when the client isn't connected or the server
+// can't be reached.
+func (mb *MongoDBClient) CheckConnection(ctx context.Context) error {
 	if mb.Client == nil {
-		return false
+		return errors.New("mongodb client is not connected")
 	}
-
-	err := mb.Client.Ping(ctx, nil)
-	if err != nil {
-		log.Error().Msg("Error pinging mongoDB:" + err.Error())
-	}
-
-	return err == nil
+	return mb.Client.Ping(ctx, nil)
 }
@@ -1,29 +1,192 @@
 package mongodb
 
 import (
-	"flag"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// MongoConfig says how to reach the database: either a full connection string in URI, or the
+// Host and Port of a single server. The other settings apply to both, overriding the options of
+// the URI when set.
 type MongoConfig struct {
+	// URI is a mongodb:// or mongodb+srv:// connection string, Host and Port are ignored when set.
+	URI    string
 	Host   string
 	Port   string
 	DbName string
-	User   string
-	Passwd string
-}
 
-func (cfg *MongoConfig) AddFlagsParams() {
-	flag.StringVar(&cfg.Host, "mongo-host", "localhost", "MongoDB server host.")
-	flag.StringVar(&cfg.Port, "mongo-port", "27017", "MongoDB server port.")
-	flag.StringVar(&cfg.DbName, "mongo-db", "", "MongoDB database name (MONGODB_DATABASE).")
-	flag.StringVar(&cfg.User, "mongo-user", "", "MongoDB username (MONGODB_USER).")
-	flag.StringVar(&cfg.Passwd, "mongo-passwd", "", "MongoDB password (MONGODB_PASSWD).")
+	// User and Passwd authenticate against AuthSource, the credentials of the URI when empty.
+	// PasswdFile holds the password instead of Passwd, so it never shows in the process list.
+	User       string
+	Passwd     string
+	PasswdFile string
+	AuthSource string
+
+	ReplicaSet string
+
+	// TLS connects over TLS, verifying the server against TLSCAFile, or the system roots when
+	// empty. TLSCertFile is the client certificate, with its key in TLSKeyFile or in the same file.
+	TLS         bool
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Pool sizes and timeouts left at zero keep the value of the URI or the driver default.
+	MinPoolSize            int
+	MaxPoolSize            int
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
 }
 
-// Returns a url with the necessary format to connect to MongoDB.
+// GetURL returns the connection string, without the credentials given apart from it.
 func (cfg *MongoConfig) GetURL() string {
-	if cfg.User != "" && cfg.Passwd != "" {
-		return "mongodb://" + cfg.User + ":" + cfg.Passwd + "@" + cfg.Host + ":" + cfg.Port + "/" + cfg.DbName
+	if cfg.URI != "" {
+		return cfg.URI
+	}
+	return "mongodb://" + cfg.Host + ":" + cfg.Port + "/" + url.PathEscape(cfg.DbName)
+}
+
+// ClientOptions builds the driver options of the configuration, reading the password and the
+// TLS files.
+func (cfg *MongoConfig) ClientOptions() (*options.ClientOptions, error) {
+	clientOptions := options.Client().ApplyURI(cfg.GetURL())
+
+	if cfg.User != "" {
+		password, err := cfg.password()
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetAuth(options.Credential{
+			AuthSource:  cfg.AuthSource,
+			Username:    cfg.User,
+			Password:    password,
+			PasswordSet: password != "",
+		})
+	} else if cfg.AuthSource != "" && clientOptions.Auth != nil {
+		clientOptions.Auth.AuthSource = cfg.AuthSource
+	}
+
+	if cfg.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(cfg.ReplicaSet)
+	}
+
+	if cfg.TLS || cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	if cfg.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(uint64(cfg.MinPoolSize))
+	}
+	if cfg.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(uint64(cfg.MaxPoolSize))
+	}
+	if cfg.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+
+	if err := clientOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid MongoDB options: %w", err)
+	}
+	return clientOptions, nil
+}
+
+func (cfg *MongoConfig) password() (string, error) {
+	if cfg.PasswdFile == "" {
+		return cfg.Passwd, nil
+	}
+	content, err := os.ReadFile(cfg.PasswdFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read MongoDB password file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func (cfg *MongoConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MongoDB CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("MongoDB CA file %s holds no PEM certificate", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cfg.TLSCertFile != "" {
+		keyFile := cfg.TLSKeyFile
+		if keyFile == "" {
+			keyFile = cfg.TLSCertFile
+		}
+		certificate, err := tls.LoadX509KeyPair(cfg.TLSCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MongoDB client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// URIDatabase returns the database named in the path of a connection string, checking its
+// scheme and hosts without resolving them.
+func URIDatabase(uri string) (string, error) {
+	rest, found := strings.CutPrefix(uri, "mongodb://")
+	if !found {
+		if rest, found = strings.CutPrefix(uri, "mongodb+srv://"); !found {
+			return "", errors.New("must start with mongodb:// or mongodb+srv://")
+		}
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	hosts, database, _ := strings.Cut(rest, "/")
+	if at := strings.LastIndex(hosts, "@"); at >= 0 {
+		hosts = hosts[at+1:]
+	}
+	if hosts == "" {
+		return "", errors.New("must name at least one host")
+	}
+	database, err := url.PathUnescape(database)
+	if err != nil {
+		return "", fmt.Errorf("invalid database name: %w", err)
+	}
+	return database, nil
+}
+
+// RedactURI hides the password of a connection string, so it can be logged.
+func RedactURI(uri string) string {
+	scheme, rest, found := strings.Cut(uri, "://")
+	if !found {
+		return uri
+	}
+	end := strings.IndexAny(rest, "/?")
+	if end < 0 {
+		end = len(rest)
+	}
+	at := strings.LastIndex(rest[:end], "@")
+	if at < 0 {
+		return uri
+	}
+	user, _, hasPassword := strings.Cut(rest[:at], ":")
+	if !hasPassword {
+		return uri
 	}
-	return "mongodb://" + cfg.Host + ":" + cfg.Port + "/" + cfg.DbName
+	return scheme + "://" + user + ":xxxxx" + rest[at:]
 }
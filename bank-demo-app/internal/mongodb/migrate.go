@@ -0,0 +1,151 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationsCollection records the migrations applied to a database, one document per version.
+const MigrationsCollection = "schema_migrations"
+
+// namespaceExistsCode is the error code of creating a collection that already exists.
+const namespaceExistsCode = 48
+
+// Migration is one versioned change to the database: collections, validators or indexes. Up must
+// be idempotent, so a migration interrupted before it was recorded, or run by two instances
+// starting at once, can simply run again.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// AppliedMigration is the record of a migration in MigrationsCollection.
+type AppliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// MigrationStatus tells whether a migration was applied, Applied is nil while it is pending.
+type MigrationStatus struct {
+	Migration
+	Applied *AppliedMigration
+}
+
+// Migrate applies the pending migrations in version order and returns them. It stops at the
+// first failure, leaving the later ones pending.
+func Migrate(ctx context.Context, db *mongo.Database, migrations []Migration) ([]Migration, error) {
+	statuses, err := MigrationStatuses(ctx, db, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, status := range statuses {
+		if status.Applied != nil {
+			continue
+		}
+		migration := status.Migration
+		if err := migration.Up(ctx, db); err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+		if err := recordMigration(ctx, db, migration); err != nil {
+			return applied, err
+		}
+		log.Ctx(ctx).Info().Int("version", migration.Version).Str("description", migration.Description).Msg("Applied database migration")
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// MigrationStatuses returns every migration, in version order, with its record when applied.
+func MigrationStatuses(ctx context.Context, db *mongo.Database, migrations []Migration) ([]MigrationStatus, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.Collection(MigrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	var records []AppliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int]*AppliedMigration, len(records))
+	for i := range records {
+		applied[records[i].Version] = &records[i]
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i] = MigrationStatus{Migration: migration, Applied: applied[migration.Version]}
+	}
+	return statuses, nil
+}
+
+// validateMigrations checks that versions are positive and strictly increasing, so the order
+// migrations run in never depends on how they are listed.
+func validateMigrations(migrations []Migration) error {
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version <= previous {
+			return fmt.Errorf("migration %d is out of order, versions must be positive and increasing", migration.Version)
+		}
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no Up function", migration.Version)
+		}
+		previous = migration.Version
+	}
+	return nil
+}
+
+// recordMigration marks migration as applied. An instance that applied it concurrently keeps
+// its record.
+func recordMigration(ctx context.Context, db *mongo.Database, migration Migration) error {
+	record := AppliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().UTC()}
+	_, err := db.Collection(MigrationsCollection).UpdateOne(ctx,
+		bson.M{"_id": migration.Version},
+		bson.M{"$setOnInsert": record},
+		options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// CreateCollection creates the collection unless it exists, for use in migrations.
+func CreateCollection(ctx context.Context, db *mongo.Database, name string) error {
+	err := db.CreateCollection(ctx, name)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceExistsCode {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// SetValidator replaces the JSON schema documents of the collection are checked against. With
+// the moderate level, documents that were already invalid can still be updated.
+func SetValidator(ctx context.Context, db *mongo.Database, collection string, schema bson.M) error {
+	command := bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}
+	if err := db.RunCommand(ctx, command).Err(); err != nil {
+		return fmt.Errorf("failed to set the validator of %s: %w", collection, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package mongodb
+
+import (
+	"bank-demo-app/internal/mongodb/mongoTest"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongodPort is the port of the mongod spawned by TestMain, empty when none could be started.
+var mongodPort string
+
+func TestMain(m *testing.M) {
+	port, stop, err := mongoTest.StartMongod()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mongodb: skipping MongoDB tests: %v\n", err)
+	}
+	mongodPort = port
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// testDatabase connects to a database of its own on the mongod of TestMain.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	if mongodPort == "" {
+		t.Skip("mongod is not available")
+	}
+
+	client := NewMongoDBClient(&MongoConfig{Host: "127.0.0.1", Port: mongodPort, DbName: "migrate_" + fmt.Sprint(time.Now().UnixNano())})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, client.ConnectMongoClient(ctx))
+	t.Cleanup(func() {
+		client.Database().Drop(context.Background())
+		client.Client.Disconnect(context.Background())
+	})
+	return client.Database()
+}
+
+func TestValidateMigrations(t *testing.T) {
+	up := func(context.Context, *mongo.Database) error { return nil }
+
+	assert.NoError(t, validateMigrations([]Migration{{Version: 1, Up: up}, {Version: 2, Up: up}, {Version: 5, Up: up}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 0, Up: up}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 2, Up: up}, {Version: 1, Up: up}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 1, Up: up}, {Version: 1, Up: up}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 1}}))
+}
+
+func TestMigrate(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	runs := make(map[int]int)
+	migration := func(version int, up func(ctx context.Context, db *mongo.Database) error) Migration {
+		return Migration{Version: version, Description: fmt.Sprintf("migration %d", version), Up: func(ctx context.Context, db *mongo.Database) error {
+			runs[version]++
+			return up(ctx, db)
+		}}
+	}
+	migrations := []Migration{
+		migration(1, func(ctx context.Context, db *mongo.Database) error { return CreateCollection(ctx, db, "things") }),
+		migration(2, func(ctx context.Context, db *mongo.Database) error {
+			return SetValidator(ctx, db, "things", bson.M{"required": bson.A{"name"}})
+		}),
+	}
+
+	applied, err := Migrate(ctx, db, migrations)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	_, err = db.Collection("things").InsertOne(ctx, bson.M{"other": 1})
+	assert.Error(t, err, "the validator of migration 2 rejects documents without a name")
+
+	// Applied migrations don't run again, new ones do.
+	failing := migration(3, func(context.Context, *mongo.Database) error { return errors.New("boom") })
+	applied, err = Migrate(ctx, db, append(migrations, failing))
+	assert.ErrorContains(t, err, "migration 3 (migration 3) failed: boom")
+	assert.Empty(t, applied)
+	assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1}, runs)
+
+	statuses, err := MigrationStatuses(ctx, db, append(migrations, failing))
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.NotNil(t, statuses[0].Applied)
+	assert.NotNil(t, statuses[1].Applied)
+	assert.Nil(t, statuses[2].Applied, "a failed migration stays pending")
+
+	// Up is idempotent, so running it again as if it had not been recorded is harmless.
+	_, err = db.Collection(MigrationsCollection).DeleteMany(ctx, bson.M{})
+	require.NoError(t, err)
+	_, err = Migrate(ctx, db, migrations)
+	require.NoError(t, err)
+}
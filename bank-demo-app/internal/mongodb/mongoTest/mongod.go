@@ -0,0 +1,106 @@
+// Package mongoTest spawns throwaway mongod servers for the tests of the Mongo-backed packages.
+package mongoTest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mongodStartTimeout = 20 * time.Second
+	// replicaSet names the single-node replica set the mongod runs, so the tests get transactions.
+	replicaSet = "rs0"
+)
+
+// StartMongod spawns a mongod (from $MONGOD_PATH or the PATH) listening on a free local port as a
+// single-node replica set and waits until it accepts writes. stop kills it and removes its data; it is safe to call even on error.
+func StartMongod() (port string, stop func(), err error) {
+	stop = func() {}
+
+	binary := os.Getenv("MONGOD_PATH")
+	if binary == "" {
+		if binary, err = exec.LookPath("mongod"); err != nil {
+			return "", stop, err
+		}
+	}
+
+	port, err = freePort()
+	if err != nil {
+		return "", stop, err
+	}
+	dbPath, err := os.MkdirTemp("", "mongod-")
+	if err != nil {
+		return "", stop, err
+	}
+
+	cmd := exec.Command(binary, "--dbpath", dbPath, "--port", port, "--bind_ip", "127.0.0.1", "--replSet", replicaSet, "--quiet")
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dbPath)
+		return "", stop, err
+	}
+	stop = func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(dbPath)
+	}
+
+	if err := waitForMongod(port); err != nil {
+		stop()
+		return "", func() {}, err
+	}
+	return port, stop, nil
+}
+
+// waitForMongod initiates the replica set once mongod answers, then waits for it to become primary.
+func waitForMongod(port string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongodStartTimeout)
+	defer cancel()
+
+	// Until the replica set is initiated only a direct connection can reach the server.
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:"+port).SetDirect(true))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	admin := client.Database("admin")
+	initiated := false
+	for {
+		if !initiated {
+			initiated = admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: bson.M{
+				"_id":     replicaSet,
+				"members": bson.A{bson.M{"_id": 0, "host": "127.0.0.1:" + port}},
+			}}}).Err() == nil
+		} else {
+			var hello struct {
+				IsWritablePrimary bool `bson:"isWritablePrimary"`
+			}
+			if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil && hello.IsWritablePrimary {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mongod did not become ready on port %s: %w", port, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func freePort() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,161 @@
+// Package rateLimit throttles clients with token buckets: each client holds a bucket of Burst
+// tokens that refills at Rate tokens per second, and every request takes one.
+package rateLimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBucket is the bucket shared by the routes without a limit of their own.
+	DefaultBucket = "default"
+	// IPBucket is the bucket of each client IP address across the authenticated routes.
+	IPBucket = "ip"
+)
+
+type Limit struct {
+	// Rate is how many tokens are added to the bucket per second.
+	Rate float64
+	// Burst is the bucket capacity, the number of requests a client can send in a row.
+	Burst int
+}
+
+// Config holds the limits read from the rate limit file, for example:
+//
+//	{
+//	  "default": {"requests": 300, "per": "1m"},
+//	  "ip": {"requests": 600, "per": "1m"},
+//	  "routes": {
+//	    "POST /transfer": {"requests": 10, "per": "1m", "burst": 5}
+//	  }
+//	}
+//
+// Routes are named by method and pattern as declared in the route table. Each listed route has a
+// bucket of its own, the others share the default one. Without a default they aren't limited.
+// The ip limit is taken before the credentials are checked, so requests with a wrong API key or
+// token are limited too; it defaults to the default limit.
+type Config struct {
+	Default *Limit
+	IP      *Limit
+	Routes  map[string]Limit
+}
+
+type fileConfig struct {
+	Default *fileLimit           `json:"default"`
+	IP      *fileLimit           `json:"ip"`
+	Routes  map[string]fileLimit `json:"routes"`
+}
+
+// fileLimit allows requests per period, in bursts of up to burst requests (requests by default).
+type fileLimit struct {
+	Requests int    `json:"requests"`
+	Per      string `json:"per"`
+	Burst    int    `json:"burst"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rate limit file: %w", err)
+	}
+	defer file.Close()
+
+	config, err := ParseConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func ParseConfig(r io.Reader) (*Config, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var file fileConfig
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	config := &Config{Routes: make(map[string]Limit, len(file.Routes))}
+	if file.Default != nil {
+		limit, err := file.Default.limit()
+		if err != nil {
+			return nil, fmt.Errorf("default: %w", err)
+		}
+		config.Default = &limit
+	}
+	if file.IP != nil {
+		limit, err := file.IP.limit()
+		if err != nil {
+			return nil, fmt.Errorf("ip: %w", err)
+		}
+		config.IP = &limit
+	}
+	for route, fileLimit := range file.Routes {
+		if err := validateRoute(route); err != nil {
+			return nil, err
+		}
+		limit, err := fileLimit.limit()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", route, err)
+		}
+		config.Routes[route] = limit
+	}
+	return config, nil
+}
+
+func (l fileLimit) limit() (Limit, error) {
+	if l.Requests <= 0 {
+		return Limit{}, fmt.Errorf("requests must be greater than zero")
+	}
+	period, err := time.ParseDuration(l.Per)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("per must be a positive duration such as 1s or 1m, got %q", l.Per)
+	}
+	if l.Burst < 0 {
+		return Limit{}, fmt.Errorf("burst cannot be negative")
+	}
+
+	burst := l.Burst
+	if burst == 0 {
+		burst = l.Requests
+	}
+	return Limit{Rate: float64(l.Requests) / period.Seconds(), Burst: burst}, nil
+}
+
+func validateRoute(route string) error {
+	method, pattern, found := strings.Cut(route, " ")
+	if !found || method == "" || strings.ToUpper(method) != method || !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("route %q must be a method and a pattern, such as \"POST /transfer\"", route)
+	}
+	return nil
+}
+
+// LimitFor returns the bucket and limit of route, given as "METHOD /pattern". limited is false
+// when the route has no limit of its own and there is no default.
+func (c *Config) LimitFor(route string) (bucket string, limit Limit, limited bool) {
+	if limit, exists := c.Routes[route]; exists {
+		return route, limit, true
+	}
+	if c.Default != nil {
+		return DefaultBucket, *c.Default, true
+	}
+	return "", Limit{}, false
+}
+
+// IPLimit returns the limit of each client IP address on the authenticated routes. limited is
+// false when there is neither an ip nor a default limit.
+func (c *Config) IPLimit() (limit Limit, limited bool) {
+	if c.IP != nil {
+		return *c.IP, true
+	}
+	if c.Default != nil {
+		return *c.Default, true
+	}
+	return Limit{}, false
+}
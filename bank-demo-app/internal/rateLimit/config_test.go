@@ -0,0 +1,86 @@
+package rateLimit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(`{
+		"default": {"requests": 120, "per": "1m"},
+		"routes": {"POST /transfer": {"requests": 10, "per": "1s", "burst": 3}}
+	}`))
+	require.NoError(t, err)
+
+	bucket, limit, limited := config.LimitFor("POST /transfer")
+	assert.True(t, limited)
+	assert.Equal(t, "POST /transfer", bucket)
+	assert.Equal(t, Limit{Rate: 10, Burst: 3}, limit)
+
+	bucket, limit, limited = config.LimitFor("GET /accounts")
+	assert.True(t, limited)
+	assert.Equal(t, DefaultBucket, bucket)
+	assert.Equal(t, Limit{Rate: 2, Burst: 120}, limit, "the burst defaults to the requests")
+}
+
+func TestRoutesAreNotLimitedWithoutDefault(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(`{"routes": {"POST /transfer": {"requests": 10, "per": "1m"}}}`))
+	require.NoError(t, err)
+
+	_, _, limited := config.LimitFor("GET /accounts")
+	assert.False(t, limited)
+	_, limited = config.IPLimit()
+	assert.False(t, limited)
+}
+
+func TestIPLimitDefaultsToTheDefaultLimit(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(`{"default": {"requests": 60, "per": "1m"}}`))
+	require.NoError(t, err)
+	limit, limited := config.IPLimit()
+	assert.True(t, limited)
+	assert.Equal(t, Limit{Rate: 1, Burst: 60}, limit)
+
+	config, err = ParseConfig(strings.NewReader(`{"default": {"requests": 60, "per": "1m"}, "ip": {"requests": 10, "per": "1s"}}`))
+	require.NoError(t, err)
+	limit, limited = config.IPLimit()
+	assert.True(t, limited)
+	assert.Equal(t, Limit{Rate: 10, Burst: 10}, limit)
+}
+
+func TestParseConfigRejectsInvalidLimits(t *testing.T) {
+	tests := map[string]string{
+		"malformed":        `{"default": `,
+		"unknown field":    `{"default": {"requests": 1, "per": "1s", "rate": 3}}`,
+		"no requests":      `{"default": {"per": "1s"}}`,
+		"no period":        `{"default": {"requests": 1}}`,
+		"negative period":  `{"default": {"requests": 1, "per": "-1s"}}`,
+		"negative burst":   `{"default": {"requests": 1, "per": "1s", "burst": -1}}`,
+		"invalid ip limit": `{"ip": {"requests": 0, "per": "1s"}}`,
+		"route no method":  `{"routes": {"/transfer": {"requests": 1, "per": "1s"}}}`,
+		"route lower case": `{"routes": {"post /transfer": {"requests": 1, "per": "1s"}}}`,
+		"route no slash":   `{"routes": {"POST transfer": {"requests": 1, "per": "1s"}}}`,
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig(strings.NewReader(file))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": {"requests": 5, "per": "1s"}}`), 0o600))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Limit{Rate: 5, Burst: 5}, config.Default)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
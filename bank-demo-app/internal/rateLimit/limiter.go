@@ -0,0 +1,111 @@
+package rateLimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of taking a token from a client bucket.
+type Decision struct {
+	Allowed bool
+	// Limit is the bucket capacity and Remaining the whole tokens left in it.
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected client should wait for the next token.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Limiter takes a token from the bucket named key, creating it full the first time.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// decide describes a bucket left with tokens after a request was allowed or rejected.
+func decide(allowed bool, tokens float64, limit Limit) Decision {
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		decision.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	return decision
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(math.Max(seconds, 0) * float64(time.Second)))
+}
+
+// sweepInterval is how often MemoryLimiter drops the buckets that refilled completely,
+// which behave exactly like the new ones it would create in their place.
+const sweepInterval = time.Minute
+
+// MemoryLimiter keeps the buckets in the process, so each server instance enforces its own limits.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	limit     Limit
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (ml *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return Decision{}, err
+	}
+
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	ml.sweep(now)
+
+	b, exists := ml.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		ml.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return decide(allowed, b.tokens, limit), nil
+}
+
+func (ml *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(ml.lastSweep) < sweepInterval {
+		return
+	}
+	ml.lastSweep = now
+	for key, b := range ml.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(ml.buckets, key)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.updatedAt = now
+	}
+}
@@ -0,0 +1,140 @@
+package rateLimit
+
+import (
+	"bank-demo-app/internal/mongodb/mongoTest"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ctx = context.Background()
+
+// mongodPort is the port of the mongod spawned by TestMain, empty when none could be started.
+var mongodPort string
+
+func TestMain(m *testing.M) {
+	port, stop, err := mongoTest.StartMongod()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rateLimit: skipping MongoDB tests: %v\n", err)
+	}
+	mongodPort = port
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// fakeClock drives MemoryLimiter time by hand.
+type fakeClock struct{ now time.Time }
+
+func (fc *fakeClock) advance(d time.Duration) { fc.now = fc.now.Add(d) }
+
+func newFakeClockLimiter() (*MemoryLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return clock.now }
+	return limiter, clock
+}
+
+func TestMemoryLimiterRefillsOverTime(t *testing.T) {
+	limiter, clock := newFakeClockLimiter()
+	limit := Limit{Rate: 0.5, Burst: 2}
+
+	decision, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: 2 * time.Second}, decision)
+
+	decision, _ = limiter.Allow(ctx, "client", limit)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+
+	decision, _ = limiter.Allow(ctx, "client", limit)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 2*time.Second, decision.RetryAfter)
+	assert.Equal(t, 4*time.Second, decision.Reset)
+
+	clock.advance(time.Second)
+	decision, _ = limiter.Allow(ctx, "client", limit)
+	assert.False(t, decision.Allowed, "half a token isn't enough")
+	assert.Equal(t, time.Second, decision.RetryAfter)
+
+	clock.advance(time.Second)
+	decision, _ = limiter.Allow(ctx, "client", limit)
+	assert.True(t, decision.Allowed)
+
+	clock.advance(time.Hour)
+	decision, _ = limiter.Allow(ctx, "client", limit)
+	assert.Equal(t, 1, decision.Remaining, "the bucket never holds more than the burst")
+}
+
+func TestMemoryLimiterSweepsFullBuckets(t *testing.T) {
+	limiter, clock := newFakeClockLimiter()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, err := limiter.Allow(ctx, "idle", limit)
+	require.NoError(t, err)
+	clock.advance(sweepInterval)
+	_, err = limiter.Allow(ctx, "active", limit)
+	require.NoError(t, err)
+
+	assert.NotContains(t, limiter.buckets, "idle")
+	assert.Contains(t, limiter.buckets, "active")
+}
+
+func TestLimiters(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testLimiter(t, NewMemoryLimiter())
+	})
+	t.Run("mongo", func(t *testing.T) {
+		if mongodPort == "" {
+			t.Skip("mongod is not available")
+		}
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:"+mongodPort))
+		require.NoError(t, err)
+		database := client.Database("rate_limit_" + uuid.New().String()[:8])
+		t.Cleanup(func() {
+			database.Drop(ctx)
+			client.Disconnect(ctx)
+		})
+
+		limiter, err := NewMongoLimiter(ctx, database.Collection(Collection))
+		require.NoError(t, err)
+		testLimiter(t, limiter)
+	})
+}
+
+// testLimiter checks the behaviour every Limiter shares, on the real clock.
+func testLimiter(t *testing.T, limiter Limiter) {
+	limit := Limit{Rate: 10, Burst: 3}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		decision, err := limiter.Allow(ctx, "client", limit)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 3, decision.Limit)
+		assert.Equal(t, remaining, decision.Remaining)
+	}
+
+	decision, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Positive(t, decision.RetryAfter)
+	assert.LessOrEqual(t, decision.RetryAfter, 100*time.Millisecond)
+
+	decision, err = limiter.Allow(ctx, "other client", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "each key has its own bucket")
+
+	time.Sleep(150 * time.Millisecond)
+	decision, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "the bucket refilled")
+}
@@ -0,0 +1,78 @@
+package rateLimit
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the Mongo collection holding the shared buckets.
+const Collection = "rate_limits"
+
+// MongoLimiter keeps the buckets in a Mongo collection, so every server instance using it
+// enforces one common limit per client.
+type MongoLimiter struct {
+	collection *mongo.Collection
+}
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// NewMongoLimiter prepares collection for the buckets. Each bucket document expires once it
+// would have refilled completely, so idle clients don't pile up.
+func NewMongoLimiter(ctx context.Context, collection *mongo.Collection) (*MongoLimiter, error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to create indexes on %s: %w", collection.Name(), err)
+	}
+	return &MongoLimiter{collection: collection}, nil
+}
+
+// Allow refills and takes from the bucket in a single atomic update. It runs on the clock of
+// the database ($$NOW) rather than the one of each instance, which may drift apart.
+func (ml *MongoLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var b mongoBucket
+	err := ml.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, takePipeline(limit),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two instances created the bucket at once, the update of the one that lost applies now.
+		err = ml.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, takePipeline(limit),
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&b)
+	}
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to take a token from bucket %s: %w", key, err)
+	}
+	return decide(b.Allowed, b.Tokens, limit), nil
+}
+
+// takePipeline refills the bucket for the time elapsed since its last request, capped at the
+// burst, then takes a token if a whole one is left. A new bucket starts full.
+func takePipeline(limit Limit) mongo.Pipeline {
+	burst := float64(limit.Burst)
+	elapsedMillis := bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updated_at", "$$NOW"}}}}
+	refilled := bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{elapsedMillis, limit.Rate / 1000}},
+	}}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$min": bson.A{burst, refilled}},
+			"updated_at": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}}},
+		{{Key: "$set", Value: bson.M{"expires_at": bson.M{"$add": bson.A{
+			"$$NOW",
+			bson.M{"$multiply": bson.A{bson.M{"$subtract": bson.A{burst, "$tokens"}}, 1000 / limit.Rate}},
+		}}}}},
+	}
+}
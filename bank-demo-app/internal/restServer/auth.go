@@ -0,0 +1,224 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/jwtAuth"
+	"bank-demo-app/internal/rateLimit"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeader          = "X-API-Key"
+	bearerScheme          = "Bearer "
+	wwwAuthenticateHeader = "WWW-Authenticate"
+	callerContextKey      = "caller"
+)
+
+var (
+	// errUnauthenticated is returned when a protected route is called without credentials.
+	errUnauthenticated = errors.New("an API key or customer token is required")
+	// errInvalidCredentials covers unknown and revoked keys and invalid tokens alike, so callers
+	// can't probe which keys exist.
+	errInvalidCredentials = errors.New("the credentials are invalid or revoked")
+	errInsufficientScope  = errors.New("the credentials lack a required scope")
+	// errCustomerNotAllowed is returned when a customer token calls a route meant for API clients.
+	errCustomerNotAllowed = errors.New("the route is not available to customer tokens")
+	// errAccountAccessDenied is returned when a customer touches an account they don't own.
+	errAccountAccessDenied = errors.New("the account belongs to another customer")
+)
+
+// TokenVerifier checks the JWTs that customers authenticate with.
+type TokenVerifier interface {
+	Verify(token string) (*jwtAuth.Claims, error)
+}
+
+// RouteOption configures InitRestRoutes.
+type RouteOption func(*routeConfig)
+
+type routeConfig struct {
+	tokens      TokenVerifier
+	identities  ClientIdentities
+	limiter     rateLimit.Limiter
+	limits      *rateLimit.Config
+	metrics     *Metrics
+	maxBodySize int64
+}
+
+// WithTokenVerifier accepts customer JWTs, verified by tokens, on the routes marked Customers.
+// Without it only API keys are accepted.
+func WithTokenVerifier(tokens TokenVerifier) RouteOption {
+	return func(config *routeConfig) {
+		config.tokens = tokens
+	}
+}
+
+// caller is who sent the request: an API client holding apiKey, or a client certificate mapped
+// to one, or an end customer holding a token, who may only reach their own accounts.
+type caller struct {
+	apiKey   *bank.APIKey
+	customer *jwtAuth.Claims
+}
+
+func (c *caller) hasScope(scope string) bool {
+	if c.customer != nil {
+		return slices.Contains(c.customer.Scopes, scope)
+	}
+	return c.apiKey.HasScope(scope)
+}
+
+// currentCaller returns the caller authenticated by authMiddleware.
+func currentCaller(c *gin.Context) *caller {
+	value, _ := c.Get(callerContextKey)
+	current, _ := value.(*caller)
+	return current
+}
+
+// authMiddleware authenticates the API key or customer token sent as a bearer token or in
+// X-API-Key, or else the client certificate, and rejects callers that lack any of the route
+// scopes. Customer tokens are only accepted on routes marked Customers. The caller is stored in
+// the context for the handlers.
+func authMiddleware(bankStore BankStore, config routeConfig, route Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var current *caller
+		var err error
+		switch token := credentialsToken(c); {
+		case token == "":
+			current = authenticateClientCertificate(c, config.identities)
+			if current == nil {
+				c.Header(wwwAuthenticateHeader, strings.TrimSpace(bearerScheme))
+				writeError(c, errUnauthenticated)
+				return
+			}
+		case config.tokens != nil && jwtAuth.IsJWT(token):
+			current, err = authenticateCustomer(config.tokens, token, route)
+		default:
+			current, err = authenticateAPIKey(c, bankStore, token)
+		}
+		if err != nil {
+			if errors.Is(err, errInvalidCredentials) {
+				requestLog(c).Warn().Err(err).Msg("Rejected request with invalid credentials")
+				c.Header(wwwAuthenticateHeader, `Bearer error="invalid_token"`)
+			}
+			writeError(c, err)
+			return
+		}
+
+		for _, scope := range route.Scopes {
+			if !current.hasScope(scope) {
+				requestLog(c).Warn().Str("scope", scope).Msg("Rejected request with insufficient scope")
+				writeError(c, fmt.Errorf("%w: %s", errInsufficientScope, scope))
+				return
+			}
+		}
+
+		c.Set(callerContextKey, current)
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, bankStore BankStore, token string) (*caller, error) {
+	ctx, cancel := storeContext(c)
+	defer cancel()
+
+	key, err := bankStore.GetAPIKeyByHash(ctx, bank.HashAPIKeyToken(token))
+	if err == nil && key.IsRevoked() {
+		err = bank.ErrAPIKeyNotFound
+	}
+	if errors.Is(err, bank.ErrAPIKeyNotFound) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		requestLog(c).Error().Err(err).Msg("Failed to look up API key")
+		return nil, err
+	}
+	return &caller{apiKey: key}, nil
+}
+
+func authenticateCustomer(tokens TokenVerifier, token string, route Route) (*caller, error) {
+	claims, err := tokens.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+	}
+	if !route.Customers {
+		return nil, errCustomerNotAllowed
+	}
+	return &caller{customer: claims}, nil
+}
+
+// credentialsToken reads the token from the Authorization header, or from X-API-Key when there is none.
+func credentialsToken(c *gin.Context) string {
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		if len(authorization) < len(bearerScheme) || !strings.EqualFold(authorization[:len(bearerScheme)], bearerScheme) {
+			return ""
+		}
+		return strings.TrimSpace(authorization[len(bearerScheme):])
+	}
+	return strings.TrimSpace(c.GetHeader(apiKeyHeader))
+}
+
+// checkAccountOwner rejects customers reading or moving money from an account they don't own.
+// API clients may reach every account.
+func checkAccountOwner(c *gin.Context, account *bank.Account) error {
+	current := currentCaller(c)
+	if current == nil || current.customer == nil || account.Owner == current.customer.Owner {
+		return nil
+	}
+	requestLog(c).Warn().Str("subject", current.customer.Subject).Str("account_id", account.ID).Msg("Customer denied access to an account")
+	return fmt.Errorf("%w: account ID %s", errAccountAccessDenied, account.ID)
+}
+
+// authorizeAccount loads accountID for customers and checks they own it, before a handler
+// touches the account. It is a no-op for API clients.
+func authorizeAccount(ctx context.Context, c *gin.Context, bankStore BankStore, accountID string) error {
+	current := currentCaller(c)
+	if current == nil || current.customer == nil {
+		return nil
+	}
+	account, err := bankStore.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return checkAccountOwner(c, account)
+}
+
+// protectRoutes puts every route that declares scopes behind authMiddleware. Routes without
+// scopes stay public. The rate limiter, when configured, limits the client IP before and the
+// caller after it, so requests with bad credentials are limited too. Metrics run first, so
+// rejected requests are counted too. JSON bodies are size limited before
+// anything reads them.
+func protectRoutes(bankStore BankStore, config routeConfig, routes Routes) Routes {
+	maxBodySize := config.maxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	for i, route := range routes {
+		if config.metrics != nil {
+			routes[i].Middleware = append([]gin.HandlerFunc{metricsMiddleware(config.metrics)}, routes[i].Middleware...)
+		}
+		if len(route.Scopes) > 0 {
+			if config.limiter != nil {
+				if limit, limited := config.limits.IPLimit(); limited {
+					routes[i].Middleware = append(routes[i].Middleware, rateLimitMiddleware(config.limiter, rateLimit.IPBucket, limit, ipKey))
+				}
+			}
+			routes[i].Middleware = append(routes[i].Middleware, authMiddleware(bankStore, config, route))
+		}
+		if route.Request != nil && requestContentType(route) == jsonContentType {
+			routes[i].Middleware = append(routes[i].Middleware, limitBodyMiddleware(maxBodySize))
+		}
+		if config.limiter == nil {
+			continue
+		}
+		if bucket, limit, limited := config.limits.LimitFor(routeKey(route)); limited {
+			routes[i].Middleware = append(routes[i].Middleware, rateLimitMiddleware(config.limiter, bucket, limit, clientKey))
+		}
+	}
+	return routes
+}
@@ -0,0 +1,79 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientCertificateKeyPrefix sets the IDs of certificate identities apart from API key IDs.
+const clientCertificateKeyPrefix = "cert:"
+
+// ClientIdentities maps the subject of a verified client certificate, as printed by
+// openssl x509 -noout -subject -nameopt RFC2253 (for example "CN=reporting,O=Example Bank"),
+// to the API identity the certificate authenticates as.
+type ClientIdentities map[string]ClientIdentity
+
+// ClientIdentity is an API client authenticated by its certificate instead of an API key. It
+// grants Scopes like a key would.
+type ClientIdentity struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// LoadClientIdentities reads the identities from a JSON file, for example:
+//
+//	{
+//	  "CN=reporting,O=Example Bank": {"name": "reporting", "scopes": ["accounts:read", "transactions:read"]}
+//	}
+func LoadClientIdentities(path string) (ClientIdentities, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open client identities file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	var identities ClientIdentities
+	if err := decoder.Decode(&identities); err != nil {
+		return nil, fmt.Errorf("invalid client identities file %s: %w", path, err)
+	}
+	for subject, identity := range identities {
+		if err := bank.ValidateAPIKeyInput(identity.Name, identity.Scopes); err != nil {
+			return nil, fmt.Errorf("invalid client identity %q: %w", subject, err)
+		}
+	}
+	return identities, nil
+}
+
+// WithClientCertificates authenticates requests without an API key or token by their verified
+// client certificate, as the identity its subject maps to.
+func WithClientCertificates(identities ClientIdentities) RouteOption {
+	return func(config *routeConfig) {
+		config.identities = identities
+	}
+}
+
+// authenticateClientCertificate returns the caller of the client certificate verified during the
+// TLS handshake, nil when there is none or its subject isn't mapped to an identity.
+func authenticateClientCertificate(c *gin.Context, identities ClientIdentities) *caller {
+	if identities == nil || c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+
+	subject := c.Request.TLS.VerifiedChains[0][0].Subject.String()
+	identity, known := identities[subject]
+	if !known {
+		requestLog(c).Warn().Str("subject", subject).Msg("Client certificate is not mapped to an identity")
+		return nil
+	}
+	return &caller{apiKey: &bank.APIKey{
+		ID:     clientCertificateKeyPrefix + identity.Name,
+		Name:   identity.Name,
+		Scopes: identity.Scopes,
+	}}
+}
@@ -0,0 +1,173 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	problemContentType = "application/problem+json"
+
+	// statusClientClosedRequest is the non-standard status (popularised by nginx) used when
+	// the client went away before the response was ready.
+	statusClientClosedRequest = 499
+
+	internalErrorCode = "internal_error"
+)
+
+// errInvalidRequestBody is returned when a request body can't be decoded into its request struct.
+var errInvalidRequestBody = errors.New("invalid request body")
+
+// errRequestBodyTooLarge is returned when a request body exceeds the size limit of the server.
+var errRequestBodyTooLarge = errors.New("request body too large")
+
+// Problem is an RFC 7807 problem details document. Code is a stable, machine readable
+// identifier clients can switch on instead of parsing Detail.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// OperationIndex is the failed operation of an atomic batch.
+	OperationIndex *int `json:"operation_index,omitempty"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorMappings translates known errors to their HTTP status and code. Entries are matched
+// with errors.Is in order, so wrapped errors resolve to the sentinel they wrap.
+var errorMappings = []errorMapping{
+	// Request errors.
+	{errInvalidRequestBody, http.StatusBadRequest, "invalid_request_body"},
+	{errRequestBodyTooLarge, http.StatusRequestEntityTooLarge, "request_body_too_large"},
+	{errInvalidQueryParameter, http.StatusBadRequest, "invalid_query_parameter"},
+
+	// Authentication errors.
+	{errUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+	{errInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{errInsufficientScope, http.StatusForbidden, "insufficient_scope"},
+	{errCustomerNotAllowed, http.StatusForbidden, "customer_not_allowed"},
+	{errAccountAccessDenied, http.StatusForbidden, "account_access_denied"},
+	{errRateLimited, http.StatusTooManyRequests, "rate_limited"},
+
+	// Account errors.
+	{bank.ErrAccountNotFound, http.StatusNotFound, "account_not_found"},
+	{bank.ErrEmptyOwnerName, http.StatusBadRequest, "empty_owner_name"},
+	{bank.ErrNoTransactionsForAccount, http.StatusNotFound, "no_transactions_for_account"},
+	{bank.ErrAccountClosed, http.StatusConflict, "account_closed"},
+	{bank.ErrAccountBalanceNotZero, http.StatusConflict, "account_balance_not_zero"},
+	{bank.ErrInvalidMetadata, http.StatusBadRequest, "invalid_metadata"},
+
+	// Transaction errors.
+	{bank.ErrTransactionNotFound, http.StatusNotFound, "transaction_not_found"},
+	{bank.ErrInvalidTransaction, http.StatusBadRequest, "invalid_transaction_type"},
+	{bank.ErrTransactionTypeRequired, http.StatusBadRequest, "transaction_type_required"},
+	{bank.ErrZeroTransactionAmount, http.StatusBadRequest, "amount_not_positive"},
+	{bank.ErrReferenceTooLong, http.StatusBadRequest, "reference_too_long"},
+
+	// Balance errors.
+	{bank.ErrNegativeAmount, http.StatusBadRequest, "negative_amount"},
+	{bank.ErrAmountNotFinite, http.StatusBadRequest, "amount_not_finite"},
+	{bank.ErrNegativeInitialBalance, http.StatusBadRequest, "negative_initial_balance"},
+	{bank.ErrInsufficientFunds, http.StatusConflict, "insufficient_funds"},
+
+	// Transfer errors.
+	{bank.ErrTransferSourceNotFound, http.StatusUnprocessableEntity, "transfer_source_not_found"},
+	{bank.ErrTransferDestinationNotFound, http.StatusUnprocessableEntity, "transfer_destination_not_found"},
+	{bank.ErrSameSourceDestination, http.StatusUnprocessableEntity, "same_source_destination"},
+
+	// Query errors.
+	{bank.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{bank.ErrInvalidPageLimit, http.StatusBadRequest, "invalid_page_limit"},
+	{bank.ErrInvalidSortField, http.StatusBadRequest, "invalid_sort_field"},
+	{bank.ErrInvalidSortOrder, http.StatusBadRequest, "invalid_sort_order"},
+	{bank.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+
+	// Batch errors.
+	{bank.ErrInvalidBatch, http.StatusBadRequest, "invalid_batch"},
+	{bank.ErrAtomicBatchUnsupported, http.StatusNotImplemented, "atomic_batch_unsupported"},
+
+	// Import errors.
+	{bank.ErrInvalidImportFile, http.StatusBadRequest, "invalid_import_file"},
+	{bank.ErrInvalidImportRow, http.StatusBadRequest, "invalid_import_row"},
+
+	// API key errors.
+	{bank.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{bank.ErrInvalidAPIKey, http.StatusBadRequest, "invalid_api_key"},
+	{bank.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{bank.ErrInvalidWebhook, http.StatusBadRequest, "invalid_webhook"},
+
+	// Store call interrupted.
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "store_timeout"},
+	{context.Canceled, statusClientClosedRequest, "request_canceled"},
+}
+
+// translateError returns the status and code for err. Unknown errors are internal errors.
+func translateError(err error) (int, string) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.code
+		}
+	}
+	return http.StatusInternalServerError, internalErrorCode
+}
+
+// newProblem builds the problem document for err. Internal errors keep their details
+// out of the response, they only end up in the logs.
+func newProblem(c *gin.Context, err error) Problem {
+	status, code := translateError(err)
+
+	title := http.StatusText(status)
+	if status == statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    status,
+		Detail:    problemDetail(code, err),
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: requestID(c),
+	}
+
+	var operationErr *bank.BatchOperationError
+	if errors.As(err, &operationErr) {
+		problem.OperationIndex = &operationErr.Index
+	}
+
+	return problem
+}
+
+// problemDetail describes err to the client, without the details of internal errors.
+func problemDetail(code string, err error) string {
+	switch code {
+	case internalErrorCode:
+		return "The server failed to process the request."
+	}
+	return err.Error()
+}
+
+// writeError aborts the request with the problem+json representation of err.
+func writeError(c *gin.Context, err error) {
+	problem := newProblem(c, err)
+	if problem.Status >= http.StatusInternalServerError {
+		requestLog(c).Error().Err(err).Str("code", problem.Code).Msg("Request failed")
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -0,0 +1,179 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type translateErrorTest struct {
+	err            error
+	expectedStatus int
+	expectedCode   string
+}
+
+// sentinelTests covers every sentinel in bank/errors.go, TestEverySentinelIsTranslated keeps it so.
+var sentinelTests = []translateErrorTest{
+	{bank.ErrAccountNotFound, http.StatusNotFound, "account_not_found"},
+	{bank.ErrEmptyOwnerName, http.StatusBadRequest, "empty_owner_name"},
+	{bank.ErrNoTransactionsForAccount, http.StatusNotFound, "no_transactions_for_account"},
+	{bank.ErrAccountClosed, http.StatusConflict, "account_closed"},
+	{bank.ErrAccountBalanceNotZero, http.StatusConflict, "account_balance_not_zero"},
+	{bank.ErrInvalidMetadata, http.StatusBadRequest, "invalid_metadata"},
+	{bank.ErrTransactionNotFound, http.StatusNotFound, "transaction_not_found"},
+	{bank.ErrInvalidTransaction, http.StatusBadRequest, "invalid_transaction_type"},
+	{bank.ErrTransactionTypeRequired, http.StatusBadRequest, "transaction_type_required"},
+	{bank.ErrZeroTransactionAmount, http.StatusBadRequest, "amount_not_positive"},
+	{bank.ErrReferenceTooLong, http.StatusBadRequest, "reference_too_long"},
+	{bank.ErrNegativeAmount, http.StatusBadRequest, "negative_amount"},
+	{bank.ErrAmountNotFinite, http.StatusBadRequest, "amount_not_finite"},
+	{bank.ErrNegativeInitialBalance, http.StatusBadRequest, "negative_initial_balance"},
+	{bank.ErrInsufficientFunds, http.StatusConflict, "insufficient_funds"},
+	{bank.ErrTransferSourceNotFound, http.StatusUnprocessableEntity, "transfer_source_not_found"},
+	{bank.ErrTransferDestinationNotFound, http.StatusUnprocessableEntity, "transfer_destination_not_found"},
+	{bank.ErrSameSourceDestination, http.StatusUnprocessableEntity, "same_source_destination"},
+	{bank.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{bank.ErrInvalidPageLimit, http.StatusBadRequest, "invalid_page_limit"},
+	{bank.ErrInvalidSortField, http.StatusBadRequest, "invalid_sort_field"},
+	{bank.ErrInvalidSortOrder, http.StatusBadRequest, "invalid_sort_order"},
+	{bank.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+	{bank.ErrInvalidBatch, http.StatusBadRequest, "invalid_batch"},
+	{bank.ErrAtomicBatchUnsupported, http.StatusNotImplemented, "atomic_batch_unsupported"},
+	{bank.ErrInvalidImportFile, http.StatusBadRequest, "invalid_import_file"},
+	{bank.ErrInvalidImportRow, http.StatusBadRequest, "invalid_import_row"},
+	{bank.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{bank.ErrInvalidAPIKey, http.StatusBadRequest, "invalid_api_key"},
+	{bank.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{bank.ErrInvalidWebhook, http.StatusBadRequest, "invalid_webhook"},
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := append(slices.Clone(sentinelTests), []translateErrorTest{
+		// Wrapped by the bank helpers.
+		{bank.AccountNotFoundError("1"), http.StatusNotFound, "account_not_found"},
+		{bank.InsufficientFundsError("1", 10, 20), http.StatusConflict, "insufficient_funds"},
+		{bank.TransferDestinationNotFoundError("2"), http.StatusUnprocessableEntity, "transfer_destination_not_found"},
+		{&bank.BatchOperationError{Index: 3, Err: bank.ErrInsufficientFunds}, http.StatusConflict, "insufficient_funds"},
+		{bank.AtomicBatchUnsupportedError("standalone server"), http.StatusNotImplemented, "atomic_batch_unsupported"},
+
+		// Request and infrastructure errors.
+		{fmt.Errorf("%w: EOF", errInvalidRequestBody), http.StatusBadRequest, "invalid_request_body"},
+		{invalidQueryParameterError("limit", "ten"), http.StatusBadRequest, "invalid_query_parameter"},
+		{errUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+		{errInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+		{fmt.Errorf("%w: admin", errInsufficientScope), http.StatusForbidden, "insufficient_scope"},
+		{errCustomerNotAllowed, http.StatusForbidden, "customer_not_allowed"},
+		{fmt.Errorf("%w: account ID 1", errAccountAccessDenied), http.StatusForbidden, "account_access_denied"},
+		{fmt.Errorf("%w: retry in 3 seconds", errRateLimited), http.StatusTooManyRequests, "rate_limited"},
+		{fmt.Errorf("failed to get account: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "store_timeout"},
+		{context.Canceled, statusClientClosedRequest, "request_canceled"},
+		{errors.New("connection refused"), http.StatusInternalServerError, internalErrorCode},
+	}...)
+
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			status, code := translateError(test.err)
+			assert.Equal(t, test.expectedStatus, status)
+			assert.Equal(t, test.expectedCode, code)
+		})
+	}
+}
+
+// TestEverySentinelIsTranslated reads the sentinels declared in bank/errors.go, so one added
+// there without a row in sentinelTests fails here.
+func TestEverySentinelIsTranslated(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../bank/errors.go", nil, 0)
+	require.NoError(t, err)
+
+	tested := make(map[string]bool, len(sentinelTests))
+	for _, test := range sentinelTests {
+		tested[test.err.Error()] = true
+	}
+	var sentinels int
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, name := range spec.Names {
+			if !strings.HasPrefix(name.Name, "Err") || i >= len(spec.Values) {
+				continue
+			}
+			call, ok := spec.Values[i].(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				continue
+			}
+			message, ok := call.Args[0].(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			sentinels++
+			text, err := strconv.Unquote(message.Value)
+			require.NoError(t, err)
+			assert.True(t, tested[text], "bank.%s is missing from sentinelTests", name.Name)
+		}
+		return false
+	})
+	assert.Equal(t, len(sentinelTests), sentinels, "sentinelTests lists exactly the sentinels")
+}
+
+func TestErrorCodesAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, mapping := range errorMappings {
+		assert.False(t, seen[mapping.code], "duplicated error code %s", mapping.code)
+		seen[mapping.code] = true
+	}
+}
+
+func TestProblemResponse(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	req, _ := http.NewRequest(http.MethodGet, "/accounts/missing", nil)
+	req.Header.Set(requestIDHeader, "test-request-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "test-request-id", w.Header().Get(requestIDHeader))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "account_not_found", problem.Code)
+	assert.Equal(t, "Not Found", problem.Title)
+	assert.Equal(t, "/accounts/missing", problem.Instance)
+	assert.Equal(t, "test-request-id", problem.RequestID)
+	assert.Contains(t, problem.Detail, "missing")
+}
+
+func TestProblemResponseForValidationError(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	req, _ := http.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"owner": "", "initial_balance": 10}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "empty_owner_name", problem.Code)
+	assert.NotEmpty(t, problem.RequestID, "a request ID must be assigned when the client sends none")
+	assert.Equal(t, w.Header().Get(requestIDHeader), problem.RequestID)
+}
@@ -2,30 +2,97 @@ package restServer
 
 import (
 	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/csvImport"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
+)
+
+const (
+	// storeOperationTimeout bounds every BankStore call made while serving a request.
+	storeOperationTimeout = 5 * time.Second
+
+	// importTimeout bounds a whole CSV import, which makes several store calls per row.
+	importTimeout = 2 * time.Minute
+	// batchOperationTimeout is added to storeOperationTimeout for every operation of a batch, which
+	// stores may apply one by one with a few round trips each.
+	batchOperationTimeout = 100 * time.Millisecond
+	// responseWriteMargin is left to write the response of a long request once its store calls are done.
+	responseWriteMargin = 5 * time.Second
+	// maxImportSize caps the size of an uploaded CSV file.
+	maxImportSize = 10 << 20
 )
 
 // BankStore defines the methods required for managing accounts and transactions.
+// Every method honours ctx cancellation and deadlines.
 type BankStore interface {
 	// Account operations
-	CreateAccount(owner string, initialBalance float64) (*bank.Account, error)
-	GetAccountByID(id string) (*bank.Account, error)
-	ListAccounts() []bank.Account
+	CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error)
+	GetAccountByID(ctx context.Context, id string) (*bank.Account, error)
+	ListAccounts(ctx context.Context, filter bank.AccountFilter, page bank.PageRequest) (*bank.AccountPage, error)
+	UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error)
+	CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error)
 
 	// Transaction operations
-	PerformTransaction(accountID string, txType string, amount float64) (*bank.Transaction, error)
-	GetTransactionsByAccountID(accountID string) ([]bank.Transaction, error)
+	PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error)
+	GetTransactionByID(ctx context.Context, id string) (*bank.Transaction, error)
+	GetTransactionsByAccountID(ctx context.Context, accountID string, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionPage, error)
+	SearchTransactions(ctx context.Context, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionSearchPage, error)
 
 	// Transfer operations
-	TransferFunds(fromAccountID, toAccountID string, amount float64) error
+	TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error
+
+	// Batch operations
+	ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error)
+
+	// API key operations, keys are looked up by the hash of their token
+	CreateAPIKey(ctx context.Context, key bank.APIKey) (*bank.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*bank.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]bank.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (*bank.APIKey, error)
+
+	// Webhook operations, deleting a webhook deletes its deliveries
+	CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*bank.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]bank.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// Webhook delivery queue. Claiming pending deliveries postpones them by lease, so a
+	// delivery is only retried by another dispatcher if the one that claimed it stops.
+	EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error)
+}
+
+// storeContext derives the context for a store call from the request one, so a client
+// disconnect or server shutdown cancels it, and caps it with storeOperationTimeout.
+func storeContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), storeOperationTimeout)
+}
+
+// longStoreContext is storeContext for requests making many store calls, capped by timeout instead.
+// The write deadline of the connection is pushed back to match, so the server write timeout doesn't
+// drop the response of a request still within its own.
+func longStoreContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(timeout)
+	// Writers without a deadline to move, like test recorders, don't support it.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(deadline.Add(responseWriteMargin))
+	return context.WithDeadline(c.Request.Context(), deadline)
+}
+
+// batchTimeout scales storeOperationTimeout with the number of operations of a batch.
+func batchTimeout(operations int) time.Duration {
+	return storeOperationTimeout + time.Duration(operations)*batchOperationTimeout
 }
 
 // This is the default status handler that will be used to check if the REST server is up.
 func statusHandler(c *gin.Context) {
-	log.Info().Msg("Called GET status method.")
+	requestLog(c).Info().Msg("Called GET status method.")
 	if c.Request.Method != http.MethodGet {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Expected GET method!",
@@ -40,40 +107,63 @@ func createAccountHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request createAccountRequest
 
-		if err := c.ShouldBindJSON(&request); err != nil {
-			log.Error().Err(err).Msg("Invalid request body while creating account")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body while creating account")
+			writeError(c, err)
 			return
 		}
 
 		if err := bank.ValidateAccountInput(request.Owner, request.InitialBalance); err != nil {
-			log.Error().Err(err).Msg("Failed validationg account.")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Msg("Failed validationg account.")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("owner", request.Owner).Float64("initial_balance", request.InitialBalance).Msg("Creating account")
+		requestLog(c).Info().Str("owner", request.Owner).Float64("initial_balance", request.InitialBalance).Msg("Creating account")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
 
-		account, err := bankStore.CreateAccount(request.Owner, request.InitialBalance)
+		account, err := bankStore.CreateAccount(ctx, request.Owner, request.InitialBalance)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to create account")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Msg("Failed to create account")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("account_id", account.ID).Str("owner", account.Owner).Float64("initial_balance", account.Balance).Msg("Account created successfully")
+		requestLog(c).Info().Str("account_id", account.ID).Str("owner", account.Owner).Float64("initial_balance", account.Balance).Msg("Account created successfully")
 		c.JSON(http.StatusCreated, account)
 	}
 }
 
 func listAccountsHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Info().Msg("Listing all accounts")
+		filter, err := parseAccountFilter(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		page, err := parsePageRequest(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
 
-		accounts := bankStore.ListAccounts()
+		requestLog(c).Info().Msg("Listing accounts")
 
-		log.Info().Int("accounts_count", len(accounts)).Msg("Accounts listed successfully")
-		c.JSON(http.StatusOK, accounts)
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		accountPage, err := bankStore.ListAccounts(ctx, filter, page)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to list accounts")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Int("accounts_count", len(accountPage.Accounts)).Msg("Accounts listed successfully")
+		setPageHeaders(c, accountPage.NextCursor)
+		c.JSON(http.StatusOK, accountPage.Accounts)
 	}
 }
 
@@ -81,47 +171,150 @@ func getAccountByIDHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accountID := c.Param("id")
 
-		log.Info().Str("account_id", accountID).Msg("Retrieving account details")
+		requestLog(c).Info().Str("account_id", accountID).Msg("Retrieving account details")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
 
-		account, err := bankStore.GetAccountByID(accountID)
+		account, err := bankStore.GetAccountByID(ctx, accountID)
+		if err == nil {
+			err = checkAccountOwner(c, account)
+		}
 		if err != nil {
-			log.Error().Err(err).Str("account_id", accountID)
-			c.JSON(http.StatusNotFound, gin.H{"error": err})
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Failed to retrieve account")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("account_id", accountID).Str("owner", account.Owner).Float64("balance", account.Balance).Msg("Account retrieved successfully")
+		requestLog(c).Info().Str("account_id", accountID).Str("owner", account.Owner).Float64("balance", account.Balance).Msg("Account retrieved successfully")
 		c.JSON(http.StatusOK, account)
 	}
 }
 
+func replaceAccountHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request replaceAccountRequest
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body while replacing account")
+			writeError(c, err)
+			return
+		}
+
+		update := bank.AccountUpdate{
+			Owner:           &request.Owner,
+			Metadata:        make(map[string]*string, len(request.Metadata)),
+			ReplaceMetadata: true,
+		}
+		for key, value := range request.Metadata {
+			update.Metadata[key] = &value
+		}
+
+		applyAccountUpdate(c, bankStore, update)
+	}
+}
+
+func updateAccountHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request updateAccountRequest
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body while updating account")
+			writeError(c, err)
+			return
+		}
+
+		applyAccountUpdate(c, bankStore, bank.AccountUpdate{Owner: request.Owner, Metadata: request.Metadata})
+	}
+}
+
+// applyAccountUpdate validates update and applies it to the account in the id path parameter.
+func applyAccountUpdate(c *gin.Context, bankStore BankStore, update bank.AccountUpdate) {
+	accountID := c.Param("id")
+
+	if err := bank.ValidateAccountUpdate(update); err != nil {
+		requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Account update validation failed.")
+		writeError(c, err)
+		return
+	}
+
+	requestLog(c).Info().Str("account_id", accountID).Int("metadata_keys", len(update.Metadata)).Msg("Updating account")
+
+	ctx, cancel := storeContext(c)
+	defer cancel()
+
+	account, err := bankStore.UpdateAccount(ctx, accountID, update)
+	if err != nil {
+		requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Failed to update account")
+		writeError(c, err)
+		return
+	}
+
+	requestLog(c).Info().Str("account_id", account.ID).Str("owner", account.Owner).Msg("Account updated successfully")
+	c.JSON(http.StatusOK, account)
+}
+
+func closeAccountHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID := c.Param("id")
+		payoutAccountID := c.Query("payout_account_id")
+
+		requestLog(c).Info().Str("account_id", accountID).Str("payout_account_id", payoutAccountID).Msg("Closing account")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		closure, err := bankStore.CloseAccount(ctx, accountID, payoutAccountID)
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Failed to close account")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("account_id", closure.Account.ID).Msg("Account closed successfully")
+		c.JSON(http.StatusOK, closure.Account)
+	}
+}
+
 func performTransactionHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accountID := c.Param("id")
 		var request createTransactionRequest
 
-		if err := c.ShouldBindJSON(&request); err != nil {
-			log.Error().Err(err).Str("account_id", accountID).Msg("Invalid request body for transaction")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Invalid request body for transaction")
+			writeError(c, err)
 			return
 		}
 
 		if err := bank.ValidateTransaction(request.Type, request.Amount); err != nil {
-			log.Error().Err(err).Msg("Failed validationg account.")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Msg("Failed validationg account.")
+			writeError(c, err)
 			return
 		}
+		if err := bank.ValidateReference(request.Reference); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid transaction reference.")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("account_id", accountID).Str("transaction_type", request.Type).Float64("amount", request.Amount).Msg("Creating transaction")
 
-		log.Info().Str("account_id", accountID).Str("transaction_type", request.Type).Float64("amount", request.Amount).Msg("Creating transaction")
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		if err := authorizeAccount(ctx, c, bankStore, accountID); err != nil {
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Transaction not authorized")
+			writeError(c, err)
+			return
+		}
 
-		transaction, err := bankStore.PerformTransaction(accountID, request.Type, request.Amount)
+		transaction, err := bankStore.PerformTransaction(ctx, accountID, request.Type, request.Amount, request.Reference)
 		if err != nil {
-			log.Error().Err(err).Str("account_id", accountID).Msg("Failed to create transaction")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Failed to create transaction")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("account_id", accountID).Str("transaction_id", transaction.ID).Float64("amount", transaction.Amount).Msg("Transaction created successfully")
+		requestLog(c).Info().Str("account_id", accountID).Str("transaction_id", transaction.ID).Float64("amount", transaction.Amount).Msg("Transaction created successfully")
 		c.JSON(http.StatusCreated, transaction)
 	}
 }
@@ -130,44 +323,274 @@ func getTransactionsByAccountIDHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accountID := c.Param("id")
 
-		log.Info().Str("account_id", accountID).Msg("Retrieving transactions")
+		filter, err := parseTransactionFilter(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		page, err := parsePageRequest(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("account_id", accountID).Msg("Retrieving transactions")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		if err := authorizeAccount(ctx, c, bankStore, accountID); err != nil {
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Transactions access not authorized")
+			writeError(c, err)
+			return
+		}
+
+		transactionPage, err := bankStore.GetTransactionsByAccountID(ctx, accountID, filter, page)
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("account_id", accountID).Msg("Transactions not found")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("account_id", accountID).Int("transaction_count", len(transactionPage.Transactions)).Msg("Transactions retrieved successfully")
+		setPageHeaders(c, transactionPage.NextCursor)
+		c.JSON(http.StatusOK, transactionPage.Transactions)
+	}
+}
+
+func getTransactionByIDHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transactionID := c.Param("id")
+
+		requestLog(c).Info().Str("transaction_id", transactionID).Msg("Retrieving transaction details")
 
-		transactions, err := bankStore.GetTransactionsByAccountID(accountID)
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		transaction, err := bankStore.GetTransactionByID(ctx, transactionID)
 		if err != nil {
-			log.Error().Err(err).Str("account_id", accountID).Msg("Transactions not found")
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Str("transaction_id", transactionID).Msg("Failed to retrieve transaction")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("account_id", accountID).Int("transaction_count", len(transactions)).Msg("Transactions retrieved successfully")
-		c.JSON(http.StatusOK, transactions)
+		requestLog(c).Info().Str("transaction_id", transaction.ID).Str("account_id", transaction.AccountID).Msg("Transaction retrieved successfully")
+		c.JSON(http.StatusOK, transaction)
+	}
+}
+
+func searchTransactionsHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseTransactionSearchFilter(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		page, err := parsePageRequest(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Int("account_ids", len(filter.AccountIDs)).Str("transaction_type", filter.Type).Msg("Searching transactions")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		searchPage, err := bankStore.SearchTransactions(ctx, filter, page)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to search transactions")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Int("transaction_count", len(searchPage.Transactions)).Int("total_matches", searchPage.Summary.Count).Msg("Transactions searched successfully")
+		setPageHeaders(c, searchPage.NextCursor)
+		c.JSON(http.StatusOK, transactionSearchResponse{
+			Transactions: searchPage.Transactions,
+			Summary:      searchPage.Summary,
+			NextCursor:   searchPage.NextCursor,
+		})
 	}
 }
 
 func transferFundsHandler(bankStore BankStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request transferRequest
-		if err := c.ShouldBindJSON(&request); err != nil {
-			log.Error().Err(err).Msg("Invalid request body for transfer")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body for transfer")
+			writeError(c, err)
 			return
 		}
 		if err := bank.ValidateTransfer(request.FromAccountID, request.ToAccountID, request.Amount); err != nil {
-			log.Error().Err(err).Msg("Transfer validation failed.")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Msg("Transfer validation failed.")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Float64("amount", request.Amount).Msg("Initiating fund transfer")
+		requestLog(c).Info().Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Float64("amount", request.Amount).Msg("Initiating fund transfer")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		// Customers may only move money out of their own accounts, any account can receive it.
+		if err := authorizeAccount(ctx, c, bankStore, request.FromAccountID); err != nil {
+			if errors.Is(err, bank.ErrAccountNotFound) {
+				err = bank.TransferSourceNotFoundError(request.FromAccountID)
+			}
+			requestLog(c).Error().Err(err).Str("from_account_id", request.FromAccountID).Msg("Transfer not authorized")
+			writeError(c, err)
+			return
+		}
+
+		err := bankStore.TransferFunds(ctx, request.FromAccountID, request.ToAccountID, request.Amount)
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Msg("Failed to transfer funds")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Float64("amount", request.Amount).Msg("Transfer successful")
+		c.JSON(http.StatusOK, messageResponse{Message: "Transfer successful"})
+	}
+}
+
+func executeBatchHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request batchRequest
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body for batch")
+			writeError(c, err)
+			return
+		}
+
+		batch := bank.Batch{Atomic: request.Atomic, Operations: request.Operations}
+		if err := bank.ValidateBatch(batch); err != nil {
+			requestLog(c).Error().Err(err).Msg("Batch validation failed.")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Bool("atomic", batch.Atomic).Int("operations", len(batch.Operations)).Msg("Executing batch")
+
+		ctx, cancel := longStoreContext(c, batchTimeout(len(batch.Operations)))
+		defer cancel()
+
+		result, err := bankStore.ExecuteBatch(ctx, batch)
+		if err != nil {
+			requestLog(c).Error().Err(err).Bool("atomic", batch.Atomic).Msg("Failed to execute batch")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Bool("atomic", batch.Atomic).Int("succeeded", result.Succeeded).Int("failed", result.Failed).Msg("Batch executed")
+		c.JSON(http.StatusOK, newBatchResponse(result))
+	}
+}
+
+// importHandler ingests a CSV of accounts and opening transactions sent as the raw request body.
+// With ?dry_run=true rows are only validated. Rejected rows are reported with 422.
+func importHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun := false
+		if value := c.Query("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				writeError(c, invalidQueryParameterError("dry_run", value))
+				return
+			}
+		}
+
+		requestLog(c).Info().Bool("dry_run", dryRun).Msg("Importing CSV")
+
+		ctx, cancel := longStoreContext(c, importTimeout)
+		defer cancel()
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		result, err := csvImport.Import(ctx, bankStore, body, dryRun)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to read CSV import")
+			writeError(c, err)
+			return
+		}
+
+		status := http.StatusCreated
+		switch {
+		case len(result.Errors) > 0:
+			status = http.StatusUnprocessableEntity
+			requestLog(c).Error().Int("errors", len(result.Errors)).Int("imported", len(result.Imported)).Msg("CSV import rejected rows")
+		case result.DryRun:
+			status = http.StatusOK
+		}
+
+		requestLog(c).Info().Bool("dry_run", dryRun).Int("accounts", result.Accounts).Int("transactions", result.Transactions).Int("imported", len(result.Imported)).Msg("CSV import finished")
+		c.JSON(status, newImportResponse(result))
+	}
+}
+
+func createAPIKeyHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createAPIKeyRequest
+
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body while creating API key")
+			writeError(c, err)
+			return
+		}
+
+		key, token, err := bank.NewAPIKey(request.Name, request.Scopes, time.Now())
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed validating API key.")
+			writeError(c, err)
+			return
+		}
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		created, err := bankStore.CreateAPIKey(ctx, key)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to create API key")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("api_key_id", created.ID).Str("name", created.Name).Strs("scopes", created.Scopes).Msg("API key created successfully")
+		c.JSON(http.StatusCreated, createdAPIKeyResponse{APIKey: *created, Token: token})
+	}
+}
+
+func listAPIKeysHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		keys, err := bankStore.ListAPIKeys(ctx)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to list API keys")
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+func revokeAPIKeyHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyID := c.Param("id")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
 
-		err := bankStore.TransferFunds(request.FromAccountID, request.ToAccountID, request.Amount)
+		key, err := bankStore.RevokeAPIKey(ctx, keyID, time.Now())
 		if err != nil {
-			log.Error().Err(err).Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Msg("Failed to transfer funds")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			requestLog(c).Error().Err(err).Str("api_key_id", keyID).Msg("Failed to revoke API key")
+			writeError(c, err)
 			return
 		}
 
-		log.Info().Str("from_account_id", request.FromAccountID).Str("to_account_id", request.ToAccountID).Float64("amount", request.Amount).Msg("Transfer successful")
-		c.JSON(http.StatusOK, gin.H{"message": "Transfer successful"})
+		requestLog(c).Info().Str("api_key_id", key.ID).Msg("API key revoked successfully")
+		c.JSON(http.StatusOK, key)
 	}
 }
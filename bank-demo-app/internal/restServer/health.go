@@ -0,0 +1,110 @@
+package restServer
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	// dependencyCheckTimeout bounds each readiness check, so a hung dependency fails the probe
+	// instead of hanging it.
+	dependencyCheckTimeout = 2 * time.Second
+
+	dependencyUp   = "up"
+	dependencyDown = "down"
+)
+
+// Pinger is implemented by the stores that depend on a database.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DependencyCheck tells whether Name, a service the server needs to answer requests, is reachable.
+type DependencyCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// dependencyStatus only tells whether the dependency is up: the probes are unauthenticated, so
+// the error of a failed check, which may name hosts or credentials, is logged instead.
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// NewHealthHandler serves the probes of the server, outside the API versions and its
+// authentication: LivenessPath answers as long as the process runs, ReadinessPath only while
+// every dependency check passes. Like the API router, it expects gin to be in release mode.
+func NewHealthHandler(checks ...DependencyCheck) http.Handler {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET(LivenessPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive"})
+	})
+	router.GET(ReadinessPath, readinessHandler(checks))
+	return router
+}
+
+// readinessHandler runs the checks concurrently and answers 503 Service Unavailable when any
+// of them fails, so load balancers stop sending traffic until the dependency is back. Probes
+// carry no request ID, failures are logged through the global logger.
+func readinessHandler(checks []DependencyCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response := readinessResponse{Status: "ready", Dependencies: make(map[string]dependencyStatus, len(checks))}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status, err := runCheck(c.Request.Context(), check)
+				if err != nil {
+					log.Warn().Err(err).Str("dependency", check.Name).Msg("Dependency check failed")
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				response.Dependencies[check.Name] = status
+				if status.Status == dependencyDown {
+					response.Status = "not_ready"
+				}
+			}()
+		}
+		wg.Wait()
+
+		if response.Status != "ready" {
+			log.Warn().Interface("dependencies", response.Dependencies).Msg("Server is not ready")
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func runCheck(ctx context.Context, check DependencyCheck) (dependencyStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	status := dependencyStatus{Status: dependencyUp, LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		status.Status = dependencyDown
+	}
+	return status, err
+}
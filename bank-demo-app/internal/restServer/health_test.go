@@ -0,0 +1,76 @@
+package restServer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkReturning(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+func TestLivenessDoesNotCheckDependencies(t *testing.T) {
+	health := NewHealthHandler(DependencyCheck{Name: "mongo", Check: checkReturning(errors.New("connection refused"))})
+
+	w := serve(health, http.MethodGet, LivenessPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "alive"}`, w.Body.String())
+}
+
+func TestReadinessReportsEachDependency(t *testing.T) {
+	database := errors.New("server selection timeout")
+	health := NewHealthHandler(
+		DependencyCheck{Name: "mongo", Check: func(context.Context) error { return database }},
+		DependencyCheck{Name: "cache", Check: checkReturning(nil)},
+	)
+
+	logs := captureLogs(t)
+
+	var response readinessResponse
+	w := serve(health, http.MethodGet, ReadinessPath, "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "not_ready", response.Status)
+	assert.Equal(t, dependencyDown, response.Dependencies["mongo"].Status)
+	assert.Equal(t, dependencyUp, response.Dependencies["cache"].Status)
+	assert.NotContains(t, w.Body.String(), "server selection timeout", "errors are logged, not served")
+
+	var logged bool
+	for _, line := range logs() {
+		if line["dependency"] == "mongo" && line["error"] == "server selection timeout" {
+			logged = true
+		}
+	}
+	assert.True(t, logged, "the error of the failed check is logged")
+
+	database = nil
+	w = serve(health, http.MethodGet, ReadinessPath, "")
+	assert.Equal(t, http.StatusOK, w.Code, "ready again once the database is back")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ready", response.Status)
+}
+
+func TestReadinessChecksHaveADeadline(t *testing.T) {
+	health := NewHealthHandler(DependencyCheck{Name: "mongo", Check: func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		if !hasDeadline {
+			return errors.New("no deadline")
+		}
+		return nil
+	}})
+
+	w := serve(health, http.MethodGet, ReadinessPath, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestReadyWithoutDependencies(t *testing.T) {
+	w := serve(NewHealthHandler(), http.MethodGet, ReadinessPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ready", "dependencies": {}}`, w.Body.String())
+}
@@ -0,0 +1,298 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/tracing"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metrics are the series the server exposes on /metrics: HTTP traffic, store latency and
+// business volumes.
+type Metrics struct {
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	storeDuration       *prometheus.HistogramVec
+	transactions        *prometheus.CounterVec
+	transfers           prometheus.Counter
+	transferVolume      prometheus.Counter
+	failedTransfers     *prometheus.CounterVec
+}
+
+// NewMetrics registers the server metrics in registerer.
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bank_http_requests_total",
+			Help: "HTTP requests served, by method, route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "bank_http_request_duration_seconds",
+			Help: "Time to serve HTTP requests, by method, route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "bank_store_operation_duration_seconds",
+			Help: "Time spent in BankStore operations, by backend and operation.",
+		}, []string{"backend", "operation"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bank_transactions_total",
+			Help: "Deposits and withdrawals recorded, by type.",
+		}, []string{"type"}),
+		transfers: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bank_transfers_total",
+			Help: "Transfers completed.",
+		}),
+		transferVolume: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bank_transfer_volume_total",
+			Help: "Amount moved by completed transfers.",
+		}),
+		failedTransfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bank_transfers_failed_total",
+			Help: "Transfers that failed, by the error code they were answered with.",
+		}, []string{"reason"}),
+	}
+	registerer.MustRegister(m.httpRequests, m.httpRequestDuration, m.storeDuration,
+		m.transactions, m.transfers, m.transferVolume, m.failedTransfers)
+	return m
+}
+
+// WithMetrics records the HTTP requests of every route in m.
+func WithMetrics(m *Metrics) RouteOption {
+	return func(config *routeConfig) {
+		config.metrics = m
+	}
+}
+
+// metricsMiddleware counts the request and its latency once the rest of the chain has run.
+// The route label is the matched pattern, so the set of series stays bounded.
+func metricsMiddleware(m *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		labels := []string{c.Request.Method, c.FullPath(), strconv.Itoa(c.Writer.Status())}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) recordTransaction(transactionType string) {
+	m.transactions.WithLabelValues(transactionType).Inc()
+}
+
+func (m *Metrics) recordTransfer(amount float64, err error) {
+	if err != nil {
+		_, code := translateError(err)
+		m.failedTransfers.WithLabelValues(code).Inc()
+		return
+	}
+	m.transfers.Inc()
+	m.transferVolume.Add(amount)
+}
+
+// recordBatch counts the operations of a batch as if they had been requested one by one.
+func (m *Metrics) recordBatch(batch bank.Batch, result *bank.BatchResult, err error) {
+	var operationErr *bank.BatchOperationError
+	if errors.As(err, &operationErr) && batch.Operations[operationErr.Index].Type == bank.BatchTransfer {
+		m.recordTransfer(0, operationErr.Err)
+	}
+	if err != nil {
+		return
+	}
+
+	for _, operationResult := range result.Results {
+		operation := batch.Operations[operationResult.Index]
+		switch {
+		case operation.Type == bank.BatchTransfer:
+			m.recordTransfer(operation.Amount, operationResult.Err)
+		case operationResult.Err == nil:
+			m.recordTransaction(operation.Type)
+		}
+	}
+}
+
+// instrumentedStore times every operation of store and counts the business events it sees.
+type instrumentedStore struct {
+	store   BankStore
+	metrics *Metrics
+	backend string
+}
+
+// InstrumentStore records the latency of the operations of store, labeled with backend, and
+// the transactions and transfers made through it. Each operation is also traced as a span.
+func InstrumentStore(store BankStore, m *Metrics, backend string) BankStore {
+	return &instrumentedStore{store: store, metrics: m, backend: backend}
+}
+
+// observe runs call in a span of its own and records how long it took.
+func observe[T any](ctx context.Context, s *instrumentedStore, operation string, call func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "BankStore."+operation, trace.WithAttributes(attribute.String("bank.store.backend", s.backend)))
+	defer span.End()
+
+	start := time.Now()
+	result, err := call(ctx)
+	s.metrics.storeDuration.WithLabelValues(s.backend, operation).Observe(time.Since(start).Seconds())
+	tracing.SetError(span, err)
+	return result, err
+}
+
+func (s *instrumentedStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	return observe(ctx, s, "CreateAccount", func(ctx context.Context) (*bank.Account, error) {
+		return s.store.CreateAccount(ctx, owner, initialBalance)
+	})
+}
+
+func (s *instrumentedStore) GetAccountByID(ctx context.Context, id string) (*bank.Account, error) {
+	return observe(ctx, s, "GetAccountByID", func(ctx context.Context) (*bank.Account, error) {
+		return s.store.GetAccountByID(ctx, id)
+	})
+}
+
+func (s *instrumentedStore) ListAccounts(ctx context.Context, filter bank.AccountFilter, page bank.PageRequest) (*bank.AccountPage, error) {
+	return observe(ctx, s, "ListAccounts", func(ctx context.Context) (*bank.AccountPage, error) {
+		return s.store.ListAccounts(ctx, filter, page)
+	})
+}
+
+func (s *instrumentedStore) UpdateAccount(ctx context.Context, id string, update bank.AccountUpdate) (*bank.Account, error) {
+	return observe(ctx, s, "UpdateAccount", func(ctx context.Context) (*bank.Account, error) {
+		return s.store.UpdateAccount(ctx, id, update)
+	})
+}
+
+func (s *instrumentedStore) CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error) {
+	closure, err := observe(ctx, s, "CloseAccount", func(ctx context.Context) (*bank.AccountClosure, error) {
+		return s.store.CloseAccount(ctx, id, payoutAccountID)
+	})
+	if err == nil && closure.Withdrawal != nil {
+		s.metrics.recordTransaction(closure.Withdrawal.Type)
+		s.metrics.recordTransaction(closure.Deposit.Type)
+	}
+	return closure, err
+}
+
+func (s *instrumentedStore) PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	transaction, err := observe(ctx, s, "PerformTransaction", func(ctx context.Context) (*bank.Transaction, error) {
+		return s.store.PerformTransaction(ctx, accountID, txType, amount, reference)
+	})
+	if err == nil {
+		s.metrics.recordTransaction(transaction.Type)
+	}
+	return transaction, err
+}
+
+func (s *instrumentedStore) GetTransactionByID(ctx context.Context, id string) (*bank.Transaction, error) {
+	return observe(ctx, s, "GetTransactionByID", func(ctx context.Context) (*bank.Transaction, error) {
+		return s.store.GetTransactionByID(ctx, id)
+	})
+}
+
+func (s *instrumentedStore) GetTransactionsByAccountID(ctx context.Context, accountID string, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionPage, error) {
+	return observe(ctx, s, "GetTransactionsByAccountID", func(ctx context.Context) (*bank.TransactionPage, error) {
+		return s.store.GetTransactionsByAccountID(ctx, accountID, filter, page)
+	})
+}
+
+func (s *instrumentedStore) SearchTransactions(ctx context.Context, filter bank.TransactionFilter, page bank.PageRequest) (*bank.TransactionSearchPage, error) {
+	return observe(ctx, s, "SearchTransactions", func(ctx context.Context) (*bank.TransactionSearchPage, error) {
+		return s.store.SearchTransactions(ctx, filter, page)
+	})
+}
+
+func (s *instrumentedStore) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	_, err := observe(ctx, s, "TransferFunds", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.store.TransferFunds(ctx, fromAccountID, toAccountID, amount)
+	})
+	s.metrics.recordTransfer(amount, err)
+	return err
+}
+
+func (s *instrumentedStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	result, err := observe(ctx, s, "ExecuteBatch", func(ctx context.Context) (*bank.BatchResult, error) {
+		return s.store.ExecuteBatch(ctx, batch)
+	})
+	s.metrics.recordBatch(batch, result, err)
+	return result, err
+}
+
+func (s *instrumentedStore) CreateAPIKey(ctx context.Context, key bank.APIKey) (*bank.APIKey, error) {
+	return observe(ctx, s, "CreateAPIKey", func(ctx context.Context) (*bank.APIKey, error) {
+		return s.store.CreateAPIKey(ctx, key)
+	})
+}
+
+func (s *instrumentedStore) GetAPIKeyByHash(ctx context.Context, hash string) (*bank.APIKey, error) {
+	return observe(ctx, s, "GetAPIKeyByHash", func(ctx context.Context) (*bank.APIKey, error) {
+		return s.store.GetAPIKeyByHash(ctx, hash)
+	})
+}
+
+func (s *instrumentedStore) ListAPIKeys(ctx context.Context) ([]bank.APIKey, error) {
+	return observe(ctx, s, "ListAPIKeys", func(ctx context.Context) ([]bank.APIKey, error) {
+		return s.store.ListAPIKeys(ctx)
+	})
+}
+
+func (s *instrumentedStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (*bank.APIKey, error) {
+	return observe(ctx, s, "RevokeAPIKey", func(ctx context.Context) (*bank.APIKey, error) {
+		return s.store.RevokeAPIKey(ctx, id, revokedAt)
+	})
+}
+
+func (s *instrumentedStore) CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error) {
+	return observe(ctx, s, "CreateWebhook", func(ctx context.Context) (*bank.Webhook, error) {
+		return s.store.CreateWebhook(ctx, webhook)
+	})
+}
+
+func (s *instrumentedStore) GetWebhook(ctx context.Context, id string) (*bank.Webhook, error) {
+	return observe(ctx, s, "GetWebhook", func(ctx context.Context) (*bank.Webhook, error) {
+		return s.store.GetWebhook(ctx, id)
+	})
+}
+
+func (s *instrumentedStore) ListWebhooks(ctx context.Context) ([]bank.Webhook, error) {
+	return observe(ctx, s, "ListWebhooks", func(ctx context.Context) ([]bank.Webhook, error) {
+		return s.store.ListWebhooks(ctx)
+	})
+}
+
+func (s *instrumentedStore) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := observe(ctx, s, "DeleteWebhook", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.store.DeleteWebhook(ctx, id)
+	})
+	return err
+}
+
+func (s *instrumentedStore) EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error {
+	_, err := observe(ctx, s, "EnqueueWebhookDeliveries", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.store.EnqueueWebhookDeliveries(ctx, deliveries)
+	})
+	return err
+}
+
+func (s *instrumentedStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error) {
+	return observe(ctx, s, "ClaimWebhookDeliveries", func(ctx context.Context) ([]bank.WebhookDelivery, error) {
+		return s.store.ClaimWebhookDeliveries(ctx, now, limit, lease)
+	})
+}
+
+func (s *instrumentedStore) UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error {
+	_, err := observe(ctx, s, "UpdateWebhookDelivery", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.store.UpdateWebhookDelivery(ctx, delivery)
+	})
+	return err
+}
+
+func (s *instrumentedStore) ListWebhookDeliveries(ctx context.Context, webhookID string, filter bank.WebhookDeliveryFilter) ([]bank.WebhookDelivery, error) {
+	return observe(ctx, s, "ListWebhookDeliveries", func(ctx context.Context) ([]bank.WebhookDelivery, error) {
+		return s.store.ListWebhookDeliveries(ctx, webhookID, filter)
+	})
+}
@@ -0,0 +1,77 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank/memoryBank"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeMetrics(t *testing.T, registry *prometheus.Registry) string {
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetricsAreScraped(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	serverMetrics := NewMetrics(registry)
+	store := InstrumentStore(memoryBank.NewBankStore(), serverMetrics, "memory")
+	router := withAPIKey(t, store, NewRouter(InitRestRoutes(store, WithMetrics(serverMetrics))))
+
+	from, err := store.CreateAccount(ctx, "Alex Camara", 100)
+	require.NoError(t, err)
+	to, err := store.CreateAccount(ctx, "Sam Rivera", 0)
+	require.NoError(t, err)
+
+	w := serve(router, http.MethodPost, "/v1/accounts/"+to.ID+"/transactions", `{"type": "deposit", "amount": 5}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = serve(router, http.MethodPost, "/v1/transfer", fmt.Sprintf(`{"from_account_id": %q, "to_account_id": %q, "amount": 30}`, from.ID, to.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = serve(router, http.MethodPost, "/v1/transfer", fmt.Sprintf(`{"from_account_id": %q, "to_account_id": %q, "amount": 500}`, from.ID, to.ID))
+	require.Equal(t, http.StatusConflict, w.Code)
+	w = serve(router, http.MethodPost, "/v1/batches", fmt.Sprintf(`{"operations": [
+		{"type": "withdrawal", "account_id": %q, "amount": 1},
+		{"type": "transfer", "from_account_id": %q, "to_account_id": %q, "amount": 10}
+	]}`, to.ID, from.ID, to.ID))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serve(router, http.MethodGet, "/v1/accounts/unknown", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(router, http.MethodGet, "/accounts/unknown", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	output := scrapeMetrics(t, registry)
+	for _, line := range []string{
+		`bank_http_requests_total{method="POST",route="/v1/transfer",status="200"} 1`,
+		`bank_http_requests_total{method="POST",route="/v1/transfer",status="409"} 1`,
+		`bank_http_requests_total{method="GET",route="/v1/accounts/:id",status="404"} 1`,
+		`bank_http_requests_total{method="GET",route="/accounts/:id",status="404"} 1`,
+		`bank_http_request_duration_seconds_count{method="POST",route="/v1/accounts/:id/transactions",status="201"} 1`,
+		`bank_store_operation_duration_seconds_count{backend="memory",operation="CreateAccount"} 2`,
+		`bank_store_operation_duration_seconds_count{backend="memory",operation="TransferFunds"} 2`,
+		`bank_transactions_total{type="deposit"} 1`,
+		`bank_transactions_total{type="withdrawal"} 1`,
+		`bank_transfers_total 2`,
+		`bank_transfer_volume_total 40`,
+		`bank_transfers_failed_total{reason="insufficient_funds"} 1`,
+	} {
+		assert.Contains(t, output, line+"\n")
+	}
+}
+
+func TestRejectedRequestsAreCounted(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := NewRouter(InitRestRoutes(memoryBank.NewBankStore(), WithMetrics(NewMetrics(registry))))
+
+	w := serve(router, http.MethodGet, "/v1/accounts", "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Contains(t, scrapeMetrics(t, registry), `bank_http_requests_total{method="GET",route="/v1/accounts",status="401"} 1`)
+}
@@ -0,0 +1,143 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/tracing"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+
+	// maxRequestIDLength caps client supplied IDs so they can't flood logs.
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware propagates the caller's X-Request-ID, or assigns a new one, and echoes it
+// back so clients can quote it when reporting a failed request. It also puts a logger tagged
+// with the ID in the request context, which the handlers and stores log through, so every line
+// of one request can be found by its ID.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+
+		logContext := log.With().Str(requestIDKey, id)
+		span := trace.SpanFromContext(c.Request.Context())
+		if sc := span.SpanContext(); sc.IsValid() {
+			span.SetAttributes(attribute.String(requestIDKey, id))
+			logContext = logContext.Str("trace_id", sc.TraceID().String())
+		}
+		logger := logContext.Logger()
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// tracingMiddleware opens the server span of the request, continuing the trace of the caller
+// when it sent a valid traceparent header, and ends it once the response is written.
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+		ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		span.SetAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", c.FullPath()),
+			attribute.String("url.path", c.Request.URL.Path),
+		)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// requestID returns the ID assigned to the current request by requestIDMiddleware.
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// requestLog returns the logger of the current request, see requestIDMiddleware.
+func requestLog(c *gin.Context) *zerolog.Logger {
+	return zerolog.Ctx(c.Request.Context())
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// accessLogMiddleware logs one line per request once it has been served. The route is the
+// pattern that matched, such as /v1/accounts/:id, so lines of one endpoint can be grouped.
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := zerolog.InfoLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = zerolog.ErrorLevel
+		case status >= http.StatusBadRequest:
+			level = zerolog.WarnLevel
+		}
+		requestLog(c).WithLevel(level).
+			Str("method", c.Request.Method).
+			Str("route", c.FullPath()).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Int("bytes", max(c.Writer.Size(), 0)).
+			Str("client_ip", c.ClientIP()).
+			Msg("Request served")
+	}
+}
+
+// recoverPanic logs a handler panic with its stack to the request log and answers with a
+// problem document, like any other internal error. A handler that already started its response
+// can't be answered anymore, the response is only cut short.
+func recoverPanic(c *gin.Context, recovered any) {
+	requestLog(c).Error().Interface("panic", recovered).Bytes("stack", debug.Stack()).Msg("Handler panicked")
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	writeError(c, fmt.Errorf("handler panicked: %v", recovered))
+}
@@ -0,0 +1,211 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank/memoryBank"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// captureLogs sends the global logger output to the returned function, one map per line.
+func captureLogs(t *testing.T) func() []map[string]any {
+	var buffer bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buffer)
+	t.Cleanup(func() { log.Logger = previous })
+
+	return func() []map[string]any {
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &fields), line)
+			lines = append(lines, fields)
+		}
+		return lines
+	}
+}
+
+func TestRequestLinesShareTheRequestID(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+	logs := captureLogs(t)
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/accounts", strings.NewReader(`{"owner": "Alex Camara", "initial_balance": 10}`))
+	req.Header.Set(requestIDHeader, "test-request-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	lines := logs()
+	require.Len(t, lines, 3, "creating, created and the access log")
+	for _, line := range lines {
+		assert.Equal(t, "test-request-id", line[requestIDKey], line["message"])
+	}
+
+	access := lines[2]
+	assert.Equal(t, "Request served", access["message"])
+	assert.Equal(t, "info", access["level"])
+	assert.Equal(t, http.MethodPost, access["method"])
+	assert.Equal(t, "/v1/accounts", access["route"])
+	assert.Equal(t, float64(http.StatusCreated), access["status"])
+	assert.Equal(t, float64(w.Body.Len()), access["bytes"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestAccessLogRecordsFailures(t *testing.T) {
+	router := NewVersionedRouter(APIVersion{Name: "v1", Routes: Routes{{
+		Method:  http.MethodGet,
+		Pattern: "/panic/:id",
+		Handler: func(c *gin.Context) { panic("handler bug") },
+	}}})
+	logs := captureLogs(t)
+
+	w := serve(router, http.MethodGet, "/v1/panic/1", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"), "panics answer with a problem too")
+	w = serve(router, http.MethodGet, "/v1/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var access []map[string]any
+	for _, line := range logs() {
+		if line["message"] == "Request served" {
+			access = append(access, line)
+		}
+	}
+	require.Len(t, access, 2)
+	assert.Equal(t, "error", access[0]["level"])
+	assert.Equal(t, "/v1/panic/:id", access[0]["route"], "the route is the pattern, not the path")
+	assert.Equal(t, "/v1/panic/1", access[0]["path"])
+	assert.Equal(t, "warn", access[1]["level"])
+	assert.Equal(t, "", access[1]["route"], "no route matched")
+}
+
+func TestRecoverPanicLogsTheStackWithTheRequestID(t *testing.T) {
+	router := NewVersionedRouter(APIVersion{Name: "v1", Routes: Routes{
+		{Method: http.MethodGet, Pattern: "/panic", Handler: func(c *gin.Context) { panic("handler bug") }},
+		{Method: http.MethodGet, Pattern: "/partial", Handler: func(c *gin.Context) {
+			c.String(http.StatusOK, "half")
+			panic("handler bug")
+		}},
+	}})
+	logs := captureLogs(t)
+
+	w := serve(router, http.MethodGet, "/v1/panic", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, internalErrorCode, problem.Code)
+	assert.NotContains(t, problem.Detail, "handler bug", "the panic value stays in the logs")
+	assert.Equal(t, w.Header().Get(requestIDHeader), problem.RequestID)
+
+	w = serve(router, http.MethodGet, "/v1/partial", "")
+	assert.Equal(t, "half", w.Body.String(), "a started response isn't followed by a problem")
+
+	var panics []map[string]any
+	for _, line := range logs() {
+		if line["message"] == "Handler panicked" {
+			panics = append(panics, line)
+		}
+	}
+	require.Len(t, panics, 2)
+	assert.Equal(t, problem.RequestID, panics[0]["request_id"])
+	assert.Equal(t, "handler bug", panics[0]["panic"])
+	assert.Contains(t, panics[0]["stack"], "runtime/debug.Stack")
+}
+
+// recordSpans installs a tracer provider keeping the ended spans in memory for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return recorder
+}
+
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]any {
+	attributes := make(map[string]any)
+	for _, kv := range span.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attributes
+}
+
+func TestRequestsAreTraced(t *testing.T) {
+	store := InstrumentStore(memoryBank.NewBankStore(), NewMetrics(prometheus.NewRegistry()), "memory")
+	router := newTestRouter(t, store)
+	account, err := store.CreateAccount(ctx, "Alex Camara", 10)
+	require.NoError(t, err)
+
+	recorder := recordSpans(t)
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/accounts/"+account.ID+"/transactions", strings.NewReader(`{"type": "deposit", "amount": 5}`))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	spans := spansByName(recorder)
+	server, found := spans["POST /v1/accounts/:id/transactions"]
+	require.True(t, found, "spans: %v", spans)
+	attributes := spanAttributes(server)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String(), "the caller's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, "/v1/accounts/:id/transactions", attributes["http.route"])
+	assert.Equal(t, int64(http.StatusCreated), attributes["http.response.status_code"])
+	assert.Equal(t, w.Header().Get(requestIDHeader), attributes[requestIDKey])
+
+	for _, name := range []string{"validate request body", "BankStore.GetAPIKeyByHash", "BankStore.PerformTransaction"} {
+		span, found := spans[name]
+		if assert.True(t, found, name) {
+			assert.Equal(t, server.SpanContext().TraceID(), span.SpanContext().TraceID(), name)
+			assert.Equal(t, server.SpanContext().SpanID(), span.Parent().SpanID(), name)
+		}
+	}
+	assert.Equal(t, "memory", spanAttributes(spans["BankStore.PerformTransaction"])["bank.store.backend"])
+}
+
+func TestServerErrorsFailTheSpan(t *testing.T) {
+	router := NewVersionedRouter(APIVersion{Name: "v1", Routes: Routes{{
+		Method:  http.MethodGet,
+		Pattern: "/panic",
+		Handler: func(c *gin.Context) { panic("handler bug") },
+	}}})
+	recorder := recordSpans(t)
+	captureLogs(t)
+
+	serve(router, http.MethodGet, "/v1/panic", "")
+
+	span, found := spansByName(recorder)["GET /v1/panic"]
+	require.True(t, found)
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.True(t, span.SpanContext().TraceID().IsValid(), "a new trace starts without traceparent")
+	assert.False(t, span.Parent().IsValid())
+}
@@ -0,0 +1,295 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/tracing"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	openAPIPath    = "/openapi.json"
+	openAPIVersion = "3.0.3"
+
+	jsonContentType = "application/json"
+	csvContentType  = "text/csv"
+)
+
+// OpenAPIDocument is the OpenAPI 3.0 description of the API, generated from the route table and
+// the request and response structs so it can't fall behind the code.
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Servers    []Server                         `json:"servers"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is the base path the document's paths are relative to.
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security lists the accepted ways to send an API key, Scopes the scopes the key must grant.
+	Security []map[string][]string `json:"security,omitempty"`
+	Scopes   []string              `json:"x-scopes,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// securitySchemes are the two equivalent ways of sending an API key and the customer tokens
+// accepted on some routes, see authMiddleware.
+var securitySchemes = map[string]SecurityScheme{
+	"bearerAuth":   {Type: "http", Scheme: "bearer", Description: "API key sent as a bearer token."},
+	"apiKeyAuth":   {Type: "apiKey", In: "header", Name: apiKeyHeader, Description: "API key sent in the X-API-Key header."},
+	"customerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Customer token, only grants access to the accounts of its owner."},
+}
+
+// queryParameters describes every query parameter a route can list in Route.Query.
+var queryParameters = map[string]Parameter{
+	"limit":             {Description: "Page size, 1 to 500. Defaults to 50.", Schema: &Schema{Type: "integer"}},
+	"cursor":            {Description: "Cursor of the next page, taken from the X-Next-Cursor header.", Schema: &Schema{Type: "string"}},
+	"sort":              {Description: "Field to sort by.", Schema: &Schema{Type: "string"}},
+	"order":             {Description: "Sort order, asc or desc.", Schema: &Schema{Type: "string"}},
+	"owner":             {Description: "Exact owner name.", Schema: &Schema{Type: "string"}},
+	"min_balance":       {Description: "Inclusive lower bound of the balance.", Schema: &Schema{Type: "number", Format: "double"}},
+	"max_balance":       {Description: "Inclusive upper bound of the balance.", Schema: &Schema{Type: "number", Format: "double"}},
+	"type":              {Description: "Transaction type, deposit or withdrawal.", Schema: &Schema{Type: "string"}},
+	"min_amount":        {Description: "Inclusive lower bound of the amount.", Schema: &Schema{Type: "number", Format: "double"}},
+	"max_amount":        {Description: "Inclusive upper bound of the amount.", Schema: &Schema{Type: "number", Format: "double"}},
+	"from":              {Description: "Inclusive start of the time range.", Schema: &Schema{Type: "string", Format: "date-time"}},
+	"to":                {Description: "Exclusive end of the time range.", Schema: &Schema{Type: "string", Format: "date-time"}},
+	"reference":         {Description: "Case-insensitive substring of the transaction reference.", Schema: &Schema{Type: "string"}},
+	"account_id":        {Description: "Account IDs, repeated or comma separated.", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+	"payout_account_id": {Description: "Open account that receives the remaining balance.", Schema: &Schema{Type: "string"}},
+	"dry_run":           {Description: "Only validate the file.", Schema: &Schema{Type: "boolean"}},
+	"status":            {Description: "Webhook delivery status, pending, succeeded or dead.", Schema: &Schema{Type: "string"}},
+}
+
+// apiSpec holds the generated document and the request schemas used to validate bodies.
+type apiSpec struct {
+	document       *OpenAPIDocument
+	registry       *schemaRegistry
+	requestSchemas map[string]*Schema
+}
+
+func newAPISpec(version APIVersion) *apiSpec {
+	documentVersion := version.Name
+	if documentVersion == "" {
+		documentVersion = "unversioned"
+	}
+	spec := &apiSpec{
+		document: &OpenAPIDocument{
+			OpenAPI: openAPIVersion,
+			Info:    OpenAPIInfo{Title: "Bank demo API", Version: documentVersion},
+			Servers: []Server{{URL: version.basePath()}},
+			Paths:   make(map[string]map[string]*Operation),
+		},
+		registry:       newSchemaRegistry(),
+		requestSchemas: make(map[string]*Schema),
+	}
+	// Every error is a problem document.
+	spec.registry.schemaFor(Problem{})
+
+	for _, route := range version.Routes {
+		path := openAPIPathTemplate(route.Pattern)
+		if spec.document.Paths[path] == nil {
+			spec.document.Paths[path] = make(map[string]*Operation)
+		}
+		operation := spec.operation(route)
+		operation.Deprecated = !version.DeprecatedAt.IsZero()
+		spec.document.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	spec.document.Components.Schemas = spec.registry.components
+	spec.document.Components.SecuritySchemes = securitySchemes
+	return spec
+}
+
+func (spec *apiSpec) operation(route Route) *Operation {
+	operation := &Operation{
+		Summary:    route.Summary,
+		Parameters: pathParameters(route.Pattern),
+		Responses:  make(map[string]*Response),
+	}
+
+	for _, name := range route.Query {
+		parameter, known := queryParameters[name]
+		if !known {
+			panic(fmt.Sprintf("openapi: route %s %s reads undocumented query parameter %q", route.Method, route.Pattern, name))
+		}
+		parameter.Name = name
+		parameter.In = "query"
+		if parameter.Schema.Type == "array" {
+			explode := true
+			parameter.Explode = &explode
+		}
+		operation.Parameters = append(operation.Parameters, parameter)
+	}
+
+	if route.Request != nil {
+		schema := spec.registry.schemaFor(route.Request)
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{requestContentType(route): {Schema: schema}},
+		}
+		if requestContentType(route) == jsonContentType {
+			spec.requestSchemas[routeKey(route)] = schema
+		}
+	}
+
+	if len(route.Scopes) > 0 {
+		operation.Security = []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}}
+		if route.Customers {
+			operation.Security = append(operation.Security, map[string][]string{"customerAuth": {}})
+		}
+		operation.Scopes = route.Scopes
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		success.Content = map[string]MediaType{jsonContentType: {Schema: spec.registry.schemaFor(route.Response)}}
+	}
+	operation.Responses[strconv.Itoa(status)] = success
+	operation.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]MediaType{problemContentType: {Schema: spec.registry.schemaFor(Problem{})}},
+	}
+
+	return operation
+}
+
+// middleware returns the handlers that run before route.Handler: a body validator for JSON requests.
+func (spec *apiSpec) middleware(route Route) []gin.HandlerFunc {
+	schema, validated := spec.requestSchemas[routeKey(route)]
+	if !validated {
+		return nil
+	}
+	return []gin.HandlerFunc{validateBodyMiddleware(spec.registry, schema)}
+}
+
+func (spec *apiSpec) serveDocument(c *gin.Context) {
+	c.JSON(http.StatusOK, spec.document)
+}
+
+// validateBodyMiddleware rejects bodies that don't match schema with invalid_request_body, naming
+// the offending field, and hands the body on untouched to the next handler.
+func validateBodyMiddleware(registry *schemaRegistry, schema *Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, span := tracing.Tracer().Start(c.Request.Context(), "validate request body")
+		err := validateBody(c, registry, schema)
+		tracing.SetError(span, err)
+		span.End()
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func validateBody(c *gin.Context, registry *schemaRegistry, schema *Schema) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+	if err := registry.validate(value, schema, ""); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+	return nil
+}
+
+func requestContentType(route Route) string {
+	if route.RequestContentType == "" {
+		return jsonContentType
+	}
+	return route.RequestContentType
+}
+
+func routeKey(route Route) string {
+	return route.Method + " " + route.Pattern
+}
+
+// openAPIPathTemplate turns gin parameters into OpenAPI ones: /accounts/:id becomes /accounts/{id}.
+func openAPIPathTemplate(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if name, isParameter := strings.CutPrefix(segment, ":"); isParameter {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParameters(pattern string) []Parameter {
+	var parameters []Parameter
+	for _, segment := range strings.Split(pattern, "/") {
+		if name, isParameter := strings.CutPrefix(segment, ":"); isParameter {
+			parameters = append(parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return parameters
+}
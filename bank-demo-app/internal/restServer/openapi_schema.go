@@ -0,0 +1,268 @@
+package restServer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object needed to describe the API structs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaRegistry builds schemas from Go types. Named structs are registered once as components
+// and referenced everywhere else, so the document mirrors the request and response structs.
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schemaFor returns the schema of value's type, or nil when value is nil.
+func (r *schemaRegistry) schemaFor(value any) *Schema {
+	if value == nil {
+		return nil
+	}
+	return r.schemaOf(reflect.TypeOf(value))
+}
+
+func (r *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		// Raw JSON is embedded as is, only objects are stored that way.
+		return &Schema{Type: "object"}
+	case t.Kind() == reflect.Pointer:
+		return nullable(r.schemaOf(t.Elem()))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			// Anonymous structs have no name to register, describe them in place.
+			schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			r.addProperties(schema, t)
+			return schema
+		}
+		return r.structRef(t)
+	default:
+		panic(fmt.Sprintf("openapi: unsupported type %s", t))
+	}
+}
+
+// nullable marks schema as accepting null. References can't carry siblings in OpenAPI 3.0,
+// so nullable references are left as they are.
+func nullable(schema *Schema) *Schema {
+	if schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
+
+func (r *schemaRegistry) structRef(t reflect.Type) *Schema {
+	name, registered := r.names[t]
+	if !registered {
+		name = componentName(t)
+		if _, taken := r.components[name]; taken {
+			panic(fmt.Sprintf("openapi: two types are named %s", name))
+		}
+		r.names[t] = name
+		// Register before walking the fields so recursive types terminate.
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		r.components[name] = schema
+		r.addProperties(schema, t)
+	}
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// addProperties adds the JSON fields of t to schema, flattening embedded structs like encoding/json does.
+func (r *schemaRegistry) addProperties(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			r.addProperties(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		schema.Properties[name] = r.schemaOf(field.Type)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+// componentName exports the Go type name, so createAccountRequest becomes CreateAccountRequest.
+func componentName(t reflect.Type) string {
+	runes := []rune(t.Name())
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// validate checks a decoded JSON value against schema and returns the path and reason of the
+// first mismatch. Properties that aren't in the schema are ignored, as encoding/json does.
+func (r *schemaRegistry) validate(value any, schema *Schema, path string) error {
+	if schema.Ref != "" {
+		return r.validate(value, r.components[strings.TrimPrefix(schema.Ref, schemaRefPrefix)], path)
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return schemaMismatch(path, schema, value)
+	}
+
+	switch schema.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return schemaMismatch(path, schema, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return schemaMismatch(path, schema, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return schemaMismatch(path, schema, value)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return schemaMismatch(path, schema, value)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, value.(string)); err != nil {
+				return fmt.Errorf("%s: expected an RFC 3339 date-time", displayPath(path))
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return schemaMismatch(path, schema, value)
+		}
+		for i, item := range items {
+			if err := r.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return schemaMismatch(path, schema, value)
+		}
+		return r.validateObject(object, schema, path)
+	}
+	return nil
+}
+
+func (r *schemaRegistry) validateObject(object map[string]any, schema *Schema, path string) error {
+	// Walk the properties in a fixed order so the same body always reports the same error.
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := object[name]
+		propertySchema := schema.Properties[name]
+		if propertySchema == nil {
+			propertySchema = schema.AdditionalProperties
+		}
+		if propertySchema == nil {
+			return fmt.Errorf("%s: unknown field", displayPath(joinPath(path, name)))
+		}
+		if err := r.validate(property, propertySchema, joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func schemaMismatch(path string, schema *Schema, value any) error {
+	return fmt.Errorf("%s: expected %s, got %s", displayPath(path), schema.Type, jsonTypeName(value))
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
@@ -0,0 +1,142 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank/memoryBank"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateOpenAPI = flag.Bool("update", false, "rewrite testdata/openapi.json from the route table")
+
+var openAPIGoldenFile = filepath.Join("testdata", "openapi.json")
+
+// TestOpenAPIDocumentIsUpToDate keeps the published spec in sync with the code. After changing a
+// route or an API struct, regenerate it with: go test ./internal/restServer -run OpenAPI -update
+func TestOpenAPIDocumentIsUpToDate(t *testing.T) {
+	document := newAPISpec(APIVersion{Name: CurrentAPIVersion, Routes: InitRestRoutes(memoryBank.NewBankStore())}).document
+	generated, err := json.MarshalIndent(document, "", "  ")
+	require.NoError(t, err)
+
+	if *updateOpenAPI {
+		require.NoError(t, os.WriteFile(openAPIGoldenFile, append(generated, '\n'), 0o644))
+	}
+
+	published, err := os.ReadFile(openAPIGoldenFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(published), string(generated), "%s is stale, regenerate it with -update", openAPIGoldenFile)
+}
+
+func TestOpenAPIDocumentCoversEveryRoute(t *testing.T) {
+	routes := InitRestRoutes(memoryBank.NewBankStore())
+	document := newAPISpec(APIVersion{Name: CurrentAPIVersion, Routes: routes}).document
+	router := NewVersionedRouter(APIVersion{Name: CurrentAPIVersion, Routes: routes})
+
+	documented := 0
+	for _, operations := range document.Paths {
+		documented += len(operations)
+	}
+
+	registered := 0
+	for _, route := range router.Routes() {
+		path := strings.TrimPrefix(route.Path, "/"+CurrentAPIVersion)
+		if path == openAPIPath {
+			continue
+		}
+		registered++
+		operation := document.Paths[openAPIPathTemplate(path)][strings.ToLower(route.Method)]
+		assert.NotNil(t, operation, "%s %s is not documented", route.Method, route.Path)
+	}
+	assert.Equal(t, registered, documented, "the document describes routes the router doesn't serve")
+
+	for _, route := range routes {
+		assert.NotEmpty(t, route.Summary, "%s %s has no summary", route.Method, route.Pattern)
+		switch route.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			assert.NotNil(t, route.Request, "%s %s doesn't declare its request body", route.Method, route.Pattern)
+		}
+	}
+}
+
+func TestServeOpenAPIDocument(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1"+openAPIPath, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var document OpenAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, openAPIVersion, document.OpenAPI)
+	assert.Equal(t, []Server{{URL: "/v1"}}, document.Servers)
+	assert.Contains(t, document.Paths, "/accounts/{id}/transactions")
+	assert.Contains(t, document.Components.Schemas, "CreateAccountRequest")
+	assert.Contains(t, document.Components.Schemas, "Problem")
+}
+
+func TestRequestBodyValidation(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedDetail string
+	}{
+		{"not json", "/accounts", `{"owner":`, "unexpected end of JSON input"},
+		{"not an object", "/accounts", `["Alex Camara"]`, "body: expected object, got array"},
+		{"string amount", "/accounts", `{"owner": "Alex Camara", "initial_balance": "100"}`, "initial_balance: expected number, got string"},
+		{"nested field", "/batches", `{"operations": [{"type": "deposit", "account_id": "1", "amount": 1}, {"type": 7}]}`, "operations[1].type: expected string, got number"},
+		{"account id type", "/transfer", `{"from_account_id": 1}`, "from_account_id: expected string, got number"},
+		{"null owner", "/accounts", `{"owner": null}`, "owner: expected string, got null"},
+		{"unknown field", "/accounts", `{"owner": "Alex Camara", "initial_balance": 10, "currency": "EUR"}`, "currency: unknown field"},
+		{"nested unknown field", "/batches", `{"operations": [{"type": "deposit", "account_id": "1", "amount": 1, "memo": "x"}]}`, "operations[0].memo: unknown field"},
+		{"trailing data", "/accounts", `{"owner": "Alex Camara"} {"owner": "John Doe"}`, "invalid character"},
+		{"NaN amount", "/transfer", `{"from_account_id": "1", "to_account_id": "2", "amount": NaN}`, "invalid character 'N'"},
+		{"infinite amount", "/transfer", `{"from_account_id": "1", "to_account_id": "2", "amount": 1e400}`, "cannot unmarshal number 1e400"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, test.path, bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, "invalid_request_body", problem.Code)
+			assert.Contains(t, problem.Detail, test.expectedDetail)
+		})
+	}
+}
+
+func TestRequestBodyValidationAcceptsValidBodies(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	// Null clears nullable fields, as with encoding/json.
+	req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBufferString(`{"owner": "Alex Camara", "initial_balance": 10}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var account struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+
+	req, _ = http.NewRequest(http.MethodPatch, "/accounts/"+account.ID, bytes.NewBufferString(`{"owner": null, "metadata": {"tier": "gold", "branch": null}}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,152 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// nextCursorHeader carries the cursor of the next page. It is empty on the last page.
+	nextCursorHeader = "X-Next-Cursor"
+	linkHeader       = "Link"
+)
+
+// errInvalidQueryParameter is returned when a query parameter can't be parsed.
+var errInvalidQueryParameter = errors.New("invalid query parameter")
+
+// parsePageRequest reads limit, cursor, sort and order. Range checks are left to the stores.
+func parsePageRequest(c *gin.Context) (bank.PageRequest, error) {
+	page := bank.PageRequest{
+		Cursor: c.Query("cursor"),
+		SortBy: c.Query("sort"),
+		Order:  bank.SortOrder(c.Query("order")),
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return page, invalidQueryParameterError("limit", value)
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+func parseWebhookDeliveryFilter(c *gin.Context) (bank.WebhookDeliveryFilter, error) {
+	filter := bank.WebhookDeliveryFilter{Status: c.Query("status")}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, invalidQueryParameterError("limit", value)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func parseAccountFilter(c *gin.Context) (bank.AccountFilter, error) {
+	filter := bank.AccountFilter{Owner: c.Query("owner")}
+
+	var err error
+	if filter.MinBalance, err = queryFloat(c, "min_balance"); err != nil {
+		return filter, err
+	}
+	if filter.MaxBalance, err = queryFloat(c, "max_balance"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseTransactionFilter(c *gin.Context) (bank.TransactionFilter, error) {
+	filter := bank.TransactionFilter{Type: c.Query("type"), Reference: c.Query("reference")}
+
+	var err error
+	if filter.MinAmount, err = queryFloat(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = queryFloat(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseTransactionSearchFilter adds the account_id filter, given either repeated or comma separated,
+// to the transaction filter.
+func parseTransactionSearchFilter(c *gin.Context) (bank.TransactionFilter, error) {
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		return filter, err
+	}
+
+	for _, value := range c.QueryArray("account_id") {
+		for _, accountID := range strings.Split(value, ",") {
+			filter.AccountIDs = append(filter.AccountIDs, strings.TrimSpace(accountID))
+		}
+	}
+
+	return filter, nil
+}
+
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	// ParseFloat accepts NaN and Inf, which no amount or balance can be compared with.
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil, invalidQueryParameterError(name, value)
+	}
+	return &number, nil
+}
+
+// queryTime parses RFC 3339 timestamps such as 2024-01-31T00:00:00Z.
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, invalidQueryParameterError(name, value)
+	}
+	return &timestamp, nil
+}
+
+func invalidQueryParameterError(name, value string) error {
+	return fmt.Errorf("%w: %s=%q", errInvalidQueryParameter, name, value)
+}
+
+// setPageHeaders advertises the next page both as a bare cursor and as an RFC 8288 link that
+// repeats the current query with the new cursor, so clients can follow it without rebuilding filters.
+func setPageHeaders(c *gin.Context, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	query := c.Request.URL.Query()
+	query.Set("cursor", nextCursor)
+	next := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+
+	c.Header(nextCursorHeader, nextCursor)
+	c.Header(linkHeader, fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
@@ -0,0 +1,82 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/rateLimit"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	retryAfterHeader         = "Retry-After"
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+)
+
+var errRateLimited = errors.New("too many requests")
+
+// WithRateLimiter limits how often each client may call the routes, as configured in limits.
+// Clients are told apart by API key, customer or, for anonymous requests, IP address. Each IP
+// address is limited on the authenticated routes as well, before its credentials are checked.
+func WithRateLimiter(limiter rateLimit.Limiter, limits *rateLimit.Config) RouteOption {
+	return func(config *routeConfig) {
+		config.limiter = limiter
+		config.limits = limits
+	}
+}
+
+// rateLimitMiddleware takes a token from the client bucket and rejects the request with
+// 429 Too Many Requests when it is empty. Requests pass when the limiter itself fails, as
+// refusing every client because of a limiter outage would be worse than a burst. key tells the
+// clients apart.
+func rateLimitMiddleware(limiter rateLimit.Limiter, bucket string, limit rateLimit.Limit, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := storeContext(c)
+		decision, err := limiter.Allow(ctx, bucket+"|"+key(c), limit)
+		cancel()
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("bucket", bucket).Msg("Rate limiter failed, letting the request through.")
+			c.Next()
+			return
+		}
+
+		c.Header(rateLimitLimitHeader, strconv.Itoa(decision.Limit))
+		c.Header(rateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+		c.Header(rateLimitResetHeader, strconv.Itoa(headerSeconds(decision.Reset)))
+		if !decision.Allowed {
+			retryAfter := max(headerSeconds(decision.RetryAfter), 1)
+			c.Header(retryAfterHeader, strconv.Itoa(retryAfter))
+			writeError(c, fmt.Errorf("%w: retry in %d seconds", errRateLimited, retryAfter))
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey identifies who sent the request, once authMiddleware ran.
+func clientKey(c *gin.Context) string {
+	if caller := currentCaller(c); caller != nil {
+		if caller.apiKey != nil {
+			return "api_key:" + caller.apiKey.ID
+		}
+		if caller.customer != nil {
+			return "customer:" + caller.customer.Subject
+		}
+	}
+	return ipKey(c)
+}
+
+// ipKey identifies the address the request came from, whoever it claims to be.
+func ipKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// headerSeconds rounds up, so a client waiting the advertised time always finds a token.
+func headerSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,109 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"bank-demo-app/internal/rateLimit"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseRateLimits(t *testing.T, file string) *rateLimit.Config {
+	limits, err := rateLimit.ParseConfig(strings.NewReader(file))
+	require.NoError(t, err)
+	return limits
+}
+
+func TestRateLimitsArePerClientAndRoute(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	limits := parseRateLimits(t, `{
+		"default": {"requests": 100, "per": "1m"},
+		"routes": {"POST /transfer": {"requests": 1, "per": "1h"}}
+	}`)
+	router := NewRouter(InitRestRoutes(store, WithRateLimiter(rateLimit.NewMemoryLimiter(), limits)))
+	first := createTestAPIKey(t, store, bank.ScopeAdmin)
+	second := createTestAPIKey(t, store, bank.ScopeAdmin)
+
+	from, err := store.CreateAccount(ctx, "Alex Camara", 100)
+	require.NoError(t, err)
+	to, err := store.CreateAccount(ctx, "Sam Rivera", 0)
+	require.NoError(t, err)
+	transfer := fmt.Sprintf(`{"from_account_id": %q, "to_account_id": %q, "amount": 1}`, from.ID, to.ID)
+
+	status, _, header := serveWithHeader(router, http.MethodPost, "/v1/transfer", transfer, "Authorization", bearerScheme+first)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "1", header.Get(rateLimitLimitHeader))
+	assert.Equal(t, "0", header.Get(rateLimitRemainingHeader))
+	assert.Equal(t, "3600", header.Get(rateLimitResetHeader))
+
+	status, problem, header := serveWithHeader(router, http.MethodPost, "/v1/transfer", transfer, "Authorization", bearerScheme+first)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, "rate_limited", problem.Code)
+	assert.Equal(t, "3600", header.Get(retryAfterHeader))
+	assert.Equal(t, 99.0, balance(t, store, from.ID), "rejected transfers don't run")
+
+	status, _, _ = serveWithHeader(router, http.MethodPost, "/transfer", transfer, "Authorization", bearerScheme+first)
+	assert.Equal(t, http.StatusTooManyRequests, status, "the deprecated alias shares the bucket")
+
+	status, _, _ = serveWithHeader(router, http.MethodPost, "/v1/transfer", transfer, "Authorization", bearerScheme+second)
+	assert.Equal(t, http.StatusOK, status, "each API key has its own bucket")
+
+	status, _, header = serveWithHeader(router, http.MethodGet, "/v1/accounts", "", "Authorization", bearerScheme+first)
+	assert.Equal(t, http.StatusOK, status, "other routes use the default limit")
+	assert.Equal(t, "100", header.Get(rateLimitLimitHeader))
+	assert.Equal(t, "99", header.Get(rateLimitRemainingHeader))
+}
+
+func TestAnonymousRequestsAreLimitedByConnectionIP(t *testing.T) {
+	limits := parseRateLimits(t, `{"default": {"requests": 1, "per": "1m"}}`)
+	router := NewRouter(InitRestRoutes(memoryBank.NewBankStore(), WithRateLimiter(rateLimit.NewMemoryLimiter(), limits)))
+
+	status, _, _ := serveWithHeader(router, http.MethodGet, "/v1/status", "", "X-Forwarded-For", "203.0.113.1")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, header := serveWithHeader(router, http.MethodGet, "/v1/status", "", "X-Forwarded-For", "203.0.113.2")
+	assert.Equal(t, http.StatusTooManyRequests, status, "X-Forwarded-For can't be used to get a fresh bucket")
+	assert.Equal(t, "60", header.Get(retryAfterHeader))
+}
+
+func TestRequestsWithBadCredentialsAreLimitedByIP(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	limits := parseRateLimits(t, `{"default": {"requests": 100, "per": "1m"}, "ip": {"requests": 2, "per": "1m"}}`)
+	router := NewRouter(InitRestRoutes(store, WithRateLimiter(rateLimit.NewMemoryLimiter(), limits)))
+
+	for range 2 {
+		status, problem, _ := serveWithHeader(router, http.MethodGet, "/v1/accounts", "", "Authorization", bearerScheme+"bk_wrong")
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.NotEqual(t, "rate_limited", problem.Code)
+	}
+
+	status, problem, header := serveWithHeader(router, http.MethodGet, "/v1/accounts", "", "Authorization", bearerScheme+"bk_wrong")
+	assert.Equal(t, http.StatusTooManyRequests, status, "the key is never checked once the IP is over its limit")
+	assert.Equal(t, "rate_limited", problem.Code)
+	assert.Equal(t, "30", header.Get(retryAfterHeader))
+
+	status, _, _ = serveWithHeader(router, http.MethodGet, "/v1/accounts", "", "Authorization", bearerScheme+createTestAPIKey(t, store, bank.ScopeAdmin))
+	assert.Equal(t, http.StatusTooManyRequests, status, "valid keys share the limit of their IP")
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, rateLimit.Limit) (rateLimit.Decision, error) {
+	return rateLimit.Decision{}, errors.New("connection refused")
+}
+
+func TestRequestsPassWhenTheLimiterFails(t *testing.T) {
+	limits := parseRateLimits(t, `{"default": {"requests": 1, "per": "1m"}}`)
+	router := NewRouter(InitRestRoutes(memoryBank.NewBankStore(), WithRateLimiter(failingLimiter{}, limits)))
+
+	w := serve(router, http.MethodGet, "/v1/status", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(rateLimitLimitHeader))
+}
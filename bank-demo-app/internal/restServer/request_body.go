@@ -0,0 +1,63 @@
+package restServer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodySize caps JSON request bodies when WithMaxBodySize isn't given. CSV imports have
+// their own, larger, limit.
+const DefaultMaxBodySize = 1 << 20
+
+// WithMaxBodySize rejects JSON request bodies larger than limit bytes with
+// request_body_too_large, before they are read.
+func WithMaxBodySize(limit int64) RouteOption {
+	return func(config *routeConfig) {
+		config.maxBodySize = limit
+	}
+}
+
+// limitBodyMiddleware answers 413 right away when the declared length is over limit, and
+// otherwise stops reading the body once limit is reached, so chunked bodies are bounded too.
+func limitBodyMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			writeError(c, fmt.Errorf("%w: %d bytes, the limit is %d", errRequestBodyTooLarge, c.Request.ContentLength, limit))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// bindJSON decodes the request body into request, strictly: unknown fields and anything after
+// the JSON value are rejected, rather than silently ignored like ShouldBindJSON does. NaN and
+// Infinity aren't JSON, and numbers overflowing a float64 fail to decode, so amounts are finite.
+func bindJSON(c *gin.Context, request any) error {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		return bodyError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err != nil {
+			return bodyError(err)
+		}
+		return fmt.Errorf("%w: unexpected data after the JSON value", errInvalidRequestBody)
+	}
+	return nil
+}
+
+// bodyError wraps an error met reading or decoding a request body in its request error.
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w: the limit is %d bytes", errRequestBodyTooLarge, tooLarge.Limit)
+	}
+	return fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+}
@@ -0,0 +1,57 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank/memoryBank"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBodiesAreSizeLimited(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	router := withAPIKey(t, store, NewRouter(InitRestRoutes(store, WithMaxBodySize(64))))
+	large := `{"owner": "` + strings.Repeat("a", 100) + `", "initial_balance": 10}`
+
+	w := serve(router, http.MethodPost, "/v1/accounts", large)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "request_body_too_large", problem.Code)
+
+	// Without a declared length the body is cut once the limit is read.
+	req, _ := http.NewRequest(http.MethodPost, "/v1/accounts", io.MultiReader(strings.NewReader(large)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = serve(router, http.MethodPost, "/v1/accounts", `{"owner": "Alex Camara"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// CSV imports keep their own limit.
+	w = serve(router, http.MethodPost, "/v1/imports?dry_run=true", "external_reference,owner,initial_balance\nlegacy-1,Alex Camara,10\nlegacy-2,John Doe,20\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBindJSONIsStrict(t *testing.T) {
+	bind := func(body string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		var request transferRequest
+		return bindJSON(c, &request)
+	}
+
+	assert.NoError(t, bind(`{"from_account_id": "1", "to_account_id": "2", "amount": 5}`+"\n"))
+	assert.ErrorContains(t, bind(`{"amount": 5, "currency": "EUR"}`), `unknown field "currency"`)
+	assert.ErrorContains(t, bind(`{"amount": 5} {"amount": 6}`), "unexpected data after the JSON value")
+	assert.ErrorContains(t, bind(`{"amount": 5} ]`), "invalid character")
+	assert.ErrorContains(t, bind(`{"amount": Infinity}`), "invalid character")
+	assert.ErrorIs(t, bind(``), errInvalidRequestBody)
+}
@@ -1,15 +1,36 @@
 package restServer
 
+import "bank-demo-app/internal/bank"
+
 // request struct for creating an account
 type createAccountRequest struct {
 	Owner          string  `json:"owner"`
 	InitialBalance float64 `json:"initial_balance"`
 }
 
+// request struct for replacing the owner and metadata of an account
+type replaceAccountRequest struct {
+	Owner    string            `json:"owner"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// request struct for partially updating an account, a null metadata value removes its key
+type updateAccountRequest struct {
+	Owner    *string            `json:"owner"`
+	Metadata map[string]*string `json:"metadata"`
+}
+
 // request struct for creating a transaction
 type createTransactionRequest struct {
-	Type   string  `json:"type"`
-	Amount float64 `json:"amount"`
+	Type      string  `json:"type"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+}
+
+// request struct for a batch of deposits, withdrawals and transfers
+type batchRequest struct {
+	Atomic     bool                  `json:"atomic"`
+	Operations []bank.BatchOperation `json:"operations"`
 }
 
 // request struct for transferring funds
@@ -18,3 +39,15 @@ type transferRequest struct {
 	ToAccountID   string  `json:"to_account_id"`
 	Amount        float64 `json:"amount"`
 }
+
+// request struct for creating an API key
+type createAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// request struct for subscribing a URL to webhook events
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
@@ -0,0 +1,120 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/csvImport"
+)
+
+// response struct for operations that only report their outcome
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// response struct for a cross-account transaction search
+type transactionSearchResponse struct {
+	Transactions []bank.Transaction      `json:"transactions"`
+	Summary      bank.TransactionSummary `json:"summary"`
+	NextCursor   string                  `json:"next_cursor,omitempty"`
+}
+
+// response struct for a new API key, the only time its token is returned
+type createdAPIKeyResponse struct {
+	bank.APIKey
+	Token string `json:"token"`
+}
+
+// response struct for a new webhook, the only time its signing secret is returned
+type createdWebhookResponse struct {
+	bank.Webhook
+	Secret string `json:"secret"`
+}
+
+// response struct for a batch, with one result per operation in request order
+type batchResponse struct {
+	Atomic    bool                   `json:"atomic"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []batchOperationResult `json:"results"`
+}
+
+type batchOperationResult struct {
+	Index       int                  `json:"index"`
+	Status      string               `json:"status"`
+	Transaction *bank.Transaction    `json:"transaction,omitempty"`
+	Error       *batchOperationError `json:"error,omitempty"`
+}
+
+// batchOperationError carries the same status and code a single request would have failed with
+type batchOperationError struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+func newBatchOperationError(err error) *batchOperationError {
+	status, code := translateError(err)
+	return &batchOperationError{Status: status, Code: code, Detail: problemDetail(code, err)}
+}
+
+func newBatchResponse(result *bank.BatchResult) batchResponse {
+	response := batchResponse{
+		Atomic:    result.Atomic,
+		Succeeded: result.Succeeded,
+		Failed:    result.Failed,
+		Results:   make([]batchOperationResult, 0, len(result.Results)),
+	}
+	for _, operation := range result.Results {
+		item := batchOperationResult{Index: operation.Index, Status: operation.Status, Transaction: operation.Transaction}
+		if operation.Err != nil {
+			item.Error = newBatchOperationError(operation.Err)
+		}
+		response.Results = append(response.Results, item)
+	}
+	return response
+}
+
+// response struct for a CSV import, errors lists every rejected row
+type importResponse struct {
+	DryRun       bool              `json:"dry_run"`
+	Accounts     int               `json:"accounts"`
+	Transactions int               `json:"transactions"`
+	Imported     []importedAccount `json:"imported"`
+	Errors       []importRowError  `json:"errors"`
+}
+
+type importedAccount struct {
+	Line              int    `json:"line"`
+	ExternalReference string `json:"external_reference"`
+	AccountID         string `json:"account_id"`
+}
+
+type importRowError struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	batchOperationError
+}
+
+func newImportResponse(result *csvImport.Result) importResponse {
+	response := importResponse{
+		DryRun:       result.DryRun,
+		Accounts:     result.Accounts,
+		Transactions: result.Transactions,
+		Imported:     make([]importedAccount, 0, len(result.Imported)),
+		Errors:       make([]importRowError, 0, len(result.Errors)),
+	}
+	for _, imported := range result.Imported {
+		response.Imported = append(response.Imported, importedAccount{
+			Line:              imported.Line,
+			ExternalReference: imported.ExternalReference,
+			AccountID:         imported.Account.ID,
+		})
+	}
+	for _, rowErr := range result.Errors {
+		response.Errors = append(response.Errors, importRowError{
+			Line:                rowErr.Line,
+			Column:              rowErr.Column,
+			batchOperationError: *newBatchOperationError(rowErr.Err),
+		})
+	}
+	return response
+}
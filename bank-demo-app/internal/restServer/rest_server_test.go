@@ -5,26 +5,39 @@ import (
 	"bank-demo-app/internal/bank/memoryBank"
 
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
 type BankRestAPITestSuite struct {
 	suite.Suite
-	router    *gin.Engine
+	router    http.Handler
 	bankStore *memoryBank.BankStore
 }
 
+var ctx = context.Background()
+
+// TestMain quiets gin, which main puts in release mode for the server.
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
 func (suite *BankRestAPITestSuite) SetupTest() {
 	suite.bankStore = memoryBank.NewBankStore()
-	routes := InitRestRoutes(suite.bankStore)
-	suite.router = NewRouter(routes)
+	suite.router = newTestRouter(suite.T(), suite.bankStore)
 }
 
 func (suite *BankRestAPITestSuite) TestStatusHandler() {
@@ -57,7 +70,7 @@ func (suite *BankRestAPITestSuite) TestListAccountsHandler() {
 		Owner:   "Alex Camara",
 		Balance: 500.0,
 	}
-	_, err := suite.bankStore.CreateAccount(account.Owner, account.Balance)
+	_, err := suite.bankStore.CreateAccount(ctx, account.Owner, account.Balance)
 	assert.NoError(suite.T(), err)
 
 	req, _ := http.NewRequest(http.MethodGet, "/accounts", nil)
@@ -77,7 +90,7 @@ func (suite *BankRestAPITestSuite) TestGetAccountByIDHandler() {
 		Owner:   "Alex Camara",
 		Balance: 3000.0,
 	}
-	createdAccount, err := suite.bankStore.CreateAccount(account.Owner, account.Balance)
+	createdAccount, err := suite.bankStore.CreateAccount(ctx, account.Owner, account.Balance)
 	assert.NoError(suite.T(), err)
 
 	req, _ := http.NewRequest(http.MethodGet, "/accounts/"+createdAccount.ID, nil)
@@ -91,15 +104,89 @@ func (suite *BankRestAPITestSuite) TestGetAccountByIDHandler() {
 	assert.Equal(suite.T(), createdAccount.ID, retrievedAccount.ID)
 }
 
+func (suite *BankRestAPITestSuite) TestUpdateAccountHandlers() {
+	account, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 100.0)
+	assert.NoError(suite.T(), err)
+
+	req, _ := http.NewRequest(http.MethodPatch, "/accounts/"+account.ID, bytes.NewBufferString(`{"metadata": {"branch": "BCN", "tier": "gold"}}`))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPatch, "/accounts/"+account.ID, bytes.NewBufferString(`{"owner": "Alex Cámara", "metadata": {"tier": null}}`))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var patched bank.Account
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &patched))
+	assert.Equal(suite.T(), "Alex Cámara", patched.Owner)
+	assert.Equal(suite.T(), map[string]string{"branch": "BCN"}, patched.Metadata)
+
+	req, _ = http.NewRequest(http.MethodPut, "/accounts/"+account.ID, bytes.NewBufferString(`{"owner": "Alex Camara", "metadata": {"segment": "retail"}}`))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var replaced bank.Account
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &replaced))
+	assert.Equal(suite.T(), "Alex Camara", replaced.Owner)
+	assert.Equal(suite.T(), map[string]string{"segment": "retail"}, replaced.Metadata)
+
+	req, _ = http.NewRequest(http.MethodPut, "/accounts/"+account.ID, bytes.NewBufferString(`{"metadata": {}}`))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code, "PUT requires the owner")
+}
+
+func (suite *BankRestAPITestSuite) TestCloseAccountHandler() {
+	account, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 100.0)
+	assert.NoError(suite.T(), err)
+	payout, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 0)
+	assert.NoError(suite.T(), err)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/accounts/"+account.ID, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	var problem Problem
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "account_balance_not_zero", problem.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/accounts/"+account.ID+"?payout_account_id="+payout.ID, nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var closed bank.Account
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &closed))
+	assert.Equal(suite.T(), bank.AccountStatusClosed, closed.Status)
+	assert.NotNil(suite.T(), closed.ClosedAt)
+
+	req, _ = http.NewRequest(http.MethodGet, "/accounts/"+account.ID, nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code, "closed accounts stay readable")
+
+	body, _ := json.Marshal(createTransactionRequest{Type: bank.DepositTransactionType, Amount: 10.0})
+	req, _ = http.NewRequest(http.MethodPost, "/accounts/"+account.ID+"/transactions", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "account_closed", problem.Code)
+}
+
 func (suite *BankRestAPITestSuite) TestPerformTransactionHandler() {
 	account := bank.Account{
 		Owner:   "Alex Camara",
 		Balance: 2000.0,
 	}
-	createdAccount, err := suite.bankStore.CreateAccount(account.Owner, account.Balance)
+	createdAccount, err := suite.bankStore.CreateAccount(ctx, account.Owner, account.Balance)
 	assert.NoError(suite.T(), err)
 
-	transaction := bank.Transaction{
+	transaction := createTransactionRequest{
 		Type:   "deposit",
 		Amount: 500.0,
 	}
@@ -116,6 +203,87 @@ func (suite *BankRestAPITestSuite) TestPerformTransactionHandler() {
 	assert.Equal(suite.T(), transaction.Amount, createdTransaction.Amount)
 }
 
+func (suite *BankRestAPITestSuite) TestGetTransactionByIDHandler() {
+	account, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 1000.0)
+	assert.NoError(suite.T(), err)
+	transaction, err := suite.bankStore.PerformTransaction(ctx, account.ID, bank.WithdrawalTransactionType, 250.0, "")
+	assert.NoError(suite.T(), err)
+
+	req, _ := http.NewRequest(http.MethodGet, "/transactions/"+transaction.ID, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var retrievedTransaction bank.Transaction
+	err = json.Unmarshal(w.Body.Bytes(), &retrievedTransaction)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), transaction.ID, retrievedTransaction.ID)
+	assert.Equal(suite.T(), account.ID, retrievedTransaction.AccountID)
+	assert.Equal(suite.T(), 250.0, retrievedTransaction.Amount)
+
+	req, _ = http.NewRequest(http.MethodGet, "/transactions/unknown-id", nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	var problem Problem
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "transaction_not_found", problem.Code)
+}
+
+func (suite *BankRestAPITestSuite) TestSearchTransactionsHandler() {
+	alex, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 50000.0)
+	assert.NoError(suite.T(), err)
+	john, err := suite.bankStore.CreateAccount(ctx, "John Doe", 50000.0)
+	assert.NoError(suite.T(), err)
+	_, err = suite.bankStore.PerformTransaction(ctx, alex.ID, bank.WithdrawalTransactionType, 12000.0, "Rent")
+	assert.NoError(suite.T(), err)
+	_, err = suite.bankStore.PerformTransaction(ctx, john.ID, bank.WithdrawalTransactionType, 15000.0, "Car")
+	assert.NoError(suite.T(), err)
+	_, err = suite.bankStore.PerformTransaction(ctx, john.ID, bank.DepositTransactionType, 20000.0, "")
+	assert.NoError(suite.T(), err)
+
+	req, _ := http.NewRequest(http.MethodGet, "/transactions?type=withdrawal&min_amount=10000&limit=1&account_id="+alex.ID+","+john.ID, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response transactionSearchResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(suite.T(), response.Transactions, 1)
+	assert.NotEmpty(suite.T(), response.NextCursor)
+	assert.Equal(suite.T(), response.NextCursor, w.Header().Get(nextCursorHeader))
+	assert.Equal(suite.T(), 2, response.Summary.Count)
+	assert.Equal(suite.T(), bank.TransactionTypeSummary{Count: 2, Sum: 27000.0}, response.Summary.ByType[bank.WithdrawalTransactionType])
+	assert.Equal(suite.T(), bank.TransactionTypeSummary{}, response.Summary.ByType[bank.DepositTransactionType])
+
+	req, _ = http.NewRequest(http.MethodGet, "/transactions?reference=rent&account_id="+john.ID+"&account_id="+alex.ID, nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var filtered transactionSearchResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &filtered))
+	assert.Len(suite.T(), filtered.Transactions, 1)
+	assert.Equal(suite.T(), "Rent", filtered.Transactions[0].Reference)
+	assert.Empty(suite.T(), filtered.NextCursor)
+}
+
+func (suite *BankRestAPITestSuite) TestPerformTransactionWithReference() {
+	account, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 100.0)
+	assert.NoError(suite.T(), err)
+
+	body, _ := json.Marshal(createTransactionRequest{Type: bank.DepositTransactionType, Amount: 10.0, Reference: "Invoice 42"})
+	req, _ := http.NewRequest(http.MethodPost, "/accounts/"+account.ID+"/transactions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var transaction bank.Transaction
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &transaction))
+	assert.Equal(suite.T(), "Invoice 42", transaction.Reference)
+}
+
 func (suite *BankRestAPITestSuite) TestTransferFundsHandler() {
 	account1 := bank.Account{
 		Owner:   "Alex Camara",
@@ -126,9 +294,9 @@ func (suite *BankRestAPITestSuite) TestTransferFundsHandler() {
 		Balance: 1000.0,
 	}
 
-	account1Created, err := suite.bankStore.CreateAccount(account1.Owner, account1.Balance)
+	account1Created, err := suite.bankStore.CreateAccount(ctx, account1.Owner, account1.Balance)
 	assert.NoError(suite.T(), err)
-	account2Created, err := suite.bankStore.CreateAccount(account2.Owner, account2.Balance)
+	account2Created, err := suite.bankStore.CreateAccount(ctx, account2.Owner, account2.Balance)
 	assert.NoError(suite.T(), err)
 
 	transfer := transferRequest{
@@ -143,28 +311,212 @@ func (suite *BankRestAPITestSuite) TestTransferFundsHandler() {
 	suite.router.ServeHTTP(w, req)
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 
-	account1Updated, err := suite.bankStore.GetAccountByID(account1Created.ID)
+	account1Updated, err := suite.bankStore.GetAccountByID(ctx, account1Created.ID)
 	assert.NoError(suite.T(), err)
-	account2Updated, err := suite.bankStore.GetAccountByID(account2Created.ID)
+	account2Updated, err := suite.bankStore.GetAccountByID(ctx, account2Created.ID)
 	assert.NoError(suite.T(), err)
 
 	assert.Equal(suite.T(), 1300.0, account1Updated.Balance)
 	assert.Equal(suite.T(), 1200.0, account2Updated.Balance)
 }
 
+func (suite *BankRestAPITestSuite) TestExecuteBatchHandler() {
+	source, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 100.0)
+	assert.NoError(suite.T(), err)
+	destination, err := suite.bankStore.CreateAccount(ctx, "John Doe", 0)
+	assert.NoError(suite.T(), err)
+
+	operations := []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: source.ID, Amount: 50.0, Reference: "Top up"},
+		{Type: bank.BatchTransfer, FromAccountID: source.ID, ToAccountID: destination.ID, Amount: 120.0},
+		{Type: bank.BatchWithdrawal, AccountID: destination.ID, Amount: 500.0},
+	}
+
+	// Atomic: the overdrawn withdrawal rolls back the deposit and the transfer.
+	body, _ := json.Marshal(batchRequest{Atomic: true, Operations: operations})
+	req, _ := http.NewRequest(http.MethodPost, "/batches", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	var problem Problem
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "insufficient_funds", problem.Code)
+	if assert.NotNil(suite.T(), problem.OperationIndex) {
+		assert.Equal(suite.T(), 2, *problem.OperationIndex)
+	}
+
+	unchanged, err := suite.bankStore.GetAccountByID(ctx, source.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 100.0, unchanged.Balance)
+
+	// Best effort: the first two operations go through, the last one reports its own error.
+	body, _ = json.Marshal(batchRequest{Operations: operations})
+	req, _ = http.NewRequest(http.MethodPost, "/batches", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response batchResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(suite.T(), response.Atomic)
+	assert.Equal(suite.T(), 2, response.Succeeded)
+	assert.Equal(suite.T(), 1, response.Failed)
+	if assert.Len(suite.T(), response.Results, 3) {
+		assert.Equal(suite.T(), bank.BatchOperationSucceeded, response.Results[0].Status)
+		assert.Equal(suite.T(), "Top up", response.Results[0].Transaction.Reference)
+		assert.Equal(suite.T(), bank.BatchOperationSucceeded, response.Results[1].Status)
+		assert.Nil(suite.T(), response.Results[1].Transaction)
+		assert.Equal(suite.T(), bank.BatchOperationFailed, response.Results[2].Status)
+		assert.Equal(suite.T(), &batchOperationError{Status: http.StatusConflict, Code: "insufficient_funds", Detail: response.Results[2].Error.Detail}, response.Results[2].Error)
+	}
+
+	updated, err := suite.bankStore.GetAccountByID(ctx, destination.ID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 120.0, updated.Balance)
+}
+
+func (suite *BankRestAPITestSuite) TestExecuteBatchHandlerRejectsInvalidBatches() {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode string
+	}{
+		{"empty", `{"operations": []}`, "invalid_batch"},
+		{"unknown type", `{"atomic": true, "operations": [{"type": "refund", "account_id": "1", "amount": 1}]}`, "invalid_batch"},
+		{"invalid amount", `{"atomic": true, "operations": [{"type": "deposit", "account_id": "1", "amount": -1}]}`, "amount_not_positive"},
+		{"malformed", `{"operations": "deposit"}`, "invalid_request_body"},
+	}
+
+	for _, test := range tests {
+		suite.Run(test.name, func() {
+			req, _ := http.NewRequest(http.MethodPost, "/batches", bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+			suite.router.ServeHTTP(w, req)
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+			var problem Problem
+			assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(suite.T(), test.expectedCode, problem.Code)
+		})
+	}
+}
+
+// deadlineStore records the deadline of the batches executed through it.
+type deadlineStore struct {
+	BankStore
+	deadline time.Duration
+}
+
+func (s *deadlineStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		s.deadline = time.Until(deadline)
+	}
+	return s.BankStore.ExecuteBatch(ctx, batch)
+}
+
+func TestExecuteBatchHandlerScalesTimeoutWithOperations(t *testing.T) {
+	store := &deadlineStore{BankStore: memoryBank.NewBankStore()}
+	router := newTestRouter(t, store)
+	account, err := store.CreateAccount(ctx, "Alex Camara", 0)
+	require.NoError(t, err)
+
+	operations := make([]bank.BatchOperation, bank.MaxBatchOperations)
+	for i := range operations {
+		operations[i] = bank.BatchOperation{Type: bank.BatchDeposit, AccountID: account.ID, Amount: 1}
+	}
+	body, _ := json.Marshal(batchRequest{Atomic: true, Operations: operations})
+
+	req, _ := http.NewRequest(http.MethodPost, "/batches", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Greater(t, store.deadline, storeOperationTimeout+time.Minute, "a full batch gets far more time than a single store call")
+	assert.LessOrEqual(t, store.deadline, batchTimeout(bank.MaxBatchOperations))
+}
+
+func (suite *BankRestAPITestSuite) TestImportHandler() {
+	file := `external_reference,owner,initial_balance,transaction_type,amount,reference
+L-1,Alex Camara,100,,,
+L-1,,,withdrawal,40,Legacy fee
+`
+
+	req, _ := http.NewRequest(http.MethodPost, "/imports?dry_run=true", bytes.NewBufferString(file))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response importResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(suite.T(), response.DryRun)
+	assert.Equal(suite.T(), 1, response.Accounts)
+	assert.Equal(suite.T(), 1, response.Transactions)
+	assert.Empty(suite.T(), response.Imported)
+
+	req, _ = http.NewRequest(http.MethodPost, "/imports", bytes.NewBufferString(file))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	response = importResponse{}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(suite.T(), response.Imported, 1) {
+		assert.Equal(suite.T(), "L-1", response.Imported[0].ExternalReference)
+		account, err := suite.bankStore.GetAccountByID(ctx, response.Imported[0].AccountID)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 60.0, account.Balance)
+	}
+}
+
+func (suite *BankRestAPITestSuite) TestImportHandlerRejectsInvalidRows() {
+	file := `external_reference,owner,initial_balance,transaction_type,amount
+L-1,,100,,
+L-1,,,withdrawal,abc
+`
+	req, _ := http.NewRequest(http.MethodPost, "/imports", bytes.NewBufferString(file))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, w.Code)
+
+	var response importResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Empty(suite.T(), response.Imported)
+	if assert.Len(suite.T(), response.Errors, 2) {
+		assert.Equal(suite.T(), 2, response.Errors[0].Line)
+		assert.Equal(suite.T(), "empty_owner_name", response.Errors[0].Code)
+		assert.Equal(suite.T(), 3, response.Errors[1].Line)
+		assert.Equal(suite.T(), "amount", response.Errors[1].Column)
+		assert.Equal(suite.T(), "invalid_import_row", response.Errors[1].Code)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/imports", bytes.NewBufferString("owner\nAlex Camara\n"))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	var problem Problem
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "invalid_import_file", problem.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/imports?dry_run=maybe", bytes.NewBufferString(file))
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
 func (suite *BankRestAPITestSuite) TestGetTransactionsByAccountIDHandler() {
 	account := bank.Account{
 		Owner:   "Alex Camara",
 		Balance: 1000.0,
 	}
-	createdAccount, err := suite.bankStore.CreateAccount(account.Owner, account.Balance)
+	createdAccount, err := suite.bankStore.CreateAccount(ctx, account.Owner, account.Balance)
 	assert.NoError(suite.T(), err)
 
 	transaction := bank.Transaction{
 		Type:   "deposit",
 		Amount: 300.0,
 	}
-	_, err = suite.bankStore.PerformTransaction(createdAccount.ID, transaction.Type, transaction.Amount)
+	_, err = suite.bankStore.PerformTransaction(ctx, createdAccount.ID, transaction.Type, transaction.Amount, "")
 	assert.NoError(suite.T(), err)
 
 	req, _ := http.NewRequest(http.MethodGet, "/accounts/"+createdAccount.ID+"/transactions", nil)
@@ -179,6 +531,85 @@ func (suite *BankRestAPITestSuite) TestGetTransactionsByAccountIDHandler() {
 	assert.Equal(suite.T(), transaction.Amount, transactions[0].Amount)
 }
 
+func (suite *BankRestAPITestSuite) TestCanceledRequestDoesNotReachStore() {
+	body, _ := json.Marshal(createAccountRequest{Owner: "Alex Camara", InitialBalance: 100.0})
+	requestCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	req, _ := http.NewRequestWithContext(requestCtx, http.MethodPost, "/accounts", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.NotEqual(suite.T(), http.StatusCreated, w.Code)
+
+	page, err := suite.bankStore.ListAccounts(ctx, bank.AccountFilter{}, bank.PageRequest{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), page.Accounts)
+}
+
+func (suite *BankRestAPITestSuite) TestListAccountsPaginationHeaders() {
+	for _, balance := range []float64{100.0, 300.0, 200.0} {
+		_, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", balance)
+		assert.NoError(suite.T(), err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/accounts?limit=2&sort=balance&order=desc&owner=Alex+Camara", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var accounts []bank.Account
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &accounts))
+	assert.Len(suite.T(), accounts, 2)
+	assert.Equal(suite.T(), 300.0, accounts[0].Balance)
+
+	cursor := w.Header().Get(nextCursorHeader)
+	assert.NotEmpty(suite.T(), cursor)
+	assert.Contains(suite.T(), w.Header().Get(linkHeader), `rel="next"`)
+	assert.Contains(suite.T(), w.Header().Get(linkHeader), "owner=Alex+Camara")
+
+	req, _ = http.NewRequest(http.MethodGet, "/accounts?limit=2&sort=balance&order=desc&cursor="+cursor, nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &accounts))
+	assert.Len(suite.T(), accounts, 1)
+	assert.Equal(suite.T(), 100.0, accounts[0].Balance)
+	assert.Empty(suite.T(), w.Header().Get(nextCursorHeader))
+	assert.Empty(suite.T(), w.Header().Get(linkHeader))
+}
+
+func (suite *BankRestAPITestSuite) TestListInvalidQueryParameters() {
+	account, err := suite.bankStore.CreateAccount(ctx, "Alex Camara", 100.0)
+	assert.NoError(suite.T(), err)
+
+	tests := []struct {
+		url          string
+		expectedCode string
+	}{
+		{"/accounts?limit=ten", "invalid_query_parameter"},
+		{"/accounts?min_balance=abc", "invalid_query_parameter"},
+		{"/accounts?limit=100000", "invalid_page_limit"},
+		{"/accounts?sort=amount", "invalid_sort_field"},
+		{"/accounts?order=random", "invalid_sort_order"},
+		{"/accounts?cursor=garbage", "invalid_cursor"},
+		{"/accounts?min_balance=10&max_balance=5", "invalid_filter"},
+		{"/accounts/" + account.ID + "/transactions?from=yesterday", "invalid_query_parameter"},
+	}
+
+	for _, test := range tests {
+		suite.Run(test.url, func() {
+			req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+			w := httptest.NewRecorder()
+			suite.router.ServeHTTP(w, req)
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+			var problem Problem
+			assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(suite.T(), test.expectedCode, problem.Code)
+		})
+	}
+}
+
 func TestBankRestAPITestSuite(t *testing.T) {
 	suite.Run(t, new(BankRestAPITestSuite))
 }
@@ -3,6 +3,7 @@ package restServer
 import (
 	"io"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -14,34 +15,89 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler gin.HandlerFunc
+
+	// The fields below describe the route in the OpenAPI document served at /openapi.json.
+	Summary string
+	// Query lists the query parameters the route reads, see queryParameters.
+	Query []string
+	// Request is a zero value of the request body, nil when the route takes none. JSON bodies
+	// are validated against its schema before they reach Handler.
+	Request any
+	// RequestContentType defaults to application/json.
+	RequestContentType string
+	// Response is a zero value of the success response body, nil when there is none.
+	Response any
+	// Status is the success status code, 200 when zero.
+	Status int
+
+	// Scopes an API key must all grant to call the route. Routes without scopes are public.
+	Scopes []string
+	// Customers lets end-customer tokens call the route. Its handler must only serve the
+	// caller's own accounts.
+	Customers bool
+	// Middleware runs before the request body is validated, so unauthenticated calls are
+	// rejected before anything else.
+	Middleware []gin.HandlerFunc
 }
 
 // Vector to store declared routes.
 type Routes []Route
 
+// NewRouter serves serverRoutes as the current API version under /v1, and at the root as a
+// deprecated alias for clients written before the API was versioned.
 func NewRouter(serverRoutes Routes) *gin.Engine {
-	// Avoid GIN verbose messages.
-	gin.SetMode(gin.ReleaseMode)
-	gin.DefaultWriter = io.Discard
+	return NewVersionedRouter(
+		APIVersion{Name: CurrentAPIVersion, Routes: serverRoutes},
+		APIVersion{Routes: serverRoutes, DeprecatedAt: unprefixedDeprecatedAt, Sunset: unprefixedSunset},
+	)
+}
+
+// NewVersionedRouter mounts every version in its own route group, so versions can be served side
+// by side with different request and response structs.
+func NewVersionedRouter(versions ...APIVersion) *gin.Engine {
 	// Create the muxer of our Rest server that will
 	// route each request and response to correspondent
 	// declared route.
-	router := gin.Default()
+	router := gin.New()
+	// Take the client IP from the connection, not from X-Forwarded-For, which any client can
+	// set to dodge the per-IP rate limits.
+	router.SetTrustedProxies(nil)
+	// Recovery runs inside the access log, so a request that panicked is still logged as a 500.
+	router.Use(tracingMiddleware(), requestIDMiddleware(), accessLogMiddleware(), gin.CustomRecoveryWithWriter(io.Discard, recoverPanic))
+
+	for _, version := range versions {
+		group := router.Group(version.basePath())
+		if !version.DeprecatedAt.IsZero() {
+			group.Use(deprecationMiddleware(version.DeprecatedAt, version.Sunset))
+		}
+
+		spec := newAPISpec(version)
+		group.GET(openAPIPath, spec.serveDocument)
 
-	for _, route := range serverRoutes {
-		addRoute(router, route)
+		for _, route := range version.Routes {
+			middleware := append(slices.Clone(route.Middleware), spec.middleware(route)...)
+			addRoute(group, route, middleware...)
+		}
 	}
 
 	// Return muxer with all its added routes.
 	return router
 }
 
-func addRoute(router *gin.Engine, route Route) {
+// addRoute registers route, running middleware before its handler.
+func addRoute(router gin.IRoutes, route Route, middleware ...gin.HandlerFunc) {
+	handlers := append(middleware, route.Handler)
 	switch route.Method {
 	case http.MethodGet:
-		router.GET(route.Pattern, route.Handler)
+		router.GET(route.Pattern, handlers...)
 	case http.MethodPost:
-		router.POST(route.Pattern, route.Handler)
+		router.POST(route.Pattern, handlers...)
+	case http.MethodPut:
+		router.PUT(route.Pattern, handlers...)
+	case http.MethodPatch:
+		router.PATCH(route.Pattern, handlers...)
+	case http.MethodDelete:
+		router.DELETE(route.Pattern, handlers...)
 	default:
 		log.Warn().Msg("Invalid HTTP method specified: " + route.Method)
 	}
@@ -1,53 +1,234 @@
 package restServer
 
 import (
+	"bank-demo-app/internal/bank"
 	"net/http"
 )
 
-func InitRestRoutes(bankStore BankStore) Routes {
+// Query parameters shared by the list endpoints, described in queryParameters.
+var (
+	pageQuery              = []string{"limit", "cursor", "sort", "order"}
+	accountFilterQuery     = []string{"owner", "min_balance", "max_balance"}
+	transactionFilterQuery = []string{"type", "min_amount", "max_amount", "from", "to", "reference"}
+)
+
+// InitRestRoutes declares every route served by the API. Routes with scopes require an API key,
+// or a customer token when marked Customers and a TokenVerifier is given. WithRateLimiter throttles
+// them per client.
+func InitRestRoutes(bankStore BankStore, options ...RouteOption) Routes {
+	var config routeConfig
+	for _, option := range options {
+		option(&config)
+	}
+
 	serverRoutes := Routes{
-		// Route to get if server is up.
 		{
 			Method:  http.MethodGet,
 			Pattern: "/status",
 			Handler: statusHandler,
+			Summary: "Check whether the server is up.",
 		},
-		// Create a new bank account with an initial balance.
 		{
-			Method:  http.MethodPost,
-			Pattern: "/accounts",
-			Handler: createAccountHandler(bankStore),
+			Method:   http.MethodPost,
+			Pattern:  "/accounts",
+			Handler:  createAccountHandler(bankStore),
+			Summary:  "Create a new bank account with an initial balance.",
+			Request:  createAccountRequest{},
+			Response: bank.Account{},
+			Status:   http.StatusCreated,
+			Scopes:   []string{bank.ScopeAccountsWrite},
 		},
-		// Retrieve a list of all bank accounts.
 		{
-			Method:  http.MethodGet,
-			Pattern: "/accounts",
-			Handler: listAccountsHandler(bankStore),
+			Method:   http.MethodGet,
+			Pattern:  "/accounts",
+			Handler:  listAccountsHandler(bankStore),
+			Summary:  "List bank accounts, one page at a time.",
+			Query:    concatQuery(pageQuery, accountFilterQuery),
+			Response: []bank.Account{},
+			Scopes:   []string{bank.ScopeAccountsRead},
 		},
-		// Retrieve details of a specific account by ID.
 		{
-			Method:  http.MethodGet,
-			Pattern: "/accounts/:id",
-			Handler: getAccountByIDHandler(bankStore),
+			Method:    http.MethodGet,
+			Pattern:   "/accounts/:id",
+			Handler:   getAccountByIDHandler(bankStore),
+			Summary:   "Retrieve details of a specific account by ID.",
+			Response:  bank.Account{},
+			Scopes:    []string{bank.ScopeAccountsRead},
+			Customers: true,
 		},
-		// Create a deposit or withdrawal transaction for a specific account.
 		{
-			Method:  http.MethodPost,
-			Pattern: "/accounts/:id/transactions",
-			Handler: performTransactionHandler(bankStore),
+			Method:   http.MethodPut,
+			Pattern:  "/accounts/:id",
+			Handler:  replaceAccountHandler(bankStore),
+			Summary:  "Replace the owner and metadata of an account.",
+			Request:  replaceAccountRequest{},
+			Response: bank.Account{},
+			Scopes:   []string{bank.ScopeAccountsWrite},
 		},
-		// Retrieve all transactions sassociated with a specific account.
 		{
-			Method:  http.MethodGet,
-			Pattern: "/accounts/:id/transactions",
-			Handler: getTransactionsByAccountIDHandler(bankStore),
+			Method:   http.MethodPatch,
+			Pattern:  "/accounts/:id",
+			Handler:  updateAccountHandler(bankStore),
+			Summary:  "Change the owner or some metadata keys of an account.",
+			Request:  updateAccountRequest{},
+			Response: bank.Account{},
+			Scopes:   []string{bank.ScopeAccountsWrite},
+		},
+		{
+			Method:   http.MethodDelete,
+			Pattern:  "/accounts/:id",
+			Handler:  closeAccountHandler(bankStore),
+			Summary:  "Close an account, paying out its balance to payout_account_id if it isn't zero.",
+			Query:    []string{"payout_account_id"},
+			Response: bank.Account{},
+			Scopes:   []string{bank.ScopeAccountsWrite},
+		},
+		{
+			Method:    http.MethodPost,
+			Pattern:   "/accounts/:id/transactions",
+			Handler:   performTransactionHandler(bankStore),
+			Summary:   "Create a deposit or withdrawal transaction for a specific account.",
+			Request:   createTransactionRequest{},
+			Response:  bank.Transaction{},
+			Status:    http.StatusCreated,
+			Scopes:    []string{bank.ScopeTransactionsWrite},
+			Customers: true,
+		},
+		{
+			Method:    http.MethodGet,
+			Pattern:   "/accounts/:id/transactions",
+			Handler:   getTransactionsByAccountIDHandler(bankStore),
+			Summary:   "List the transactions of a specific account, one page at a time.",
+			Query:     concatQuery(pageQuery, transactionFilterQuery),
+			Response:  []bank.Transaction{},
+			Scopes:    []string{bank.ScopeTransactionsRead},
+			Customers: true,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/transactions",
+			Handler:  searchTransactionsHandler(bankStore),
+			Summary:  "Search transactions across accounts, with a summary of every match.",
+			Query:    concatQuery(pageQuery, transactionFilterQuery, []string{"account_id"}),
+			Response: transactionSearchResponse{},
+			Scopes:   []string{bank.ScopeTransactionsRead},
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/transactions/:id",
+			Handler:  getTransactionByIDHandler(bankStore),
+			Summary:  "Retrieve a single transaction by ID.",
+			Response: bank.Transaction{},
+			Scopes:   []string{bank.ScopeTransactionsRead},
+		},
+		{
+			Method:    http.MethodPost,
+			Pattern:   "/transfer",
+			Handler:   transferFundsHandler(bankStore),
+			Summary:   "Transfer funds from one account to another.",
+			Request:   transferRequest{},
+			Response:  messageResponse{},
+			Scopes:    []string{bank.ScopeTransfersWrite},
+			Customers: true,
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/batches",
+			Handler:  executeBatchHandler(bankStore),
+			Summary:  "Apply a list of deposits, withdrawals and transfers, all-or-nothing when atomic.",
+			Request:  batchRequest{},
+			Response: batchResponse{},
+			Scopes:   []string{bank.ScopeTransactionsWrite, bank.ScopeTransfersWrite},
+		},
+		{
+			Method:             http.MethodPost,
+			Pattern:            "/imports",
+			Handler:            importHandler(bankStore),
+			Summary:            "Import accounts and their opening transactions from a CSV file, only validating it with dry_run=true.",
+			Query:              []string{"dry_run"},
+			Request:            "",
+			RequestContentType: csvContentType,
+			Response:           importResponse{},
+			Status:             http.StatusCreated,
+			Scopes:             []string{bank.ScopeAccountsWrite, bank.ScopeTransactionsWrite},
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/api-keys",
+			Handler:  createAPIKeyHandler(bankStore),
+			Summary:  "Create an API key. Its token is only returned in this response.",
+			Request:  createAPIKeyRequest{},
+			Response: createdAPIKeyResponse{},
+			Status:   http.StatusCreated,
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/api-keys",
+			Handler:  listAPIKeysHandler(bankStore),
+			Summary:  "List API keys, revoked ones included, without their tokens.",
+			Response: []bank.APIKey{},
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+		{
+			Method:   http.MethodDelete,
+			Pattern:  "/api-keys/:id",
+			Handler:  revokeAPIKeyHandler(bankStore),
+			Summary:  "Revoke an API key, which is rejected from then on.",
+			Response: bank.APIKey{},
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/webhooks",
+			Handler:  createWebhookHandler(bankStore),
+			Summary:  "Subscribe a URL to events. The secret signing its deliveries is only returned in this response.",
+			Request:  createWebhookRequest{},
+			Response: createdWebhookResponse{},
+			Status:   http.StatusCreated,
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/webhooks",
+			Handler:  listWebhooksHandler(bankStore),
+			Summary:  "List webhooks, without their secrets.",
+			Response: []bank.Webhook{},
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/webhooks/:id",
+			Handler:  getWebhookHandler(bankStore),
+			Summary:  "Retrieve a webhook by ID.",
+			Response: bank.Webhook{},
+			Scopes:   []string{bank.ScopeAdmin},
 		},
-		// Transfer funds from one account to another.
 		{
-			Method:  http.MethodPost,
-			Pattern: "/transfer",
-			Handler: transferFundsHandler(bankStore),
+			Method:  http.MethodDelete,
+			Pattern: "/webhooks/:id",
+			Handler: deleteWebhookHandler(bankStore),
+			Summary: "Delete a webhook along with its pending deliveries and delivery log.",
+			Status:  http.StatusNoContent,
+			Scopes:  []string{bank.ScopeAdmin},
 		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/webhooks/:id/deliveries",
+			Handler:  listWebhookDeliveriesHandler(bankStore),
+			Summary:  "List the deliveries of a webhook and their attempts, newest first.",
+			Query:    []string{"status", "limit"},
+			Response: []bank.WebhookDelivery{},
+			Scopes:   []string{bank.ScopeAdmin},
+		},
+	}
+	return protectRoutes(bankStore, config, serverRoutes)
+}
+
+func concatQuery(groups ...[]string) []string {
+	var names []string
+	for _, group := range groups {
+		names = append(names, group...)
 	}
-	return serverRoutes
+	return names
 }
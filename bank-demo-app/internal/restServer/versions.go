@@ -0,0 +1,59 @@
+package restServer
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentAPIVersion is the version new clients should use.
+const CurrentAPIVersion = "v1"
+
+const (
+	deprecationHeader = "Deprecation"
+	sunsetHeader      = "Sunset"
+)
+
+// The unprefixed paths (/accounts rather than /v1/accounts) are kept for clients written before the
+// API was versioned, until they are removed at unprefixedSunset.
+var (
+	unprefixedDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	unprefixedSunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// APIVersion is a set of routes served under /<Name>. An empty Name serves them at the root.
+type APIVersion struct {
+	Name   string
+	Routes Routes
+
+	// DeprecatedAt is set once clients should move to a newer version. Responses then carry a
+	// Deprecation header, and a Sunset header announcing the removal date when Sunset is set.
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+func (v APIVersion) basePath() string {
+	if v.Name == "" {
+		return "/"
+	}
+	return "/" + v.Name
+}
+
+// deprecationMiddleware sets the Deprecation (RFC 9745) and Sunset (RFC 8594) headers.
+func deprecationMiddleware(deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := ""
+	if !sunset.IsZero() {
+		sunsetDate = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header(deprecationHeader, deprecation)
+		if sunsetDate != "" {
+			c.Header(sunsetHeader, sunsetDate)
+		}
+		c.Next()
+	}
+}
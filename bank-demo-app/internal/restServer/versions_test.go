@@ -0,0 +1,130 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCurrentVersionIsNotDeprecated(t *testing.T) {
+	router := newTestRouter(t, memoryBank.NewBankStore())
+
+	w := serve(router, http.MethodPost, "/v1/accounts", `{"owner": "Alex Camara", "initial_balance": 10}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(deprecationHeader))
+	assert.Empty(t, w.Header().Get(sunsetHeader))
+}
+
+func TestUnprefixedPathsAreDeprecatedAliases(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	router := newTestRouter(t, store)
+
+	w := serve(router, http.MethodPost, "/accounts", `{"owner": "Alex Camara", "initial_balance": 10}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "@"+strconv.FormatInt(unprefixedDeprecatedAt.Unix(), 10), w.Header().Get(deprecationHeader))
+	assert.Equal(t, unprefixedSunset.Format(http.TimeFormat), w.Header().Get(sunsetHeader))
+
+	var account bank.Account
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+	w = serve(router, http.MethodGet, "/v1/accounts/"+account.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code, "both paths share the same store")
+
+	w = serve(router, http.MethodGet, "/accounts/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotEmpty(t, w.Header().Get(deprecationHeader), "errors are flagged too")
+
+	w = serve(router, http.MethodGet, openAPIPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var document OpenAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.True(t, document.Paths["/accounts"]["post"].Deprecated)
+}
+
+func TestPaginationLinkKeepsTheVersionPrefix(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	router := newTestRouter(t, store)
+	for i := 0; i < 2; i++ {
+		_, err := store.CreateAccount(ctx, "Alex Camara", 10)
+		require.NoError(t, err)
+	}
+
+	w := serve(router, http.MethodGet, "/v1/accounts?limit=1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get(linkHeader), "</v1/accounts?"), w.Header().Get(linkHeader))
+}
+
+// v2CreateAccountRequest stands in for a breaking change of the request body.
+type v2CreateAccountRequest struct {
+	Holder struct {
+		Name string `json:"name"`
+	} `json:"holder"`
+}
+
+func TestVersionsAreServedSideBySide(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	v2Routes := Routes{{
+		Method:  http.MethodPost,
+		Pattern: "/accounts",
+		Handler: func(c *gin.Context) {
+			var request v2CreateAccountRequest
+			if err := c.ShouldBindJSON(&request); err != nil {
+				writeError(c, errInvalidRequestBody)
+				return
+			}
+			account, err := store.CreateAccount(c.Request.Context(), request.Holder.Name, 0)
+			if err != nil {
+				writeError(c, err)
+				return
+			}
+			c.JSON(http.StatusCreated, account)
+		},
+		Summary:  "Create a new bank account.",
+		Request:  v2CreateAccountRequest{},
+		Response: bank.Account{},
+		Status:   http.StatusCreated,
+	}}
+	sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	router := withAPIKey(t, store, NewVersionedRouter(
+		APIVersion{Name: "v1", Routes: InitRestRoutes(store), DeprecatedAt: time.Now(), Sunset: sunset},
+		APIVersion{Name: "v2", Routes: v2Routes},
+	))
+
+	w := serve(router, http.MethodPost, "/v2/accounts", `{"holder": {"name": "Alex Camara"}}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(deprecationHeader))
+
+	w = serve(router, http.MethodPost, "/v2/accounts", `{"holder": {"name": 42}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "each version validates against its own schema")
+
+	w = serve(router, http.MethodPost, "/v1/accounts", `{"owner": "Alex Camara"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEmpty(t, w.Header().Get(deprecationHeader))
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", w.Header().Get(sunsetHeader))
+
+	w = serve(router, http.MethodGet, "/accounts", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "only the versions given are served")
+
+	w = serve(router, http.MethodGet, "/v2"+openAPIPath, "")
+	var document OpenAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Contains(t, document.Components.Schemas, "V2CreateAccountRequest")
+	assert.NotContains(t, document.Components.Schemas, "CreateAccountRequest")
+	assert.Len(t, document.Paths, 1)
+}
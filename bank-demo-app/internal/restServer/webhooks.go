@@ -0,0 +1,233 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// EventPublisher queues the deliveries of events for the webhooks subscribed to them.
+// webhooks.Dispatcher is one.
+type EventPublisher interface {
+	// Publish queues the deliveries of events caused by a write already committed.
+	Publish(ctx context.Context, events ...bank.Event) error
+	// Wake has the deliveries a write queued through its outbox sent right away.
+	Wake()
+	// WebhooksChanged tells the publisher a webhook was created or deleted.
+	WebhooksChanged()
+}
+
+// OutboxStore is implemented by stores that queue the webhook deliveries of a write in the same
+// transaction as the write, with the subscriptions outbox lists.
+type OutboxStore interface {
+	SetOutbox(outbox bank.Outbox)
+}
+
+// publishingStore publishes an event for every account, transaction and transfer made through it.
+type publishingStore struct {
+	BankStore
+	publisher EventPublisher
+	// queued is set when store queues the deliveries of its writes itself, through its outbox.
+	queued bool
+}
+
+// PublishEvents publishes account.created, transaction.created and transfer.completed events to
+// publisher once the operations of store that cause them succeed, batches and closure payouts
+// included.
+//
+// When queued is set, store is an OutboxStore given publisher as its outbox: the deliveries are
+// queued in the same transaction as the operation, so neither is committed without the other,
+// and the publisher is only woken up. Otherwise events are queued after the operation is
+// committed, not along with it: a failure to queue one, or a crash in between, loses the event
+// while the operation stands.
+func PublishEvents(store BankStore, publisher EventPublisher, queued bool) BankStore {
+	return &publishingStore{BankStore: store, publisher: publisher, queued: queued}
+}
+
+func (s *publishingStore) publish(ctx context.Context, events ...bank.Event) {
+	if len(events) == 0 {
+		return
+	}
+	if s.queued {
+		s.publisher.Wake()
+		return
+	}
+
+	// The operation is done, publish even if the client has gone away since, but don't let a
+	// slow store hold the response forever.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeOperationTimeout)
+	defer cancel()
+	if err := s.publisher.Publish(ctx, events...); err != nil {
+		log.Error().Err(err).Str("event_type", events[0].Type).Msg("Failed to publish webhook event")
+	}
+}
+
+func (s *publishingStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	account, err := s.BankStore.CreateAccount(ctx, owner, initialBalance)
+	if err == nil {
+		s.publish(ctx, bank.AccountCreatedEvent(account))
+	}
+	return account, err
+}
+
+func (s *publishingStore) PerformTransaction(ctx context.Context, accountID string, txType string, amount float64, reference string) (*bank.Transaction, error) {
+	transaction, err := s.BankStore.PerformTransaction(ctx, accountID, txType, amount, reference)
+	if err == nil {
+		s.publish(ctx, bank.TransactionCreatedEvent(transaction))
+	}
+	return transaction, err
+}
+
+// CloseAccount publishes the transactions paying out the balance of the closed account.
+func (s *publishingStore) CloseAccount(ctx context.Context, id string, payoutAccountID string) (*bank.AccountClosure, error) {
+	closure, err := s.BankStore.CloseAccount(ctx, id, payoutAccountID)
+	if err == nil {
+		s.publish(ctx, bank.ClosureEvents(closure)...)
+	}
+	return closure, err
+}
+
+func (s *publishingStore) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	err := s.BankStore.TransferFunds(ctx, fromAccountID, toAccountID, amount)
+	if err == nil {
+		s.publish(ctx, bank.TransferCompletedEvent(fromAccountID, toAccountID, amount))
+	}
+	return err
+}
+
+func (s *publishingStore) ExecuteBatch(ctx context.Context, batch bank.Batch) (*bank.BatchResult, error) {
+	result, err := s.BankStore.ExecuteBatch(ctx, batch)
+	if err == nil {
+		s.publish(ctx, bank.BatchEvents(batch, result)...)
+	}
+	return result, err
+}
+
+func (s *publishingStore) CreateWebhook(ctx context.Context, webhook bank.Webhook) (*bank.Webhook, error) {
+	created, err := s.BankStore.CreateWebhook(ctx, webhook)
+	if err == nil {
+		s.publisher.WebhooksChanged()
+	}
+	return created, err
+}
+
+func (s *publishingStore) DeleteWebhook(ctx context.Context, id string) error {
+	err := s.BankStore.DeleteWebhook(ctx, id)
+	if err == nil {
+		s.publisher.WebhooksChanged()
+	}
+	return err
+}
+
+func createWebhookHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createWebhookRequest
+
+		if err := bindJSON(c, &request); err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid request body while creating webhook")
+			writeError(c, err)
+			return
+		}
+
+		webhook, err := bank.NewWebhook(request.URL, request.Events, time.Now())
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed validating webhook.")
+			writeError(c, err)
+			return
+		}
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		created, err := bankStore.CreateWebhook(ctx, webhook)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to create webhook")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("webhook_id", created.ID).Str("url", created.URL).Strs("events", created.Events).Msg("Webhook created successfully")
+		c.JSON(http.StatusCreated, createdWebhookResponse{Webhook: *created, Secret: created.Secret})
+	}
+}
+
+func listWebhooksHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		webhooks, err := bankStore.ListWebhooks(ctx)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Failed to list webhooks")
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func getWebhookHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("id")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		webhook, err := bankStore.GetWebhook(ctx, webhookID)
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("webhook_id", webhookID).Msg("Failed to get webhook")
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+func deleteWebhookHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("id")
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		if err := bankStore.DeleteWebhook(ctx, webhookID); err != nil {
+			requestLog(c).Error().Err(err).Str("webhook_id", webhookID).Msg("Failed to delete webhook")
+			writeError(c, err)
+			return
+		}
+
+		requestLog(c).Info().Str("webhook_id", webhookID).Msg("Webhook deleted successfully")
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func listWebhookDeliveriesHandler(bankStore BankStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookID := c.Param("id")
+
+		filter, err := parseWebhookDeliveryFilter(c)
+		if err != nil {
+			requestLog(c).Error().Err(err).Msg("Invalid query parameters while listing webhook deliveries")
+			writeError(c, err)
+			return
+		}
+
+		ctx, cancel := storeContext(c)
+		defer cancel()
+
+		deliveries, err := bankStore.ListWebhookDeliveries(ctx, webhookID, filter)
+		if err != nil {
+			requestLog(c).Error().Err(err).Str("webhook_id", webhookID).Msg("Failed to list webhook deliveries")
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,244 @@
+package restServer
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher keeps the events published to it, failing with err when set.
+type recordingPublisher struct {
+	events          []bank.Event
+	err             error
+	wakes           int
+	webhooksChanged int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, events ...bank.Event) error {
+	p.events = append(p.events, events...)
+	return p.err
+}
+
+func (p *recordingPublisher) Wake() {
+	p.wakes++
+}
+
+func (p *recordingPublisher) WebhooksChanged() {
+	p.webhooksChanged++
+}
+
+func (p *recordingPublisher) types() []string {
+	types := make([]string, 0, len(p.events))
+	for _, event := range p.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	router := newTestRouter(t, store)
+
+	w := serve(router, http.MethodPost, "/webhooks", `{"url": "https://example.com/hooks", "events": ["transfer.completed"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		ID     string   `json:"id"`
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "https://example.com/hooks", created.URL)
+	assert.Equal(t, []string{bank.EventTransferCompleted}, created.Events)
+	assert.NotEmpty(t, created.Secret, "the secret must be returned on creation")
+
+	for _, path := range []string{"/webhooks", "/webhooks/" + created.ID} {
+		w = serve(router, http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), created.ID)
+		assert.NotContains(t, w.Body.String(), created.Secret, "the secret must only be returned on creation")
+	}
+
+	event := bank.WebhookEvent{ID: "event-1", Type: bank.EventTransferCompleted}
+	delivery := bank.NewWebhookDelivery(created.ID, event, []byte(`{"id":"event-1"}`))
+	require.NoError(t, store.EnqueueWebhookDeliveries(ctx, []bank.WebhookDelivery{delivery}))
+	w = serve(router, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?status=pending", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var deliveries []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, delivery.ID, deliveries[0]["id"])
+	assert.Equal(t, map[string]any{"id": "event-1"}, deliveries[0]["payload"], "the payload must be embedded as JSON")
+
+	w = serve(router, http.MethodGet, "/webhooks/"+created.ID+"/deliveries?status=dead", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = serve(router, http.MethodDelete, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serve(router, http.MethodGet, "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWebhookEndpointsRejectInvalidRequests(t *testing.T) {
+	store := memoryBank.NewBankStore()
+	router := newTestRouter(t, store)
+	webhook, err := bank.NewWebhook("https://example.com/hooks", []string{bank.EventAll}, time.Now())
+	require.NoError(t, err)
+	_, err = store.CreateWebhook(ctx, webhook)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"relative url", http.MethodPost, "/webhooks", `{"url": "/hooks", "events": ["*"]}`, http.StatusBadRequest, "invalid_webhook"},
+		{"unknown event", http.MethodPost, "/webhooks", `{"url": "https://example.com", "events": ["account.deleted"]}`, http.StatusBadRequest, "invalid_webhook"},
+		{"no events", http.MethodPost, "/webhooks", `{"url": "https://example.com"}`, http.StatusBadRequest, "invalid_webhook"},
+		{"unknown webhook", http.MethodDelete, "/webhooks/unknown", "", http.StatusNotFound, "webhook_not_found"},
+		{"deliveries of unknown webhook", http.MethodGet, "/webhooks/unknown/deliveries", "", http.StatusNotFound, "webhook_not_found"},
+		{"unknown status", http.MethodGet, "/webhooks/" + webhook.ID + "/deliveries?status=failed", "", http.StatusBadRequest, "invalid_filter"},
+		{"bad limit", http.MethodGet, "/webhooks/" + webhook.ID + "/deliveries?limit=many", "", http.StatusBadRequest, "invalid_query_parameter"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(router, test.method, test.path, test.body)
+			assert.Equal(t, test.status, w.Code)
+			var problem Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, test.code, problem.Code)
+		})
+	}
+}
+
+// contextPublisher keeps the deadline and error of the context events are published with.
+type contextPublisher struct {
+	recordingPublisher
+	deadline    time.Time
+	hasDeadline bool
+	err         error
+}
+
+func (p *contextPublisher) Publish(ctx context.Context, events ...bank.Event) error {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+	p.err = ctx.Err()
+	return nil
+}
+
+func TestPublishEventsOutlivesTheRequestWithinATimeout(t *testing.T) {
+	publisher := new(contextPublisher)
+	store := PublishEvents(&cancelOnCreateStore{BankStore: memoryBank.NewBankStore()}, publisher, false)
+	requestCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_, err := store.CreateAccount(context.WithValue(requestCtx, cancelKey{}, cancel), "Alex Camara", 10)
+	require.NoError(t, err)
+
+	assert.NoError(t, publisher.err, "the client going away doesn't stop the event")
+	assert.True(t, publisher.hasDeadline, "a stuck store must not hold the response")
+	assert.WithinDuration(t, time.Now().Add(storeOperationTimeout), publisher.deadline, time.Second)
+}
+
+type cancelKey struct{}
+
+// cancelOnCreateStore cancels the request, as a client disconnecting would, once the account is created.
+type cancelOnCreateStore struct {
+	BankStore
+}
+
+func (s *cancelOnCreateStore) CreateAccount(ctx context.Context, owner string, initialBalance float64) (*bank.Account, error) {
+	account, err := s.BankStore.CreateAccount(ctx, owner, initialBalance)
+	ctx.Value(cancelKey{}).(context.CancelFunc)()
+	return account, err
+}
+
+func TestPublishEventsPublishesSuccessfulOperations(t *testing.T) {
+	publisher := &recordingPublisher{}
+	store := PublishEvents(memoryBank.NewBankStore(), publisher, false)
+
+	alex, err := store.CreateAccount(ctx, "Alex Camara", 100)
+	require.NoError(t, err)
+	john, err := store.CreateAccount(ctx, "John Doe", 0)
+	require.NoError(t, err)
+	transaction, err := store.PerformTransaction(ctx, alex.ID, bank.DepositTransactionType, 10, "")
+	require.NoError(t, err)
+	require.NoError(t, store.TransferFunds(ctx, alex.ID, john.ID, 25))
+
+	assert.Equal(t, []string{bank.EventAccountCreated, bank.EventAccountCreated, bank.EventTransactionCreated, bank.EventTransferCompleted}, publisher.types())
+	assert.Equal(t, alex, publisher.events[0].Data)
+	assert.Equal(t, transaction, publisher.events[2].Data)
+	assert.Equal(t, bank.TransferCompleted{FromAccountID: alex.ID, ToAccountID: john.ID, Amount: 25}, publisher.events[3].Data)
+
+	// Failed operations publish nothing.
+	publisher.events = nil
+	_, err = store.PerformTransaction(ctx, alex.ID, bank.WithdrawalTransactionType, 1000, "")
+	require.ErrorIs(t, err, bank.ErrInsufficientFunds)
+	require.ErrorIs(t, store.TransferFunds(ctx, alex.ID, "unknown", 1), bank.ErrTransferDestinationNotFound)
+	assert.Empty(t, publisher.events)
+
+	// Batches publish the events of their successful operations.
+	result, err := store.ExecuteBatch(ctx, bank.Batch{Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: john.ID, Amount: 5},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 1000},
+		{Type: bank.BatchTransfer, FromAccountID: john.ID, ToAccountID: alex.ID, Amount: 5},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []string{bank.EventTransactionCreated, bank.EventTransferCompleted}, publisher.types())
+	assert.Equal(t, result.Results[0].Transaction, publisher.events[0].Data)
+
+	// An atomic batch that fails is undone, so it publishes nothing.
+	publisher.events = nil
+	_, err = store.ExecuteBatch(ctx, bank.Batch{Atomic: true, Operations: []bank.BatchOperation{
+		{Type: bank.BatchDeposit, AccountID: john.ID, Amount: 5},
+		{Type: bank.BatchWithdrawal, AccountID: john.ID, Amount: 1000},
+	}})
+	require.Error(t, err)
+	assert.Empty(t, publisher.events)
+
+	// Closing an account publishes the transactions paying out its balance.
+	publisher.events = nil
+	closure, err := store.CloseAccount(ctx, john.ID, alex.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{bank.EventTransactionCreated, bank.EventTransactionCreated}, publisher.types())
+	assert.Equal(t, closure.Withdrawal, publisher.events[0].Data)
+	assert.Equal(t, closure.Deposit, publisher.events[1].Data)
+
+	// A publishing failure doesn't fail the operation that was already made.
+	publisher.err = errors.New("queue unavailable")
+	_, err = store.CreateAccount(ctx, "Jane Doe", 0)
+	assert.NoError(t, err)
+}
+
+func TestPublishEventsOnlyWakesThePublisherOfOutboxStores(t *testing.T) {
+	publisher := &recordingPublisher{}
+	store := PublishEvents(memoryBank.NewBankStore(), publisher, true)
+
+	alex, err := store.CreateAccount(ctx, "Alex Camara", 100)
+	require.NoError(t, err)
+	_, err = store.PerformTransaction(ctx, alex.ID, bank.WithdrawalTransactionType, 1000, "")
+	require.ErrorIs(t, err, bank.ErrInsufficientFunds)
+
+	assert.Empty(t, publisher.events, "the store queued the events along with its writes")
+	assert.Equal(t, 1, publisher.wakes, "only successful writes queue deliveries")
+
+	webhook, err := bank.NewWebhook("https://example.com/hooks", []string{bank.EventAll}, time.Now())
+	require.NoError(t, err)
+	_, err = store.CreateWebhook(ctx, webhook)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteWebhook(ctx, webhook.ID))
+	assert.Equal(t, 2, publisher.webhooksChanged, "cached subscriptions must be dropped when webhooks change")
+}
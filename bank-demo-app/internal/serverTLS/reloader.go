@@ -0,0 +1,184 @@
+// Package serverTLS serves HTTPS with a certificate, and optionally a client CA bundle for mutual
+// TLS, that are read again when their files change or on demand, so renewed certificates are
+// picked up without restarting the server. Connections already open keep the certificate they
+// were established with.
+package serverTLS
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM bundle client certificates are verified against. Client
+	// certificates aren't asked for when empty.
+	ClientCAFile string
+	// RequireClientCert rejects connections without a valid client certificate, otherwise one
+	// is only verified when sent.
+	RequireClientCert bool
+}
+
+// Reloader holds the current certificate and client CAs. It is safe for concurrent use.
+type Reloader struct {
+	config Config
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    map[string]time.Time
+}
+
+// NewReloader loads the files of config, failing when any is missing or invalid.
+func NewReloader(config Config) (*Reloader, error) {
+	reloader := &Reloader{config: config}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads the files again. On error the certificate and CAs loaded before are kept, so a
+// certificate caught half written is not served.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+		return fmt.Errorf("failed to parse TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA file %s holds no PEM certificate", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether a file was modified since it was last loaded.
+func (r *Reloader) changed() (bool, error) {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for path, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[path]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Reloader) readModTimes() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// Watch reloads the files when they change, checking every interval, and whenever reload
+// receives, such as on SIGHUP, until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, reload <-chan os.Signal) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			r.reloadAndLog("signal")
+		case <-ticker.C:
+			changed, err := r.changed()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to check the TLS certificate files")
+				continue
+			}
+			if changed {
+				r.reloadAndLog("file change")
+			}
+		}
+	}
+}
+
+func (r *Reloader) reloadAndLog(trigger string) {
+	if err := r.Reload(); err != nil {
+		log.Error().Err(err).Str("trigger", trigger).Msg("Failed to reload the TLS certificate, keeping the previous one")
+		return
+	}
+	log.Info().Str("trigger", trigger).Str("not_after", r.current().Leaf.NotAfter.Format(time.RFC3339)).Msg("Reloaded the TLS certificate")
+}
+
+func (r *Reloader) current() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.certificate
+}
+
+// TLSConfig returns the server configuration, which hands every new connection the certificate
+// and client CAs loaded last.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if r.certificate == nil {
+				return nil, errors.New("no TLS certificate loaded")
+			}
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.certificate},
+				// The configuration of the server is replaced, so HTTP/2 is offered again here.
+				NextProtos: []string{"h2", "http/1.1"},
+			}
+			if r.clientCAs != nil {
+				config.ClientCAs = r.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.config.RequireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
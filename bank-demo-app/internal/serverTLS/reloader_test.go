@@ -0,0 +1,224 @@
+package serverTLS
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authority is a throwaway CA issuing the certificates of a test.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for commonName, valid for localhost, and its key, both PEM encoded.
+func (ca *authority) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Example Bank"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+}
+
+// serve serves a handler echoing the client certificate subject over TLS with reloader.
+func serve(t *testing.T, reloader *Reloader) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.VerifiedChains) > 0 {
+			w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.String()))
+		}
+	})}
+	go server.Serve(tls.NewListener(listener, reloader.TLSConfig()))
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+// handshake connects to address and returns the common name of the server certificate.
+func handshake(t *testing.T, address string, ca *authority, clientCert []tls.Certificate) (string, error) {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", address, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: clientCert})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	// TLS 1.3 clients only learn that their certificate was rejected on the first read.
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); err != nil && !os.IsTimeout(err) {
+		return "", err
+	}
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestReloaderServesReloadedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, "server-1", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	reloader, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	address := serve(t, reloader)
+
+	name, err := handshake(t, address, ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hangup := make(chan os.Signal)
+	go reloader.Watch(ctx, 10*time.Millisecond, hangup)
+
+	// A renewed certificate is picked up once its files change.
+	certPEM, keyPEM = ca.issue(t, "server-2", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	assert.Eventually(t, func() bool {
+		name, err := handshake(t, address, ca, nil)
+		return err == nil && name == "server-2"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A broken certificate is not served, the previous one is kept.
+	writeFile(t, certFile, []byte("not a certificate"))
+	hangup <- syscall.SIGHUP
+	name, err = handshake(t, address, ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "server-2", name)
+
+	// The signal reloads the files even when their modification time didn't change.
+	certPEM, keyPEM = ca.issue(t, "server-3", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	hangup <- syscall.SIGHUP
+	assert.Eventually(t, func() bool {
+		name, err := handshake(t, address, ca, nil)
+		return err == nil && name == "server-3"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA, clientCA, otherCA := newAuthority(t), newAuthority(t), newAuthority(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "clients.pem")
+	certPEM, keyPEM := serverCA.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, clientCA.pem)
+
+	clientCert := func(ca *authority) []tls.Certificate {
+		certificate, err := tls.X509KeyPair(ca.issue(t, "reporting", x509.ExtKeyUsageClientAuth))
+		require.NoError(t, err)
+		return []tls.Certificate{certificate}
+	}
+
+	optional, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	require.NoError(t, err)
+	address := serve(t, optional)
+	_, err = handshake(t, address, serverCA, nil)
+	assert.NoError(t, err, "the client certificate is optional")
+	_, err = handshake(t, address, serverCA, clientCert(clientCA))
+	assert.NoError(t, err)
+	_, err = handshake(t, address, serverCA, clientCert(otherCA))
+	assert.Error(t, err, "a certificate from another CA is rejected")
+
+	required, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, RequireClientCert: true})
+	require.NoError(t, err)
+	address = serve(t, required)
+	_, err = handshake(t, address, serverCA, nil)
+	assert.Error(t, err, "the client certificate is required")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: clientCert(clientCA)}}}
+	response, err := client.Get("https://localhost:" + address[len("127.0.0.1:"):])
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body := make([]byte, 100)
+	n, _ := response.Body.Read(body)
+	assert.Equal(t, "CN=reporting,O=Example Bank", string(body[:n]))
+}
+
+func TestNewReloaderRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	_, err := NewReloader(Config{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+	assert.ErrorContains(t, err, "failed to read TLS file")
+
+	_, err = NewReloader(Config{CertFile: keyFile, KeyFile: keyFile})
+	assert.ErrorContains(t, err, "failed to load TLS certificate")
+
+	_, err = NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile})
+	assert.ErrorContains(t, err, "holds no PEM certificate")
+}
@@ -0,0 +1,76 @@
+// Package tracing sets up OpenTelemetry for the server. Spans are recorded with the OpenTelemetry
+// API through Tracer, exported as JSON lines by the stdouttrace exporter, and trace context
+// travels between services in the W3C traceparent header.
+//
+// Until Start is called the global tracer provider is a no-op one, so spans cost next to nothing
+// and are never exported.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StdoutOutput is the Start output that writes spans to the standard output.
+const StdoutOutput = "stdout"
+
+// instrumentationName names the tracer of the application spans, the Mongo commands are traced
+// by otelmongo under its own.
+const instrumentationName = "bank-demo-app"
+
+// Tracer returns the tracer to start the application spans with, from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// SetError marks span as failed because of err, if any.
+func SetError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Start installs a global tracer provider writing spans to the standard output when output is
+// StdoutOutput, or appending them to the file at output otherwise, along with the TraceContext
+// propagator. The returned function flushes the spans left and closes the output.
+func Start(output string) (func(ctx context.Context) error, error) {
+	var w io.Writer = os.Stdout
+	closeOutput := func() error { return nil }
+	if output != StdoutOutput {
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		w, closeOutput = file, file.Close
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		closeOutput()
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", instrumentationName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
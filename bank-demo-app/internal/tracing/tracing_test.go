@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestStartExportsSpansToTheOutputFile(t *testing.T) {
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Start(path)
+	require.NoError(t, err)
+
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, span := Tracer().Start(ctx, "transfer")
+	SetError(span, errors.New("insufficient funds"))
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 1, "one JSON object per span")
+
+	var exported struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+		Parent      struct{ SpanID string }
+		Status      struct{ Code, Description string }
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &exported))
+	assert.Equal(t, "transfer", exported.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", exported.SpanContext.TraceID, "the traceparent trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", exported.Parent.SpanID)
+	assert.Equal(t, "Error", exported.Status.Code)
+	assert.Equal(t, "insufficient funds", exported.Status.Description)
+}
+
+func TestStartFailsOnAnUnwritableOutput(t *testing.T) {
+	_, err := Start(filepath.Join(t.TempDir(), "missing", "traces.jsonl"))
+	assert.ErrorContains(t, err, "failed to open trace file")
+}
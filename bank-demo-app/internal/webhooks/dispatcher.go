@@ -0,0 +1,314 @@
+// Package webhooks delivers bank events to the URLs subscribed to them. Events are queued in the
+// store, one delivery per subscribed webhook, then sent by a dispatcher polling the queue, so
+// deliveries survive restarts. Failed deliveries are retried with exponential backoff until
+// they run out of attempts and are marked dead; every attempt is kept as the delivery log.
+package webhooks
+
+import (
+	"bank-demo-app/internal/bank"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Headers sent along with every delivery, besides SignatureHeader.
+const (
+	EventHeader    = "X-Bank-Event"
+	DeliveryHeader = "X-Bank-Delivery"
+)
+
+const (
+	// leaseMargin is added to the request timeout to lease claimed deliveries, so a delivery is
+	// only claimed again once the dispatcher sending it has stopped.
+	leaseMargin = time.Minute
+	// storeTimeout bounds recording the outcome of an attempt, which is done even on shutdown.
+	storeTimeout = 5 * time.Second
+	// subscriptionsTTL is how long the listed webhooks are used to queue events, which saves
+	// listing them on every write.
+	subscriptionsTTL = 10 * time.Second
+	// maxErrorBodySize caps how much of an error response is kept in the delivery log.
+	maxErrorBodySize = 256
+)
+
+// Store is the part of the bank store the dispatcher queues deliveries in.
+type Store interface {
+	GetWebhook(ctx context.Context, id string) (*bank.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]bank.Webhook, error)
+	EnqueueWebhookDeliveries(ctx context.Context, deliveries []bank.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]bank.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery bank.WebhookDelivery) error
+}
+
+type Config struct {
+	// Interval is how often the queue is checked for due deliveries. Published events are
+	// delivered right away.
+	Interval time.Duration
+	// MaxAttempts is how many times a delivery is sent before it is marked dead.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled for each one after it up to
+	// MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds each request to a webhook URL.
+	Timeout time.Duration
+	// BatchSize is how many deliveries are claimed, and sent concurrently, at once.
+	BatchSize int
+}
+
+// DefaultConfig retries a delivery for about a day: 8 attempts, 10s apart at first, at most an hour.
+func DefaultConfig() Config {
+	return Config{
+		Interval:       time.Second,
+		MaxAttempts:    8,
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     time.Hour,
+		Timeout:        10 * time.Second,
+		BatchSize:      20,
+	}
+}
+
+type Dispatcher struct {
+	store  Store
+	config Config
+	client *http.Client
+	// wake tells Run that events were published, so it doesn't wait for the next tick.
+	wake chan struct{}
+
+	subscriptionsMu       sync.Mutex
+	subscriptions         []bank.Webhook
+	subscriptionsListedAt time.Time
+}
+
+func NewDispatcher(store Store, config Config) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			// A redirect is an answer like any other non 2xx one, following it could send the
+			// event somewhere the webhook owner didn't register.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Subscriptions returns the webhooks events are delivered to, as listed at most
+// subscriptionsTTL ago. Webhooks created or deleted through this server are seen right away, see
+// WebhooksChanged; the others, by servers sharing the store, once the list expires. When the list
+// can't be refreshed, the expired one is used.
+func (d *Dispatcher) Subscriptions(ctx context.Context) ([]bank.Webhook, error) {
+	d.subscriptionsMu.Lock()
+	defer d.subscriptionsMu.Unlock()
+
+	if d.subscriptions != nil && time.Since(d.subscriptionsListedAt) < subscriptionsTTL {
+		return d.subscriptions, nil
+	}
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		if d.subscriptions != nil {
+			log.Warn().Err(err).Msg("Failed to refresh webhook subscriptions, using the expired ones")
+			return d.subscriptions, nil
+		}
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	d.subscriptions, d.subscriptionsListedAt = webhooks, time.Now()
+	return webhooks, nil
+}
+
+// WebhooksChanged drops the subscriptions listed so far, so the next events see the webhooks
+// created or deleted since.
+func (d *Dispatcher) WebhooksChanged() {
+	d.subscriptionsMu.Lock()
+	d.subscriptions = nil
+	d.subscriptionsMu.Unlock()
+}
+
+// Publish queues events, caused by a write already committed, for every webhook subscribed to
+// them. Stores with an outbox queue them along with the write instead, see bank.Outbox.
+func (d *Dispatcher) Publish(ctx context.Context, events ...bank.Event) error {
+	webhooks, err := d.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	deliveries, err := bank.NewWebhookDeliveries(webhooks, events, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.store.EnqueueWebhookDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	d.Wake()
+	return nil
+}
+
+// Wake has Run deliver the deliveries queued since its last pass right away, rather than on the
+// next tick.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches are claimed, the queue may hold more.
+		for {
+			delivered, err := d.DeliverDue(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to deliver webhooks")
+			}
+			if err != nil || delivered < d.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue claims the deliveries due now, sends them concurrently and records the outcome of
+// each attempt. It returns how many deliveries were attempted.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+	deliveries, err := d.store.ClaimWebhookDeliveries(ctx, time.Now(), d.config.BatchSize, d.config.Timeout+leaseMargin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery bank.WebhookDelivery) {
+	logger := log.With().
+		Str("delivery_id", delivery.ID).
+		Str("webhook_id", delivery.WebhookID).
+		Str("event_type", delivery.EventType).
+		Logger()
+
+	webhook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, bank.ErrWebhookNotFound) {
+		// The webhook was deleted after the delivery was claimed, its deliveries are gone too.
+		return
+	}
+	if err != nil {
+		// The lease expires and the delivery is claimed again.
+		logger.Error().Err(err).Msg("Failed to get the webhook of a delivery")
+		return
+	}
+
+	attempt := d.send(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// Interrupted by shutdown, not the receiver's failure. It is sent again once the lease expires.
+		return
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	attempts := len(delivery.Attempts)
+
+	event := logger.Info()
+	switch {
+	case attempt.Error == "":
+		delivery.Status = bank.WebhookDeliverySucceeded
+		delivery.CompletedAt = &attempt.At
+	case attempts >= d.config.MaxAttempts:
+		delivery.Status = bank.WebhookDeliveryDead
+		delivery.CompletedAt = &attempt.At
+		event = logger.Error().Str("error", attempt.Error)
+	default:
+		delivery.NextAttemptAt = attempt.At.Add(d.backoff(attempts))
+		event = logger.Warn().Str("error", attempt.Error).Time("next_attempt_at", delivery.NextAttemptAt)
+	}
+	event.Int("attempt", attempts).
+		Int("status_code", attempt.StatusCode).
+		Int64("duration_ms", attempt.DurationMS).
+		Str("status", delivery.Status).
+		Msg("Attempted webhook delivery")
+
+	// The outcome is recorded even if shutdown starts now, or the attempt would be made again.
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+	if err := d.store.UpdateWebhookDelivery(storeCtx, delivery); err != nil {
+		logger.Error().Err(err).Msg("Failed to record a webhook delivery attempt")
+	}
+}
+
+// send posts the delivery payload to the webhook URL. Only 2xx answers are successes.
+func (d *Dispatcher) send(ctx context.Context, webhook *bank.Webhook, delivery bank.WebhookDelivery) bank.WebhookAttempt {
+	start := time.Now()
+	attempt := bank.WebhookAttempt{At: start.UTC()}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.DurationMS = time.Since(start).Milliseconds()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "bank-demo-app-webhooks")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, start, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.DurationMS = time.Since(start).Milliseconds()
+		return attempt
+	}
+	defer response.Body.Close()
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		attempt.Error = fmt.Sprintf("unexpected status %s", response.Status)
+		if len(body) > 0 {
+			attempt.Error += ": " + string(body)
+		}
+	}
+	// Drain what's left so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	return attempt
+}
+
+// backoff returns the wait after the given number of failed attempts: InitialBackoff doubled for
+// every attempt after the first, capped at MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.InitialBackoff
+	for i := 1; i < attempts && wait < d.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.config.MaxBackoff)
+}
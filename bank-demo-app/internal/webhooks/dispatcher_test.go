@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"bank-demo-app/internal/bank"
+	"bank-demo-app/internal/bank/memoryBank"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a webhook endpoint answering with the statuses it is given, in turn, then 204.
+type receiver struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, request)
+		r.bodies = append(r.bodies, body)
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if status == http.StatusFound {
+			w.Header().Set("Location", "/elsewhere")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newWebhook(t *testing.T, store *memoryBank.BankStore, url string, events ...string) *bank.Webhook {
+	t.Helper()
+	webhook, err := bank.NewWebhook(url, events, time.Now())
+	require.NoError(t, err)
+	stored, err := store.CreateWebhook(context.Background(), webhook)
+	require.NoError(t, err)
+	return stored
+}
+
+func deliveriesOf(t *testing.T, store *memoryBank.BankStore, webhookID string) []bank.WebhookDelivery {
+	t.Helper()
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), webhookID, bank.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	return deliveries
+}
+
+func TestDispatcherDeliversSignedEventsToSubscribers(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+	transfers := newReceiver(t)
+	everything := newReceiver(t)
+	transfersHook := newWebhook(t, store, transfers.server.URL, bank.EventTransferCompleted)
+	everythingHook := newWebhook(t, store, everything.server.URL, bank.EventAll)
+	dispatcher := NewDispatcher(store, DefaultConfig())
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-1"}}))
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventTransferCompleted, Data: map[string]any{"amount": 25.5}}))
+	delivered, err := dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, delivered)
+
+	require.Equal(t, 1, transfers.received(), "only subscribed events must be delivered")
+	require.Equal(t, 2, everything.received())
+
+	request, body := transfers.requests[0], transfers.bodies[0]
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	assert.Equal(t, bank.EventTransferCompleted, request.Header.Get(EventHeader))
+	assert.NoError(t, Verify(transfersHook.Secret, request.Header.Get(SignatureHeader), body, time.Now(), time.Minute))
+	assert.ErrorIs(t, Verify(everythingHook.Secret, request.Header.Get(SignatureHeader), body, time.Now(), time.Minute), ErrInvalidSignature,
+		"every webhook must be signed with its own secret")
+
+	var event bank.WebhookEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, bank.EventTransferCompleted, event.Type)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, map[string]any{"amount": 25.5}, event.Data)
+
+	deliveries := deliveriesOf(t, store, transfersHook.ID)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, request.Header.Get(DeliveryHeader), deliveries[0].ID)
+	assert.Equal(t, bank.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.NotNil(t, deliveries[0].CompletedAt)
+	require.Len(t, deliveries[0].Attempts, 1)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].Attempts[0].StatusCode)
+	assert.Empty(t, deliveries[0].Attempts[0].Error)
+
+	delivered, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "succeeded deliveries must not be sent again")
+}
+
+func TestDispatcherRetriesFailedDeliveries(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+	flaky := newReceiver(t, http.StatusInternalServerError, http.StatusFound)
+	webhook := newWebhook(t, store, flaky.server.URL, bank.EventAll)
+	config := DefaultConfig()
+	config.InitialBackoff, config.MaxBackoff = time.Millisecond, time.Millisecond
+	dispatcher := NewDispatcher(store, config)
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-1"}}))
+	require.Eventually(t, func() bool {
+		_, err := dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		return deliveriesOf(t, store, webhook.ID)[0].Status == bank.WebhookDeliverySucceeded
+	}, 5*time.Second, 5*time.Millisecond)
+
+	delivery := deliveriesOf(t, store, webhook.ID)[0]
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusInternalServerError, delivery.Attempts[0].StatusCode)
+	assert.Contains(t, delivery.Attempts[0].Error, "500")
+	assert.Equal(t, http.StatusFound, delivery.Attempts[1].StatusCode, "redirects must not be followed")
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[2].StatusCode)
+	assert.Equal(t, 3, flaky.received())
+
+	// Every retry carries the same event, so receivers can tell it was already processed.
+	assert.Equal(t, flaky.bodies[0], flaky.bodies[2])
+	assert.Equal(t, flaky.requests[0].Header.Get(DeliveryHeader), flaky.requests[2].Header.Get(DeliveryHeader))
+}
+
+func TestDispatcherDeadLettersDeliveriesOutOfAttempts(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+	broken := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	webhook := newWebhook(t, store, broken.server.URL, bank.EventAll)
+	unreachable := newWebhook(t, store, "http://127.0.0.1:1/hooks", bank.EventAll)
+	config := DefaultConfig()
+	config.MaxAttempts, config.InitialBackoff, config.MaxBackoff = 3, time.Millisecond, time.Millisecond
+	dispatcher := NewDispatcher(store, config)
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventTransactionCreated, Data: map[string]string{"id": "transaction-1"}}))
+	require.Eventually(t, func() bool {
+		_, err := dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		return deliveriesOf(t, store, webhook.ID)[0].Status == bank.WebhookDeliveryDead &&
+			deliveriesOf(t, store, unreachable.ID)[0].Status == bank.WebhookDeliveryDead
+	}, 5*time.Second, 5*time.Millisecond)
+
+	delivery := deliveriesOf(t, store, webhook.ID)[0]
+	assert.Len(t, delivery.Attempts, 3)
+	assert.NotNil(t, delivery.CompletedAt)
+	assert.Equal(t, 3, broken.received(), "dead deliveries must not be sent again")
+
+	delivery = deliveriesOf(t, store, unreachable.ID)[0]
+	require.Len(t, delivery.Attempts, 3)
+	assert.Zero(t, delivery.Attempts[0].StatusCode)
+	assert.Contains(t, delivery.Attempts[0].Error, "connection refused")
+
+	for range 3 {
+		delivered, err := dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+	}
+}
+
+func TestDispatcherRunDeliversPublishedEventsRightAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := memoryBank.NewBankStore()
+	receiver := newReceiver(t)
+	newWebhook(t, store, receiver.server.URL, bank.EventAll)
+	config := DefaultConfig()
+	config.Interval = time.Hour
+	dispatcher := NewDispatcher(store, config)
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-1"}}))
+	assert.Eventually(t, func() bool { return receiver.received() == 1 }, 5*time.Second, 5*time.Millisecond,
+		"published events must not wait for the next interval")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once its context was done")
+	}
+}
+
+func TestPublishWithoutSubscribersQueuesNothing(t *testing.T) {
+	ctx := context.Background()
+	store := memoryBank.NewBankStore()
+	webhook := newWebhook(t, store, "https://example.com/hooks", bank.EventTransferCompleted)
+	dispatcher := NewDispatcher(store, DefaultConfig())
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-1"}}))
+	assert.Empty(t, deliveriesOf(t, store, webhook.ID))
+}
+
+func TestBackoffDoublesUpToTheMaximum(t *testing.T) {
+	dispatcher := NewDispatcher(nil, Config{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, wait := range expected {
+		assert.Equal(t, wait, dispatcher.backoff(i+1), "after %d attempts", i+1)
+	}
+}
+
+// listCountingStore counts how many times webhooks are listed.
+type listCountingStore struct {
+	*memoryBank.BankStore
+	lists int
+}
+
+func (s *listCountingStore) ListWebhooks(ctx context.Context) ([]bank.Webhook, error) {
+	s.lists++
+	return s.BankStore.ListWebhooks(ctx)
+}
+
+func TestSubscriptionsAreCachedUntilWebhooksChange(t *testing.T) {
+	ctx := context.Background()
+	store := &listCountingStore{BankStore: memoryBank.NewBankStore()}
+	first := newWebhook(t, store.BankStore, "https://example.com/first", bank.EventAll)
+	dispatcher := NewDispatcher(store, DefaultConfig())
+
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-1"}}))
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-2"}}))
+	assert.Equal(t, 1, store.lists, "webhooks must not be listed on every event")
+
+	second := newWebhook(t, store.BankStore, "https://example.com/second", bank.EventAll)
+	dispatcher.WebhooksChanged()
+	require.NoError(t, dispatcher.Publish(ctx, bank.Event{Type: bank.EventAccountCreated, Data: map[string]string{"id": "account-3"}}))
+	assert.Equal(t, 2, store.lists)
+	assert.Len(t, deliveriesOf(t, store.BankStore, first.ID), 3)
+	assert.Len(t, deliveriesOf(t, store.BankStore, second.ID), 1, "new webhooks must get the events published after they changed")
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the signature of a delivery, as "t=<unix seconds>,v1=<hex HMAC>".
+const SignatureHeader = "X-Bank-Signature"
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header value of body sent at timestamp: the HMAC-SHA256, keyed
+// with the webhook secret, of the timestamp and body joined by a dot. Signing the timestamp lets
+// receivers reject replayed deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := timestamp.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", unix, hex.EncodeToString(mac(secret, unix, body)))
+}
+
+// Verify checks that header is a signature of body made with secret less than tolerance away
+// from now. Receivers written in Go can use it as is, others must do the same.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix int64
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: bad timestamp %q", ErrInvalidSignature, value)
+			}
+			unix = parsed
+		case "v1":
+			// Unknown schemes are skipped, so new ones can be sent alongside v1.
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("%w: bad v1 signature", ErrInvalidSignature)
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	if unix == 0 || len(signatures) == 0 {
+		return fmt.Errorf("%w: expected t=<timestamp>,v1=<signature>", ErrInvalidSignature)
+	}
+
+	if age := now.Sub(time.Unix(unix, 0)).Abs(); age > tolerance {
+		return fmt.Errorf("%w: timestamp is %s away, the tolerance is %s", ErrInvalidSignature, age.Truncate(time.Second), tolerance)
+	}
+	expected := mac(secret, unix, body)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+}
+
+func mac(secret string, unix int64, body []byte) []byte {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(strconv.FormatInt(unix, 10)))
+	hash.Write([]byte("."))
+	hash.Write(body)
+	return hash.Sum(nil)
+}
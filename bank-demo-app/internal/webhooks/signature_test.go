@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	sentAt := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"transfer.completed"}`)
+	header := Sign("whsec_secret", sentAt, body)
+	assert.Regexp(t, `^t=\d+,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify("whsec_secret", header, body, sentAt.Add(time.Minute), 5*time.Minute))
+	assert.NoError(t, Verify("whsec_secret", header+",v2=unknown", body, sentAt, time.Minute), "unknown schemes are skipped")
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+	}{
+		{"tampered body", "whsec_secret", header, []byte(`{"type":"account.created"}`), sentAt},
+		{"other secret", "whsec_other", header, body, sentAt},
+		{"replayed", "whsec_secret", header, body, sentAt.Add(time.Hour)},
+		{"from the future", "whsec_secret", header, body, sentAt.Add(-time.Hour)},
+		{"no timestamp", "whsec_secret", "v1=00", body, sentAt},
+		{"no signature", "whsec_secret", "t=1792411200", body, sentAt},
+		{"bad signature", "whsec_secret", "t=1792411200,v1=zz", body, sentAt},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.ErrorIs(t, Verify(test.secret, test.header, test.body, test.now, 5*time.Minute), ErrInvalidSignature)
+		})
+	}
+}
@@ -6,9 +6,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 )
 
-const baseURL = "http://localhost:8080"
+const baseURL = "http://localhost:8080/v1"
+
+// defaultAPIKey matches the admin key run_server.sh creates for local development.
+const defaultAPIKey = "bk_local-development-admin-key"
+
+// apiKey is sent on every request, taken from BANK_API_KEY when set.
+func apiKey() string {
+	if key := os.Getenv("BANK_API_KEY"); key != "" {
+		return key
+	}
+	return defaultAPIKey
+}
 
 func printResponseJson(responseObject interface{}) {
 	// Indent Json to show the user pretty-printed Json.
@@ -52,6 +64,7 @@ func makeRequest(method, endpoint string, payload interface{}) (*http.Response,
 		return &http.Response{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey())
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
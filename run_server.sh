@@ -5,16 +5,22 @@ cd bank-demo-app
 
 # Ensure dependencies are tidy and build the application
 go mod tidy
-go build -o bank-server ./cmd/main.go
+go build -o bank-server ./cmd
 
 # Change to false to use database instead of builtin account manager.
 IN_MEMORY=false
 
 # Execute the application based on the in-memory parameter
 if [ "$IN_MEMORY" == "true" ]; then
+    # Admin API key created at startup for local development, the test client sends it by default.
+    # Only in memory: a well-known key must never reach a database that outlives this run.
+    export BANK_ADMIN_API_KEY="${BANK_ADMIN_API_KEY:-bk_local-development-admin-key}"
     echo "Running in in-memory mode..."
     ./bank-server
 else
+    if [ -z "$BANK_ADMIN_API_KEY" ]; then
+        echo "BANK_ADMIN_API_KEY is not set, no admin API key will be created."
+    fi
     echo "Running with database configuration..."
     ./bank-server --in-memory=false --mongo-db="BankStore"
 fi
\ No newline at end of file
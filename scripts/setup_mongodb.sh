@@ -20,22 +20,12 @@ docker login
 echo "Pulling the MongoDB Docker image..."
 docker pull mongo
 
-# Create an initialization script for MongoDB
-INIT_SCRIPT="mongo-init.js"
-cat <<EOF > $INIT_SCRIPT
-db = db.getSiblingDB('BankStore');
-db.createCollection('accounts');
-db.createCollection('transactions');
-EOF
-
-echo "Initialization script for MongoDB created: $INIT_SCRIPT"
-
-# Run MongoDB container with the initialization script
+# Run MongoDB container. The server creates the collections, validators and indexes itself by
+# applying its migrations at startup, or with `./bank-server migrate up`.
 echo "Starting MongoDB container..."
 docker run -d \
     --name mongodb \
     -p 27017:27017 \
-    -v $(pwd)/$INIT_SCRIPT:/docker-entrypoint-initdb.d/$INIT_SCRIPT:ro \
     mongo
 
-echo "MongoDB is running with the 'BankStore' database and collections 'accounts' and 'transactions' initialized."
+echo "MongoDB is running, start the server with --mongo-db=BankStore to create the 'BankStore' database."